@@ -0,0 +1,99 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accountSettings is the request body for updating account settings. Settings
+// left out keep their current value.
+type accountSettings struct {
+	TimeZone *string `json:"time_zone"`
+	Unit     *string `json:"unit"`
+}
+
+// loadTimeZone returns the IANA time zone, treating an empty name as UTC
+func loadTimeZone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	return time.LoadLocation(name)
+}
+
+// userLocation returns the user's time zone, which decides the calendar day
+// timestamps fall on. Users without one, or with one that no longer loads,
+// use UTC.
+func userLocation(userID int) *time.Location {
+	var user User
+	if db.Select("time_zone").Where("id = ?", userID).Limit(1).Find(&user).RowsAffected == 0 {
+		return time.UTC
+	}
+	loc, err := loadTimeZone(user.TimeZone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// currentLocation returns the time zone of the user whose data the request
+// works on
+func currentLocation(c *gin.Context) *time.Location {
+	return userLocation(currentUserID(c))
+}
+
+// today returns the current calendar day in loc as midnight UTC
+func today(loc *time.Location) time.Time {
+	day, _ := time.Parse(dateLayout, time.Now().In(loc).Format(dateLayout))
+	return day
+}
+
+// getAccount handles GET /account
+func getAccount(c *gin.Context) {
+	var user User
+	switch err := db.First(&user, currentUserID(c)).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, user)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+	}
+}
+
+// updateAccount handles PUT /account
+func updateAccount(c *gin.Context) {
+	var user User
+	var req accountSettings
+
+	switch {
+	case db.First(&user, currentUserID(c)).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case c.ShouldBindJSON(&req) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	if req.TimeZone != nil {
+		user.TimeZone = strings.TrimSpace(*req.TimeZone)
+		if _, err := loadTimeZone(user.TimeZone); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid time zone", "field": "time_zone", "value": user.TimeZone})
+			return
+		}
+	}
+	if req.Unit != nil {
+		user.Unit = strings.ToLower(strings.TrimSpace(*req.Unit))
+		if !validUnit(user.Unit) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit", "field": "unit", "value": user.Unit})
+			return
+		}
+	}
+
+	switch err := db.Model(&user).Select("time_zone", "unit").Updates(&user).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, user)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update account"})
+	}
+}
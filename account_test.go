@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpdateAccount_TimeZone(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	w := sendJSON(r, "PUT", "/account", gin.H{"time_zone": "Australia/Sydney"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	req, _ := http.NewRequest("GET", "/account", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var user User
+	json.Unmarshal(w.Body.Bytes(), &user)
+	assert.Equal(t, "Australia/Sydney", user.TimeZone)
+	assert.Equal(t, "Australia/Sydney", userLocation(testUserID).String())
+}
+
+func TestUpdateAccount_InvalidTimeZone(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	for _, zone := range []string{"Mars/Olympus_Mons", "+05:00"} {
+		w := sendJSON(r, "PUT", "/account", gin.H{"time_zone": zone}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, zone)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "time_zone", response["field"])
+	}
+}
+
+func TestDailyNutrition_UserTimeZone(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	// The same instant, 03:30 UTC on 2 October, logged before and after
+	// the athlete sets their time zone to Los Angeles
+	meal := gin.H{"name": "Late dinner", "occurred_at": "2023-10-02T03:30:00Z", "calories": 0, "carbs": 0}
+	w := postJSON(r, "/meals", meal, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	sendJSON(r, "PUT", "/account", gin.H{"time_zone": "America/Los_Angeles"}, "")
+	w = postJSON(r, "/meals", meal, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	days, err := buildDailyNutrition(testUserID, "", "")
+	assert.NoError(t, err)
+	if assert.Len(t, days, 2) {
+		assert.Equal(t, "2023-10-01", days[0].Date)
+		assert.Equal(t, "2023-10-02", days[1].Date)
+	}
+}
+
+func TestGetAccount_RejectsAPITokens(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	tokens := registerAndLogin(t, r, "athlete@example.com")
+	w := postJSON(r, "/tokens", gin.H{"name": "Watch", "scopes": []string{"weights:write"}}, tokens.AccessToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var created struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &created)
+
+	w = sendJSON(r, "PUT", "/account", gin.H{"time_zone": "UTC"}, created.Token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,294 @@
+package main
+
+import (
+	"archive/zip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// SourceAppleHealth tags weigh-ins and workouts imported from Apple Health
+const SourceAppleHealth = "apple_health"
+
+// Apple Health record types read from an export
+const (
+	healthBodyMass       = "HKQuantityTypeIdentifierBodyMass"
+	healthBodyFat        = "HKQuantityTypeIdentifierBodyFatPercentage"
+	healthActivityPrefix = "HKWorkoutActivityType"
+)
+
+// healthTimeLayout is the format of the dates in an Apple Health export
+const healthTimeLayout = "2006-01-02 15:04:05 -0700"
+
+// healthMassUnits converts the body mass units Apple Health records to
+// kilograms
+var healthMassUnits = map[string]float64{
+	"kg":  1,
+	"g":   0.001,
+	"lb":  kgPerLb,
+	"lbs": kgPerLb,
+	"st":  14 * kgPerLb,
+}
+
+// AppleHealthResult summarises an Apple Health import. Samples imported
+// before are counted as duplicates, or as updated when they have since gained
+// a body fat reading. Skipped counts samples that are invalid, in units that
+// cannot be converted, or body fat readings without a weigh-in at the same
+// moment.
+type AppleHealthResult struct {
+	Weights    int
+	Updated    int
+	Workouts   int
+	Duplicates int
+	Skipped    int
+}
+
+// xmlAttr returns the value of an element's attribute, or "" when it has none
+func xmlAttr(element xml.StartElement, name string) string {
+	for _, attr := range element.Attr {
+		if attr.Name.Local == name {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+// healthSampleID identifies an Apple Health sample by its type, the device or
+// app that recorded it and when it started
+func healthSampleID(kind, source string, start time.Time) string {
+	sum := sha1.Sum([]byte(kind + "\n" + source + "\n" + start.UTC().Format(time.RFC3339)))
+	return hex.EncodeToString(sum[:])
+}
+
+// healthActivityName turns a workout activity type such as
+// HKWorkoutActivityTypeTraditionalStrengthTraining into "Traditional Strength
+// Training"
+func healthActivityName(activity string) string {
+	var name strings.Builder
+	for i, r := range strings.TrimPrefix(activity, healthActivityPrefix) {
+		if i > 0 && unicode.IsUpper(r) {
+			name.WriteByte(' ')
+		}
+		name.WriteRune(r)
+	}
+	return name.String()
+}
+
+// parseAppleHealth streams an Apple Health export.xml, keeping only its body
+// mass, body fat and workout records so exports of hundreds of megabytes are
+// read without loading them. Body fat readings are attached to the weigh-in
+// recorded by the same source at the same moment, as smart scales do. Days are
+// taken in the user's time zone loc.
+func parseAppleHealth(r io.Reader, loc *time.Location) ([]Weight, []Workout, int, error) {
+	var weights []Weight
+	var workouts []Workout
+	bodyFat := map[string]float64{}
+	seen := map[string]bool{}
+	skipped := 0
+
+	decoder := xml.NewDecoder(r)
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, 0, fmt.Errorf("reading export: %w", err)
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Local != "Record" && element.Name.Local != "Workout" {
+			continue
+		}
+
+		kind, source := xmlAttr(element, "type"), xmlAttr(element, "sourceName")
+		if element.Name.Local == "Workout" {
+			kind = xmlAttr(element, "workoutActivityType")
+		} else if kind != healthBodyMass && kind != healthBodyFat {
+			continue
+		}
+		start, err := time.Parse(healthTimeLayout, xmlAttr(element, "startDate"))
+		if err != nil {
+			skipped++
+			continue
+		}
+		id := healthSampleID(kind, source, start)
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		switch kind {
+		case healthBodyMass:
+			value, err := strconv.ParseFloat(xmlAttr(element, "value"), 64)
+			perUnit, known := healthMassUnits[xmlAttr(element, "unit")]
+			weight := Weight{OccurredAt: &start, Weight: value * perUnit, Source: SourceAppleHealth, ExternalID: id}
+			if err != nil || !known || validateWeight(&weight, loc) != nil {
+				skipped++
+				continue
+			}
+			weights = append(weights, weight)
+		case healthBodyFat:
+			value, err := strconv.ParseFloat(xmlAttr(element, "value"), 64)
+			if err != nil {
+				skipped++
+				continue
+			}
+			// Body fat is recorded as a fraction
+			bodyFat[healthSampleID(healthBodyMass, source, start)] = roundTo(value*100, 2)
+		default:
+			end, err := time.Parse(healthTimeLayout, xmlAttr(element, "endDate"))
+			workout := Workout{Name: healthActivityName(kind), OccurredAt: &start, StartTime: &start, EndTime: &end, Source: SourceAppleHealth, ExternalID: id}
+			if err != nil || !normalizeDate(&workout.Date, workout.OccurredAt, loc) || !prepareWorkout(&workout, loc) {
+				skipped++
+				continue
+			}
+			workouts = append(workouts, workout)
+		}
+	}
+
+	for i := range weights {
+		if percent, ok := bodyFat[weights[i].ExternalID]; ok {
+			delete(bodyFat, weights[i].ExternalID)
+			weights[i].BodyFat = &percent
+			if validateWeight(&weights[i], loc) != nil {
+				weights[i].BodyFat = nil
+				skipped++
+			}
+		}
+	}
+	return weights, workouts, skipped + len(bodyFat), nil
+}
+
+// importedWeights returns the weigh-ins the user has imported from source
+// before, by external ID, including those since deleted
+func importedWeights(tx *gorm.DB, userID int, source string, weights []Weight) (map[string]Weight, error) {
+	imported := map[string]Weight{}
+	for start := 0; start < len(weights); start += csvBatchSize {
+		end := start + csvBatchSize
+		if end > len(weights) {
+			end = len(weights)
+		}
+		ids := make([]string, 0, end-start)
+		for _, weight := range weights[start:end] {
+			ids = append(ids, weight.ExternalID)
+		}
+		var batch []Weight
+		err := tx.Unscoped().Select("id", "external_id", "body_fat", "deleted_at").Where("user_id = ? AND source = ?", userID, source).
+			Where("external_id IN ?", ids).Find(&batch).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, weight := range batch {
+			imported[weight.ExternalID] = weight
+		}
+	}
+	return imported, nil
+}
+
+// importAppleHealth reads an Apple Health export for the user and adds the
+// weigh-ins and workouts not imported before in one transaction; samples
+// deleted after an earlier import stay deleted. Weigh-ins imported before that
+// have since gained a body fat reading are updated.
+func importAppleHealth(r io.Reader, userID int) (*AppleHealthResult, error) {
+	weights, workouts, skipped, err := parseAppleHealth(r, userLocation(userID))
+	if err != nil {
+		return nil, err
+	}
+	result := &AppleHealthResult{Skipped: skipped}
+
+	imported, err := importedWeights(db, userID, SourceAppleHealth, weights)
+	if err != nil {
+		return nil, err
+	}
+	var fresh, updated []Weight
+	for _, weight := range weights {
+		previous, ok := imported[weight.ExternalID]
+		switch {
+		case !ok:
+			weight.UserID = userID
+			weight.Audit = Audit{CreatedBy: userID, UpdatedBy: userID}
+			fresh = append(fresh, weight)
+		case weight.BodyFat != nil && previous.BodyFat == nil && !previous.DeletedAt.Valid:
+			previous.BodyFat = weight.BodyFat
+			updated = append(updated, previous)
+		default:
+			result.Duplicates++
+		}
+	}
+
+	found := len(workouts)
+	if workouts, err = skipImportedWorkouts(db, userID, SourceAppleHealth, workouts); err != nil {
+		return nil, err
+	}
+	result.Duplicates += found - len(workouts)
+	for i := range workouts {
+		workouts[i].UserID = userID
+	}
+	result.Weights, result.Updated, result.Workouts = len(fresh), len(updated), len(workouts)
+
+	return result, db.Transaction(func(tx *gorm.DB) error {
+		if len(fresh) > 0 {
+			if err := tx.CreateInBatches(&fresh, csvBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		for _, weight := range updated {
+			err := tx.Model(&Weight{}).Where("id = ?", weight.ID).
+				Updates(map[string]interface{}{"body_fat": weight.BodyFat, "updated_by": userID}).Error
+			if err != nil {
+				return err
+			}
+		}
+		if len(workouts) > 0 {
+			return tx.CreateInBatches(&workouts, csvBatchSize).Error
+		}
+		return nil
+	})
+}
+
+// zipEntry is a file read from inside a zip archive
+type zipEntry struct {
+	io.ReadCloser
+	archive *zip.ReadCloser
+}
+
+// Close closes the file and its archive
+func (z zipEntry) Close() error {
+	z.ReadCloser.Close()
+	return z.archive.Close()
+}
+
+// openAppleHealthExport opens an export.xml, or the export.xml inside the
+// export.zip the Health app shares
+func openAppleHealthExport(name string) (io.ReadCloser, error) {
+	if !strings.EqualFold(path.Ext(name), ".zip") {
+		return os.Open(name)
+	}
+	archive, err := zip.OpenReader(name)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range archive.File {
+		if path.Base(file.Name) != "export.xml" {
+			continue
+		}
+		entry, err := file.Open()
+		if err != nil {
+			archive.Close()
+			return nil, err
+		}
+		return zipEntry{ReadCloser: entry, archive: archive}, nil
+	}
+	archive.Close()
+	return nil, fmt.Errorf("%s has no export.xml", name)
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"archive/zip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// appleHealthExport is a trimmed Apple Health export.xml with a smart scale
+// weigh-in and body fat reading, a weigh-in in pounds, other records that are
+// ignored and a workout
+const appleHealthExport = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE HealthData [
+<!ELEMENT HealthData (ExportDate,Me,(Record|Workout)*)>
+]>
+<HealthData locale="en_US">
+ <ExportDate value="2023-10-05 09:00:00 +0100"/>
+ <Me HKCharacteristicTypeIdentifierBiologicalSex="HKBiologicalSexMale"/>
+ <Record type="HKQuantityTypeIdentifierStepCount" sourceName="iPhone" unit="count" startDate="2023-10-01 08:00:00 +0100" endDate="2023-10-01 08:10:00 +0100" value="500"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Withings" unit="kg" creationDate="2023-10-02 00:30:05 +0100" startDate="2023-10-02 00:30:00 +0100" endDate="2023-10-02 00:30:00 +0100" value="80.4">
+  <MetadataEntry key="HKWasUserEntered" value="0"/>
+ </Record>
+ <Record type="HKQuantityTypeIdentifierBodyFatPercentage" sourceName="Withings" unit="%" startDate="2023-10-02 00:30:00 +0100" endDate="2023-10-02 00:30:00 +0100" value="0.183"/>
+ <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Health" unit="lb" startDate="2023-10-03 07:00:00 +0100" endDate="2023-10-03 07:00:00 +0100" value="176"/>
+ <Record type="HKQuantityTypeIdentifierBodyFatPercentage" sourceName="Health" unit="%" startDate="2023-10-04 07:00:00 +0100" endDate="2023-10-04 07:00:00 +0100" value="0.18"/>
+ <Workout workoutActivityType="HKWorkoutActivityTypeTraditionalStrengthTraining" duration="50" durationUnit="min" sourceName="Apple Watch" startDate="2023-10-03 18:00:00 +0100" endDate="2023-10-03 18:50:00 +0100">
+  <WorkoutEvent type="HKWorkoutEventTypePause" date="2023-10-03 18:20:00 +0100"/>
+ </Workout>
+</HealthData>
+`
+
+func TestImportAppleHealth(t *testing.T) {
+	db = setupTestDB()
+
+	result, err := importAppleHealth(strings.NewReader(appleHealthExport), testUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, AppleHealthResult{Weights: 2, Workouts: 1, Skipped: 1}, *result)
+
+	var weights []Weight
+	db.Order("date ASC").Find(&weights)
+	assert.Len(t, weights, 2)
+	// 00:30 at +01:00 is still the 1st in the user's UTC time zone
+	assert.Equal(t, Date("2023-10-01"), weights[0].Date)
+	assert.Equal(t, 80.4, weights[0].Weight)
+	assert.Equal(t, 18.3, *weights[0].BodyFat)
+	assert.Equal(t, SourceAppleHealth, weights[0].Source)
+	assert.InDelta(t, 79.83, weights[1].Weight, 0.01)
+	assert.Nil(t, weights[1].BodyFat)
+
+	var workout Workout
+	db.First(&workout)
+	assert.Equal(t, "Traditional Strength Training", workout.Name)
+	assert.Equal(t, Date("2023-10-03"), workout.Date)
+	assert.Equal(t, 50, workout.Duration)
+}
+
+func TestImportAppleHealth_OnlyAddsNewSamples(t *testing.T) {
+	db = setupTestDB()
+	db.Model(&User{}).Where("id = ?", testUserID).Update("time_zone", "Europe/London")
+
+	_, err := importAppleHealth(strings.NewReader(appleHealthExport), testUserID)
+	assert.NoError(t, err)
+	var deleted Weight
+	db.Where("date = ?", "2023-10-03").First(&deleted)
+	db.Delete(&deleted)
+
+	newer := strings.Replace(appleHealthExport, "</HealthData>", ` <Record type="HKQuantityTypeIdentifierBodyMass" sourceName="Withings" unit="kg" startDate="2023-10-05 07:00:00 +0100" endDate="2023-10-05 07:00:00 +0100" value="80.1"/>
+</HealthData>`, 1)
+	result, err := importAppleHealth(strings.NewReader(newer), testUserID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Weights)
+	assert.Equal(t, 0, result.Workouts)
+	assert.Equal(t, 3, result.Duplicates)
+
+	var weights []Weight
+	db.Order("date ASC").Find(&weights)
+	assert.Len(t, weights, 2)
+	assert.Equal(t, Date("2023-10-02"), weights[0].Date)
+	assert.Equal(t, Date("2023-10-05"), weights[1].Date)
+}
+
+func TestOpenAppleHealthExport_Zip(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "export.zip")
+	file, _ := os.Create(name)
+	archive := zip.NewWriter(file)
+	entry, _ := archive.Create("apple_health_export/export.xml")
+	entry.Write([]byte(appleHealthExport))
+	archive.Close()
+	file.Close()
+
+	export, err := openAppleHealthExport(name)
+	assert.NoError(t, err)
+	defer export.Close()
+	weights, workouts, _, err := parseAppleHealth(export, time.UTC)
+	assert.NoError(t, err)
+	assert.Len(t, weights, 2)
+	assert.Len(t, workouts, 1)
+}
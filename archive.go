@@ -0,0 +1,830 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// archiveSchemaVersion is the version of the account archive format that
+// archive.schema.json describes. It is bumped whenever a change would stop a
+// server from reading an archive the way it was meant.
+const archiveSchemaVersion = 1
+
+// maxArchiveBytes limits the size of an uploaded archive, which carries the
+// user's original cardio files
+const maxArchiveBytes = 200 << 20
+
+// How POST /import/archive treats records the account already has
+const (
+	ConflictSkip = "skip"
+	ConflictFail = "fail"
+)
+
+// errArchiveVersion is returned for an archive written in a format version
+// this server does not know
+var errArchiveVersion = errors.New("unsupported archive version")
+
+// Archive is a copy of all of a user's data, for backups and for moving an
+// athlete between servers. Masses are in kilograms and timestamps in UTC. IDs
+// are those on the server the archive was exported from and only link its
+// records together; restored records are given new IDs.
+type Archive struct {
+	SchemaVersion int                 `json:"schema_version"`
+	ExportedAt    time.Time           `json:"exported_at"`
+	Account       ArchiveAccount      `json:"account"`
+	Workouts      []ArchiveWorkout    `json:"workouts"`
+	Exercises     []ArchiveExercise   `json:"exercises"`
+	Meals         []ArchiveMeal       `json:"meals"`
+	Weights       []ArchiveWeight     `json:"weights"`
+	Goals         []ArchiveGoal       `json:"goals"`
+	CardioFiles   []ArchiveCardioFile `json:"cardio_files"`
+}
+
+// ArchiveAccount holds the account's settings
+type ArchiveAccount struct {
+	Email    string `json:"email"`
+	TimeZone string `json:"time_zone"`
+	Unit     string `json:"unit"`
+}
+
+// ArchiveWorkout is a training session
+type ArchiveWorkout struct {
+	ID         int        `json:"id"`
+	Date       Date       `json:"date"`
+	OccurredAt *time.Time `json:"occurred_at"`
+	Name       string     `json:"name"`
+	StartTime  *time.Time `json:"start_time"`
+	EndTime    *time.Time `json:"end_time"`
+	Duration   int        `json:"duration_minutes"`
+	Bodyweight float64    `json:"bodyweight"`
+	Notes      string     `json:"notes"`
+	Source     string     `json:"source,omitempty"`
+	ExternalID string     `json:"external_id,omitempty"`
+}
+
+// ArchiveExercise is a logged exercise with its sets, belonging to the
+// archived workout WorkoutID when it has one
+type ArchiveExercise struct {
+	ID         int          `json:"id"`
+	WorkoutID  *int         `json:"workout_id"`
+	Position   int          `json:"position"`
+	Date       Date         `json:"date"`
+	OccurredAt *time.Time   `json:"occurred_at"`
+	Movement   string       `json:"movement"`
+	Type       string       `json:"type"`
+	Sets       []ArchiveSet `json:"sets"`
+}
+
+// ArchiveSet is one set of an exercise
+type ArchiveSet struct {
+	SetNumber int      `json:"set_number"`
+	Reps      int      `json:"reps"`
+	Load      float64  `json:"load"`
+	RPE       *float64 `json:"rpe"`
+	SetType   string   `json:"set_type"`
+}
+
+// ArchiveMeal is a logged meal with its items
+type ArchiveMeal struct {
+	ID         int               `json:"id"`
+	Date       Date              `json:"date"`
+	OccurredAt *time.Time        `json:"occurred_at"`
+	Name       string            `json:"name"`
+	Carbs      int               `json:"carbs"`
+	Protein    int               `json:"protein"`
+	Fats       int               `json:"fat"`
+	Calories   int               `json:"calories"`
+	Items      []ArchiveMealItem `json:"items"`
+}
+
+// ArchiveMealItem is a portion of a food or servings of a recipe with the
+// nutrition computed when it was logged. Foods are named by their external ID
+// in the food database and recipes by name, as catalog IDs differ between
+// servers.
+type ArchiveMealItem struct {
+	FoodExternalID string  `json:"food_external_id,omitempty"`
+	FoodName       string  `json:"food_name,omitempty"`
+	ServingName    string  `json:"serving_name,omitempty"`
+	Quantity       float64 `json:"quantity,omitempty"`
+	RecipeName     string  `json:"recipe_name,omitempty"`
+	Servings       float64 `json:"servings,omitempty"`
+	Grams          float64 `json:"grams"`
+	Carbs          float64 `json:"carbs"`
+	Protein        float64 `json:"protein"`
+	Fats           float64 `json:"fat"`
+	Calories       float64 `json:"calories"`
+}
+
+// ArchiveWeight is a weigh-in
+type ArchiveWeight struct {
+	ID         int        `json:"id"`
+	Date       Date       `json:"date"`
+	OccurredAt *time.Time `json:"occurred_at"`
+	Weight     float64    `json:"weight"`
+	BodyFat    *float64   `json:"body_fat"`
+	Source     string     `json:"source,omitempty"`
+	ExternalID string     `json:"external_id,omitempty"`
+}
+
+// ArchiveGoal is a bodyweight goal
+type ArchiveGoal struct {
+	ID           int     `json:"id"`
+	Name         string  `json:"name"`
+	Type         string  `json:"type"`
+	TargetWeight float64 `json:"target_weight"`
+	TargetDate   string  `json:"target_date"`
+}
+
+// ArchiveCardioFile is an uploaded activity file, base64 encoded. Its cardio
+// sessions are decoded from it again when the archive is restored, so
+// sessions deleted or changed since the upload are restored as decoded.
+type ArchiveCardioFile struct {
+	ID       int    `json:"id"`
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+	Data     []byte `json:"data"`
+}
+
+// archiveSets lists an exercise's sets, expanding the flat fields of
+// exercises logged before sets were recorded one by one
+func archiveSets(exercise *Exercise) []ArchiveSet {
+	details := exercise.SetDetails
+	if len(details) == 0 {
+		details = flatSets(exercise)
+	}
+	sets := []ArchiveSet{}
+	for _, set := range details {
+		sets = append(sets, ArchiveSet{SetNumber: set.SetNumber, Reps: set.Reps, Load: set.Load, RPE: set.RPE, SetType: set.SetType})
+	}
+	return sets
+}
+
+// archiveMealItems converts meals' items, naming the foods, servings and
+// recipes they refer to
+func archiveMealItems(meals []Meal) (map[int][]ArchiveMealItem, error) {
+	var foodIDs, servingIDs, recipeIDs []int
+	for _, meal := range meals {
+		for _, item := range meal.Items {
+			if item.FoodID != nil {
+				foodIDs = append(foodIDs, *item.FoodID)
+			}
+			if item.ServingID != nil {
+				servingIDs = append(servingIDs, *item.ServingID)
+			}
+			if item.RecipeID != nil {
+				recipeIDs = append(recipeIDs, *item.RecipeID)
+			}
+		}
+	}
+
+	// Foods and recipes deleted since are still named
+	var foods []Food
+	var servings []FoodServing
+	var recipes []Recipe
+	if err := db.Unscoped().Select("id", "external_id", "name").Where("id IN ?", foodIDs).Find(&foods).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Select("id", "name").Where("id IN ?", servingIDs).Find(&servings).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Unscoped().Select("id", "name").Where("id IN ?", recipeIDs).Find(&recipes).Error; err != nil {
+		return nil, err
+	}
+	foodByID, servingNames, recipeNames := map[int]Food{}, map[int]string{}, map[int]string{}
+	for _, food := range foods {
+		foodByID[food.ID] = food
+	}
+	for _, serving := range servings {
+		servingNames[serving.ID] = serving.Name
+	}
+	for _, recipe := range recipes {
+		recipeNames[recipe.ID] = recipe.Name
+	}
+
+	items := map[int][]ArchiveMealItem{}
+	for _, meal := range meals {
+		items[meal.ID] = []ArchiveMealItem{}
+		for _, item := range meal.Items {
+			archived := ArchiveMealItem{
+				Quantity: item.Quantity, Servings: item.Servings, Grams: item.Grams,
+				Carbs: item.Carbs, Protein: item.Protein, Fats: item.Fats, Calories: item.Calories,
+			}
+			if item.FoodID != nil {
+				archived.FoodExternalID, archived.FoodName = foodByID[*item.FoodID].ExternalID, foodByID[*item.FoodID].Name
+			}
+			if item.ServingID != nil {
+				archived.ServingName = servingNames[*item.ServingID]
+			}
+			if item.RecipeID != nil {
+				archived.RecipeName = recipeNames[*item.RecipeID]
+			}
+			items[meal.ID] = append(items[meal.ID], archived)
+		}
+	}
+	return items, nil
+}
+
+// buildArchive collects all of the user's data into an archive
+func buildArchive(userID int) (*Archive, error) {
+	var user User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	owned := func() *gorm.DB {
+		return db.Where("user_id = ?", userID).Order("id ASC")
+	}
+
+	var workouts []Workout
+	var exercises []Exercise
+	var meals []Meal
+	var weights []Weight
+	var goals []Goal
+	var files []CardioFile
+	err := owned().Find(&workouts).Error
+	if err == nil {
+		err = preloadSets(owned()).Find(&exercises).Error
+	}
+	if err == nil {
+		err = preloadItems(owned()).Find(&meals).Error
+	}
+	for _, dest := range []interface{}{&weights, &goals, &files} {
+		if err == nil {
+			err = owned().Find(dest).Error
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	items, err := archiveMealItems(meals)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &Archive{
+		SchemaVersion: archiveSchemaVersion,
+		ExportedAt:    time.Now().UTC().Truncate(time.Second),
+		Account:       ArchiveAccount{Email: user.Email, TimeZone: user.TimeZone, Unit: user.Unit},
+		Workouts:      []ArchiveWorkout{},
+		Exercises:     []ArchiveExercise{},
+		Meals:         []ArchiveMeal{},
+		Weights:       []ArchiveWeight{},
+		Goals:         []ArchiveGoal{},
+		CardioFiles:   []ArchiveCardioFile{},
+	}
+	for _, w := range workouts {
+		archive.Workouts = append(archive.Workouts, ArchiveWorkout{
+			ID: w.ID, Date: w.Date, OccurredAt: w.OccurredAt, Name: w.Name, StartTime: w.StartTime, EndTime: w.EndTime,
+			Duration: w.Duration, Bodyweight: w.Bodyweight, Notes: w.Notes, Source: w.Source, ExternalID: w.ExternalID,
+		})
+	}
+	for _, e := range exercises {
+		archive.Exercises = append(archive.Exercises, ArchiveExercise{
+			ID: e.ID, WorkoutID: e.WorkoutID, Position: e.Position, Date: e.Date, OccurredAt: e.OccurredAt,
+			Movement: e.Movement, Type: e.Type, Sets: archiveSets(&e),
+		})
+	}
+	for _, m := range meals {
+		archive.Meals = append(archive.Meals, ArchiveMeal{
+			ID: m.ID, Date: m.Date, OccurredAt: m.OccurredAt, Name: m.Name,
+			Carbs: m.Carbs, Protein: m.Protein, Fats: m.Fats, Calories: m.Calories, Items: items[m.ID],
+		})
+	}
+	for _, w := range weights {
+		archive.Weights = append(archive.Weights, ArchiveWeight{
+			ID: w.ID, Date: w.Date, OccurredAt: w.OccurredAt, Weight: w.Weight, BodyFat: w.BodyFat, Source: w.Source, ExternalID: w.ExternalID,
+		})
+	}
+	for _, g := range goals {
+		archive.Goals = append(archive.Goals, ArchiveGoal{ID: g.ID, Name: g.Name, Type: g.Type, TargetWeight: g.TargetWeight, TargetDate: g.TargetDate})
+	}
+	for _, f := range files {
+		archive.CardioFiles = append(archive.CardioFiles, ArchiveCardioFile{ID: f.ID, Filename: f.Filename, Format: f.Format, Data: f.Data})
+	}
+	return archive, nil
+}
+
+// ArchiveCounts counts records of each kind in an archive
+type ArchiveCounts struct {
+	Workouts    int `json:"workouts"`
+	Exercises   int `json:"exercises"`
+	Meals       int `json:"meals"`
+	Weights     int `json:"weights"`
+	Goals       int `json:"goals"`
+	CardioFiles int `json:"cardio_files"`
+}
+
+// total is the number of records counted
+func (a ArchiveCounts) total() int {
+	return a.Workouts + a.Exercises + a.Meals + a.Weights + a.Goals + a.CardioFiles
+}
+
+// ArchiveImportResult reports a restore: the records added, the records left
+// out because the account already has them and the errors found. Nothing is
+// saved unless every record is valid.
+type ArchiveImportResult struct {
+	DryRun    bool          `json:"dry_run"`
+	Imported  ArchiveCounts `json:"imported"`
+	Conflicts ArchiveCounts `json:"conflicts"`
+	Errors    []gin.H       `json:"errors"`
+}
+
+// archiveError is the error reported for an invalid archived record: the
+// validator's error response with the section and archived ID of the record
+func archiveError(section string, id int, problem gin.H) gin.H {
+	record := gin.H{"section": section, "id": id}
+	for key, value := range problem {
+		record[key] = value
+	}
+	return record
+}
+
+// archiveKey joins the values identifying a record, so a record restored
+// into an account that already has it is recognised whatever its ID
+func archiveKey(values ...interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		switch v := value.(type) {
+		case *time.Time:
+			if v != nil {
+				parts[i] = v.UTC().Format(time.RFC3339)
+			}
+		case string:
+			parts[i] = strings.ToLower(strings.TrimSpace(v))
+		default:
+			parts[i] = fmt.Sprint(v)
+		}
+	}
+	return strings.Join(parts, "\x1f")
+}
+
+// archiveKey identifies a workout by its date, start time and name
+func (w *Workout) archiveKey() string {
+	return archiveKey("workout", w.Date, w.StartTime, w.Name)
+}
+
+// archiveKey identifies an exercise by when it was logged, its movement and
+// its sets, reps and weight
+func (e *Exercise) archiveKey() string {
+	return archiveKey("exercise", e.Date, e.OccurredAt, e.Movement, e.Sets, e.Reps, e.Weight)
+}
+
+// archiveKey identifies a meal by when it was eaten, its name and calories
+func (m *Meal) archiveKey() string {
+	return archiveKey("meal", m.Date, m.OccurredAt, m.Name, m.Calories)
+}
+
+// archiveKey identifies a weigh-in by when it was taken and the weight
+func (w *Weight) archiveKey() string {
+	return archiveKey("weight", w.Date, w.OccurredAt, w.Weight)
+}
+
+// archiveKey identifies a goal by its name, type, target date and weight
+func (g *Goal) archiveKey() string {
+	return archiveKey("goal", g.Name, g.Type, g.TargetDate, g.TargetWeight)
+}
+
+// archiveKey identifies a cardio file by the SHA-256 of its contents
+func (f *CardioFile) archiveKey() string {
+	return archiveKey("cardio", f.SHA256)
+}
+
+// existingRecords returns the keys of the records the user already has,
+// mapped to their IDs
+func existingRecords(userID int) (map[string]int, error) {
+	var workouts []Workout
+	var exercises []Exercise
+	var meals []Meal
+	var weights []Weight
+	var goals []Goal
+	var files []CardioFile
+	for _, dest := range []interface{}{&workouts, &exercises, &meals, &weights, &goals} {
+		if err := db.Where("user_id = ?", userID).Find(dest).Error; err != nil {
+			return nil, err
+		}
+	}
+	if err := db.Select("id", "sha256").Where("user_id = ?", userID).Find(&files).Error; err != nil {
+		return nil, err
+	}
+
+	keys := map[string]int{}
+	for _, w := range workouts {
+		keys[w.archiveKey()] = w.ID
+	}
+	for _, e := range exercises {
+		keys[e.archiveKey()] = e.ID
+	}
+	for _, m := range meals {
+		keys[m.archiveKey()] = m.ID
+	}
+	for _, w := range weights {
+		keys[w.archiveKey()] = w.ID
+	}
+	for _, g := range goals {
+		keys[g.archiveKey()] = g.ID
+	}
+	for _, f := range files {
+		keys[f.archiveKey()] = f.ID
+	}
+	return keys, nil
+}
+
+// validateRestored runs a validator on an archived record without comparing
+// its date with the day of its timestamp: that was checked when the record was
+// logged, against the time zone the user had then. The timestamp is stored in
+// UTC. Records without a date, whose free-form date could not be converted
+// when dates were migrated, are checked otherwise and restored undated.
+func validateRestored(date *Date, occurredAt **time.Time, validate func() gin.H) gin.H {
+	at, undated := *occurredAt, *date == ""
+	*occurredAt = nil
+	if undated {
+		*date = Date(time.Time{}.Format(dateLayout))
+	}
+	problem := validate()
+	if undated {
+		*date = ""
+	}
+	if at != nil {
+		utc := at.UTC()
+		at = &utc
+	}
+	*occurredAt = at
+	return problem
+}
+
+// mealItemLinks finds the foods and the user's recipes this server has for
+// the items of archived meals, by food external ID and recipe name
+func mealItemLinks(meals []ArchiveMeal, userID int) (map[string]Food, map[string]int, error) {
+	var externalIDs, recipeNames []string
+	for _, meal := range meals {
+		for _, item := range meal.Items {
+			if item.FoodExternalID != "" {
+				externalIDs = append(externalIDs, item.FoodExternalID)
+			}
+			if item.RecipeName != "" {
+				recipeNames = append(recipeNames, item.RecipeName)
+			}
+		}
+	}
+
+	var foods []Food
+	var recipes []Recipe
+	if err := db.Preload("Servings").Where("external_id IN ?", externalIDs).Find(&foods).Error; err != nil {
+		return nil, nil, err
+	}
+	if err := db.Select("id", "name").Where("user_id = ? AND name IN ?", userID, recipeNames).Find(&recipes).Error; err != nil {
+		return nil, nil, err
+	}
+	foodByExternalID, recipeIDs := map[string]Food{}, map[string]int{}
+	for _, food := range foods {
+		foodByExternalID[food.ExternalID] = food
+	}
+	for _, recipe := range recipes {
+		recipeIDs[recipe.Name] = recipe.ID
+	}
+	return foodByExternalID, recipeIDs, nil
+}
+
+// restoreMealItems converts an archived meal's items, linking them to this
+// server's foods, servings and recipes where it has them. Their nutrition is
+// kept as it was logged.
+func restoreMealItems(meal ArchiveMeal, foods map[string]Food, recipes map[string]int) ([]MealItem, bool) {
+	var items []MealItem
+	for _, archived := range meal.Items {
+		item := MealItem{
+			Quantity: archived.Quantity, Servings: archived.Servings, Grams: archived.Grams,
+			Carbs: archived.Carbs, Protein: archived.Protein, Fats: archived.Fats, Calories: archived.Calories,
+		}
+		if food, ok := foods[archived.FoodExternalID]; ok && archived.FoodExternalID != "" {
+			item.FoodID = &food.ID
+			for _, serving := range food.Servings {
+				if archived.ServingName != "" && serving.Name == archived.ServingName {
+					item.ServingID = &serving.ID
+				}
+			}
+		}
+		if id, ok := recipes[archived.RecipeName]; ok {
+			item.RecipeID = &id
+		}
+		if item.Grams < 0 || item.Carbs < 0 || item.Protein < 0 || item.Fats < 0 || item.Calories < 0 {
+			return nil, false
+		}
+		items = append(items, item)
+	}
+	return items, true
+}
+
+// restoreArchive checks an archive and, unless it is a dry run or any record
+// is invalid, adds its records to the user's account in one transaction. IDs
+// are remapped so exercises stay in their workouts. Records the account
+// already has are skipped, or with onConflict set to ConflictFail nothing is
+// saved when there are any. The account's time zone and unit are taken from
+// the archive when it has none.
+func restoreArchive(archive *Archive, userID, createdBy int, onConflict string, dryRun bool) (*ArchiveImportResult, error) {
+	if archive.SchemaVersion < 1 || archive.SchemaVersion > archiveSchemaVersion {
+		return nil, errArchiveVersion
+	}
+	var user User
+	if err := db.First(&user, userID).Error; err != nil {
+		return nil, err
+	}
+	result := &ArchiveImportResult{DryRun: dryRun, Errors: []gin.H{}}
+	audit := Audit{CreatedBy: createdBy, UpdatedBy: createdBy}
+
+	settings := map[string]interface{}{}
+	if user.TimeZone == "" && archive.Account.TimeZone != "" {
+		if _, err := loadTimeZone(archive.Account.TimeZone); err != nil {
+			result.Errors = append(result.Errors, archiveError("account", 0, gin.H{"error": "Invalid time zone", "field": "time_zone", "value": archive.Account.TimeZone}))
+		}
+		settings["time_zone"], user.TimeZone = archive.Account.TimeZone, archive.Account.TimeZone
+	}
+	if user.Unit == "" && archive.Account.Unit != "" {
+		if !validUnit(archive.Account.Unit) {
+			result.Errors = append(result.Errors, archiveError("account", 0, gin.H{"error": "Invalid unit", "field": "unit", "value": archive.Account.Unit}))
+		}
+		settings["unit"] = archive.Account.Unit
+	}
+	loc, err := loadTimeZone(user.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+
+	existing, err := existingRecords(userID)
+	if err != nil {
+		return nil, err
+	}
+	foods, recipes, err := mealItemLinks(archive.Meals, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Archived workout IDs map to the workouts restored from them, or to the
+	// workouts the account already has
+	workoutIDs := map[int]int{}
+	var workouts []Workout
+	var workoutSources []int
+	for _, archived := range archive.Workouts {
+		workout := Workout{
+			UserID: userID, Date: archived.Date, OccurredAt: archived.OccurredAt, Name: archived.Name,
+			StartTime: archived.StartTime, EndTime: archived.EndTime, Duration: archived.Duration,
+			Bodyweight: archived.Bodyweight, Notes: archived.Notes, Source: archived.Source, ExternalID: archived.ExternalID,
+		}
+		problem := validateRestored(&workout.Date, &workout.OccurredAt, func() gin.H {
+			switch {
+			case !normalizeDate(&workout.Date, workout.OccurredAt, loc):
+				return dateFieldError(workout.Date, workout.OccurredAt)
+			case !prepareWorkout(&workout, loc):
+				return gin.H{"error": "Invalid input values"}
+			}
+			return nil
+		})
+		if problem != nil {
+			result.Errors = append(result.Errors, archiveError("workouts", archived.ID, problem))
+			continue
+		}
+		if id, ok := existing[workout.archiveKey()]; ok {
+			workoutIDs[archived.ID] = id
+			result.Conflicts.Workouts++
+			continue
+		}
+		workoutIDs[archived.ID] = 0
+		workouts = append(workouts, workout)
+		workoutSources = append(workoutSources, archived.ID)
+	}
+
+	var exercises []Exercise
+	var exerciseWorkouts []*int
+	for _, archived := range archive.Exercises {
+		exercise := Exercise{
+			UserID: userID, Audit: audit, Date: archived.Date, OccurredAt: archived.OccurredAt,
+			Movement: archived.Movement, Type: archived.Type, Position: archived.Position,
+		}
+		for _, set := range archived.Sets {
+			exercise.SetDetails = append(exercise.SetDetails, ExerciseSet{SetNumber: set.SetNumber, Reps: set.Reps, Load: set.Load, RPE: set.RPE, SetType: set.SetType})
+		}
+		problem := validateRestored(&exercise.Date, &exercise.OccurredAt, func() gin.H { return validateExercise(&exercise, loc) })
+		if problem == nil && archived.WorkoutID != nil {
+			if _, ok := workoutIDs[*archived.WorkoutID]; !ok {
+				problem = gin.H{"error": "Unknown workout", "field": "workout_id", "value": *archived.WorkoutID}
+			}
+		}
+		if problem != nil {
+			result.Errors = append(result.Errors, archiveError("exercises", archived.ID, problem))
+			continue
+		}
+		if _, ok := existing[exercise.archiveKey()]; ok {
+			result.Conflicts.Exercises++
+			continue
+		}
+		exercises = append(exercises, exercise)
+		exerciseWorkouts = append(exerciseWorkouts, archived.WorkoutID)
+	}
+
+	var meals []Meal
+	for _, archived := range archive.Meals {
+		meal := Meal{
+			UserID: userID, Audit: audit, Date: archived.Date, OccurredAt: archived.OccurredAt, Name: archived.Name,
+			Carbs: archived.Carbs, Protein: archived.Protein, Fats: archived.Fats, Calories: archived.Calories,
+		}
+		items, validItems := restoreMealItems(archived, foods, recipes)
+		meal.Items = items
+		// Meals keep the nutrition they were logged with rather than being
+		// checked against today's foods and calorie rules
+		problem := validateRestored(&meal.Date, &meal.OccurredAt, func() gin.H {
+			switch {
+			case !normalizeDate(&meal.Date, meal.OccurredAt, loc):
+				return dateFieldError(meal.Date, meal.OccurredAt)
+			case !validItems:
+				return gin.H{"error": "Invalid meal items"}
+			case meal.Carbs < 0 || meal.Fats < 0 || meal.Protein < 0 || meal.Calories < 0:
+				return gin.H{"error": "Invalid input values"}
+			}
+			return nil
+		})
+		if problem != nil {
+			result.Errors = append(result.Errors, archiveError("meals", archived.ID, problem))
+			continue
+		}
+		if _, ok := existing[meal.archiveKey()]; ok {
+			result.Conflicts.Meals++
+			continue
+		}
+		meals = append(meals, meal)
+	}
+
+	var weights []Weight
+	for _, archived := range archive.Weights {
+		weight := Weight{
+			UserID: userID, Audit: audit, Date: archived.Date, OccurredAt: archived.OccurredAt,
+			Weight: archived.Weight, BodyFat: archived.BodyFat, Source: archived.Source, ExternalID: archived.ExternalID,
+		}
+		if problem := validateRestored(&weight.Date, &weight.OccurredAt, func() gin.H { return validateWeight(&weight, loc) }); problem != nil {
+			result.Errors = append(result.Errors, archiveError("weights", archived.ID, problem))
+			continue
+		}
+		if _, ok := existing[weight.archiveKey()]; ok {
+			result.Conflicts.Weights++
+			continue
+		}
+		weights = append(weights, weight)
+	}
+
+	var goals []Goal
+	for _, archived := range archive.Goals {
+		goal := Goal{UserID: userID, Name: archived.Name, Type: archived.Type, TargetWeight: archived.TargetWeight, TargetDate: archived.TargetDate}
+		if !validGoal(&goal) {
+			result.Errors = append(result.Errors, archiveError("goals", archived.ID, gin.H{"error": "Invalid input values"}))
+			continue
+		}
+		if _, ok := existing[goal.archiveKey()]; ok {
+			result.Conflicts.Goals++
+			continue
+		}
+		goals = append(goals, goal)
+	}
+
+	var files []CardioFile
+	var decodedFiles [][]CardioSession
+	for _, archived := range archive.CardioFiles {
+		format, decoded, err := decodeActivityFile(archived.Data)
+		if err != nil {
+			result.Errors = append(result.Errors, archiveError("cardio_files", archived.ID, gin.H{"error": "Invalid activity file", "message": err.Error()}))
+			continue
+		}
+		sum := sha256.Sum256(archived.Data)
+		file := CardioFile{UserID: userID, Filename: archived.Filename, Format: format, SHA256: hex.EncodeToString(sum[:]), Data: archived.Data}
+		if _, ok := existing[file.archiveKey()]; ok {
+			result.Conflicts.CardioFiles++
+			continue
+		}
+		// A second copy of the file in the archive is a conflict with the first
+		existing[file.archiveKey()] = 0
+		files = append(files, file)
+		decodedFiles = append(decodedFiles, decoded)
+	}
+
+	result.Imported = ArchiveCounts{
+		Workouts: len(workouts), Exercises: len(exercises), Meals: len(meals),
+		Weights: len(weights), Goals: len(goals), CardioFiles: len(files),
+	}
+	if len(result.Errors) > 0 || dryRun || onConflict == ConflictFail && result.Conflicts.total() > 0 {
+		return result, nil
+	}
+
+	return result, db.Transaction(func(tx *gorm.DB) error {
+		if len(settings) > 0 {
+			if err := tx.Model(&User{}).Where("id = ?", userID).Updates(settings).Error; err != nil {
+				return err
+			}
+		}
+		for i := range workouts {
+			if err := tx.Create(&workouts[i]).Error; err != nil {
+				return err
+			}
+			workoutIDs[workoutSources[i]] = workouts[i].ID
+		}
+		for i, archivedID := range exerciseWorkouts {
+			if archivedID != nil {
+				id := workoutIDs[*archivedID]
+				exercises[i].WorkoutID = &id
+			}
+		}
+		if len(exercises) > 0 {
+			if err := tx.CreateInBatches(&exercises, csvBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(meals) > 0 {
+			if err := tx.CreateInBatches(&meals, csvBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(weights) > 0 {
+			if err := tx.CreateInBatches(&weights, csvBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(goals) > 0 {
+			if err := tx.CreateInBatches(&goals, csvBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		for i := range files {
+			if err := tx.Create(&files[i]).Error; err != nil {
+				return err
+			}
+			if _, err := saveCardioSessions(tx, &files[i], decodedFiles[i], loc); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// exportArchive handles GET /export/archive, downloading all of the user's
+// data as one versioned JSON document
+func exportArchive(c *gin.Context) {
+	archive, err := buildArchive(currentUserID(c))
+	if err != nil {
+		log.Println("Archive export failed:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export archive"})
+		return
+	}
+	filename := fmt.Sprintf("archive-%s.json", archive.ExportedAt.Format(dateLayout))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.JSON(http.StatusOK, archive)
+}
+
+// importArchive handles POST /import/archive with an archive from GET
+// /export/archive, as the request body or a multipart "file".
+// ?on_conflict=fail refuses archives holding records the account already
+// has instead of skipping them, and ?dry_run=true only checks.
+func importArchive(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+		return
+	}
+	onConflict := c.DefaultQuery("on_conflict", ConflictSkip)
+	if onConflict != ConflictSkip && onConflict != ConflictFail {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid on_conflict"})
+		return
+	}
+
+	var archive Archive
+	err = readUploadLimit(c, maxArchiveBytes, func(body io.Reader) error {
+		return json.NewDecoder(body).Decode(&archive)
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid archive", "message": err.Error()})
+		return
+	}
+
+	result, err := restoreArchive(&archive, currentUserID(c), actingUserID(c), onConflict, dryRun)
+	switch {
+	case err == errArchiveVersion:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported archive version", "schema_version": archive.SchemaVersion})
+	case err != nil:
+		log.Println("DB Insert Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import archive"})
+	case len(result.Errors) > 0:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case onConflict == ConflictFail && result.Conflicts.total() > 0:
+		c.JSON(http.StatusConflict, result)
+	case result.DryRun:
+		c.JSON(http.StatusOK, result)
+	default:
+		c.JSON(http.StatusCreated, result)
+	}
+}
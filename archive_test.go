@@ -0,0 +1,254 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// getArchive downloads the test user's archive
+func getArchive(t *testing.T) Archive {
+	r := setupRouter()
+	req, _ := http.NewRequest("GET", "/export/archive", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "archive-")
+
+	var archive Archive
+	err := json.Unmarshal(w.Body.Bytes(), &archive)
+	assert.NoError(t, err)
+	return archive
+}
+
+func TestArchive_RestoresIntoAnotherAccount(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	oats := Food{ExternalID: "fdc-173904", Name: "Rolled Oats", Carbs: 66, Protein: 17, Fats: 7, Calories: 380}
+	db.Create(&oats)
+
+	w := sendJSON(r, "POST", "/workouts", gin.H{
+		"date": "2023-10-01", "name": "Push", "start_time": "2023-10-01T17:00:00Z", "end_time": "2023-10-01T18:00:00Z",
+		"exercises": []gin.H{{"movement": "Bench Press", "sets": []gin.H{{"reps": 5, "load": 100}, {"reps": 5, "load": 100, "rpe": 8}}}},
+	}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSON(r, "POST", "/exercises", gin.H{"date": "2023-10-02", "movement": "Squat", "sets": 3, "reps": 5, "weight": 140}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSON(r, "POST", "/meals", gin.H{"date": "2023-10-01", "name": "Breakfast", "items": []gin.H{{"food_id": oats.ID, "grams": 80}}}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	w = sendJSON(r, "POST", "/weights", gin.H{"date": "2023-10-01", "weight": 80.4, "body_fat": 18.3}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	db.Create(&Goal{UserID: testUserID, Name: "Summer cut", Type: GoalTypeCut, TargetWeight: 75, TargetDate: "2024-06-01"})
+	assert.Equal(t, http.StatusCreated, postCSV("/cardio?filename=run.fit", string(testRunFIT())).Code)
+	db.Model(&User{}).Where("id = ?", testUserID).Updates(map[string]interface{}{"time_zone": "Europe/London", "unit": UnitLb})
+
+	archive := getArchive(t)
+	assert.Equal(t, archiveSchemaVersion, archive.SchemaVersion)
+	assert.Equal(t, ArchiveAccount{Email: "test@example.com", TimeZone: "Europe/London", Unit: UnitLb}, archive.Account)
+	assert.Len(t, archive.Workouts, 1)
+	assert.Len(t, archive.Exercises, 2)
+	assert.Equal(t, archive.Workouts[0].ID, *archive.Exercises[0].WorkoutID)
+	assert.Len(t, archive.Exercises[1].Sets, 3)
+	assert.Equal(t, "fdc-173904", archive.Meals[0].Items[0].FoodExternalID)
+	assert.Equal(t, 52.8, archive.Meals[0].Items[0].Carbs)
+	assert.Equal(t, 18.3, *archive.Weights[0].BodyFat)
+	assert.Len(t, archive.Goals, 1)
+	assert.Equal(t, testRunFIT(), archive.CardioFiles[0].Data)
+
+	other := User{Email: "other@example.com"}
+	db.Create(&other)
+	result, err := restoreArchive(&archive, other.ID, other.ID, ConflictSkip, false)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, ArchiveCounts{Workouts: 1, Exercises: 2, Meals: 1, Weights: 1, Goals: 1, CardioFiles: 1}, result.Imported)
+
+	var workout Workout
+	preloadWorkoutExercises(db).Where("user_id = ?", other.ID).First(&workout)
+	assert.NotEqual(t, archive.Workouts[0].ID, workout.ID)
+	assert.Equal(t, 60, workout.Duration)
+	assert.Len(t, workout.Exercises, 1)
+	assert.Equal(t, "Bench Press", workout.Exercises[0].Movement)
+	assert.Equal(t, 8.0, *workout.Exercises[0].SetDetails[1].RPE)
+	assert.Equal(t, other.ID, workout.Exercises[0].CreatedBy)
+
+	var meal Meal
+	preloadItems(db).Where("user_id = ?", other.ID).First(&meal)
+	assert.Equal(t, oats.ID, *meal.Items[0].FoodID)
+	assert.Equal(t, 304.0, meal.Items[0].Calories)
+
+	var session CardioSession
+	preloadLaps(db).Where("user_id = ?", other.ID).First(&session)
+	assert.Equal(t, 1800.0, session.Distance)
+	assert.Len(t, session.Laps, 2)
+	assert.Equal(t, "Europe/London", userLocation(other.ID).String())
+	assert.Equal(t, UnitLb, userUnit(other.ID))
+
+	// Restoring the same archive again finds everything already there
+	result, err = restoreArchive(&archive, other.ID, other.ID, ConflictSkip, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Imported.total())
+	assert.Equal(t, ArchiveCounts{Workouts: 1, Exercises: 2, Meals: 1, Weights: 1, Goals: 1, CardioFiles: 1}, result.Conflicts)
+}
+
+func TestImportArchive_Conflicts(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	sendJSON(r, "POST", "/weights", gin.H{"date": "2023-10-01", "weight": 80}, "")
+	archive := getArchive(t)
+
+	w := sendJSON(r, "POST", "/import/archive?on_conflict=fail", archive, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Contains(t, w.Body.String(), `"conflicts":{"workouts":0,"exercises":0,"meals":0,"weights":1`)
+
+	// Dates are kept as logged, even when the account is now in a time
+	// zone where the weigh-in falls on another day
+	db.Model(&User{}).Where("id = ?", testUserID).Update("time_zone", "Asia/Tokyo")
+	at := time.Date(2023, 10, 2, 23, 30, 0, 0, time.UTC)
+	archive.Weights = append(archive.Weights, ArchiveWeight{ID: 7, Date: "2023-10-02", OccurredAt: &at, Weight: 79.6})
+	w = sendJSON(r, "POST", "/import/archive?dry_run=true", archive, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendJSON(r, "POST", "/import/archive", archive, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result ArchiveImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 1, result.Imported.Weights)
+	assert.Equal(t, 1, result.Conflicts.Weights)
+
+	var weights []Weight
+	db.Order("id ASC").Find(&weights)
+	assert.Len(t, weights, 2)
+	assert.Equal(t, Date("2023-10-02"), weights[1].Date)
+}
+
+func TestImportArchive_RejectsInvalidArchives(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	workoutID := 3
+
+	w := sendJSON(r, "POST", "/import/archive", gin.H{"schema_version": 99}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unsupported archive version")
+
+	w = sendJSON(r, "POST", "/import/archive", Archive{
+		SchemaVersion: archiveSchemaVersion,
+		Exercises:     []ArchiveExercise{{ID: 1, WorkoutID: &workoutID, Date: "2023-10-01", Movement: "Squat", Sets: []ArchiveSet{{Reps: 5, Load: 100}}}},
+		Weights:       []ArchiveWeight{{ID: 2, Date: "10/01/2023", Weight: 80}, {ID: 3, Date: "2023-10-01", Weight: 80}},
+	}, "")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), `"error":"Unknown workout"`)
+	assert.Contains(t, w.Body.String(), `"section":"weights","value":"10/01/2023"`)
+
+	var count int64
+	db.Model(&Weight{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestArchive_RestoresUndatedLegacyRows(t *testing.T) {
+	db = setupTestDB()
+	// Dates that could not be converted when dates were migrated are cleared
+	db.Create(&Weight{UserID: testUserID, Weight: 80})
+	db.Omit("SetDetails").Create(&Exercise{UserID: testUserID, Movement: "Squat", Sets: 3, Reps: 5, Weight: 100})
+	db.Create(&Meal{UserID: testUserID, Name: "Breakfast", Calories: 500})
+	archive := getArchive(t)
+	assert.Equal(t, Date(""), archive.Weights[0].Date)
+
+	other := User{Email: "other@example.com"}
+	db.Create(&other)
+	result, err := restoreArchive(&archive, other.ID, other.ID, ConflictFail, false)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Errors)
+	assert.Equal(t, ArchiveCounts{Exercises: 1, Meals: 1, Weights: 1}, result.Imported)
+
+	var weight Weight
+	db.Where("user_id = ?", other.ID).First(&weight)
+	assert.Equal(t, Date(""), weight.Date)
+	assert.Equal(t, 80.0, weight.Weight)
+	var exercise Exercise
+	preloadSets(db).Where("user_id = ?", other.ID).First(&exercise)
+	assert.Equal(t, Date(""), exercise.Date)
+	assert.Len(t, exercise.SetDetails, 3)
+}
+
+func TestArchive_DecodesCardioSessionsAgain(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	start := time.Date(2023, 10, 1, 6, 0, 0, 0, time.UTC)
+	var messages []fitTestMessage
+	for i := 0; i < 2; i++ {
+		messages = append(messages, fitTestMessage{global: fitSession, fields: []fitTestField{
+			{2, 4, fitSeconds(start.Add(time.Duration(i) * time.Hour))}, {5, 1, 1}, {7, 4, 600000}, {9, 4, 180000},
+		}})
+	}
+	assert.Equal(t, http.StatusCreated, postCSV("/cardio?filename=brick.fit", string(encodeFIT(messages))).Code)
+
+	// Deleting one of the file's sessions and changing the other are not
+	// kept: both sessions come back as decoded from the file
+	w := sendJSON(r, "DELETE", "/cardio/2", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.Model(&CardioSession{}).Where("id = ?", 1).Update("distance", 1)
+	archive := getArchive(t)
+
+	other := User{Email: "other@example.com"}
+	db.Create(&other)
+	result, err := restoreArchive(&archive, other.ID, other.ID, ConflictSkip, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported.CardioFiles)
+
+	var sessions []CardioSession
+	db.Where("user_id = ?", other.ID).Order("occurred_at").Find(&sessions)
+	assert.Len(t, sessions, 2)
+	for _, session := range sessions {
+		assert.Equal(t, 1800.0, session.Distance)
+	}
+}
+
+func TestArchiveSchema_DescribesArchive(t *testing.T) {
+	data, err := os.ReadFile("archive.schema.json")
+	assert.NoError(t, err)
+	var schema struct {
+		Properties map[string]json.RawMessage `json:"properties"`
+		Defs       map[string]struct {
+			Properties map[string]json.RawMessage `json:"properties"`
+		} `json:"$defs"`
+	}
+	assert.NoError(t, json.Unmarshal(data, &schema))
+
+	var version struct {
+		Const int `json:"const"`
+	}
+	json.Unmarshal(schema.Properties["schema_version"], &version)
+	assert.Equal(t, archiveSchemaVersion, version.Const)
+
+	// Every field the archive is written with is described
+	described := func(properties map[string]json.RawMessage, value interface{}) {
+		data, _ := json.Marshal(value)
+		var fields map[string]interface{}
+		json.Unmarshal(data, &fields)
+		for field := range fields {
+			assert.Contains(t, properties, field)
+		}
+	}
+	described(schema.Properties, Archive{})
+	samples := map[string]interface{}{
+		"account":     ArchiveAccount{},
+		"workout":     ArchiveWorkout{Source: SourceStrong, ExternalID: "id"},
+		"exercise":    ArchiveExercise{},
+		"set":         ArchiveSet{},
+		"meal":        ArchiveMeal{},
+		"meal_item":   ArchiveMealItem{FoodExternalID: "id", FoodName: "Oats", ServingName: "cup", Quantity: 1, RecipeName: "Chili", Servings: 1},
+		"weight":      ArchiveWeight{Source: SourceAppleHealth, ExternalID: "id"},
+		"goal":        ArchiveGoal{},
+		"cardio_file": ArchiveCardioFile{},
+	}
+	for name, sample := range samples {
+		assert.Contains(t, schema.Defs, name)
+		described(schema.Defs[name].Properties, sample)
+	}
+}
@@ -0,0 +1,253 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Gin context keys holding the user whose data a request works on and, when
+// a coach acts for an athlete, the coach making the request
+const (
+	userIDKey  = "userID"
+	actorIDKey = "actorID"
+)
+
+// tokenScopesKey is the gin context key holding the scopes of the personal
+// API token a request was made with
+const tokenScopesKey = "tokenScopes"
+
+// errInvalidToken is returned for access tokens that are malformed, wrongly
+// signed or expired
+var errInvalidToken = errors.New("invalid token")
+
+var (
+	generatedSecret     []byte
+	generatedSecretOnce sync.Once
+)
+
+// jwtSecret returns the access token signing key from JWT_SECRET. Without
+// one a random key is generated, so tokens only last until restart.
+func jwtSecret() []byte {
+	if secret := envString("JWT_SECRET", ""); secret != "" {
+		return []byte(secret)
+	}
+	generatedSecretOnce.Do(func() {
+		log.Println("JWT_SECRET is not set; using a random secret for this run")
+		generatedSecret = make([]byte, 32)
+		if _, err := rand.Read(generatedSecret); err != nil {
+			log.Fatal("Failed to generate JWT secret:", err)
+		}
+	})
+	return generatedSecret
+}
+
+// accessClaims are the claims carried by an access token
+type accessClaims struct {
+	Subject   string `json:"sub"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// jwtHeader is the fixed header of every access token
+var jwtHeader = base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+
+// signJWT returns the HS256 signature of a token's header and payload
+func signJWT(unsigned string) string {
+	mac := hmac.New(sha256.New, jwtSecret())
+	mac.Write([]byte(unsigned))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// issueAccessToken returns a signed JWT access token for the user
+func issueAccessToken(userID int, now time.Time) (string, error) {
+	payload, err := json.Marshal(accessClaims{
+		Subject:   strconv.Itoa(userID),
+		IssuedAt:  now.Unix(),
+		ExpiresAt: now.Add(accessTokenTTL()).Unix(),
+	})
+	if err != nil {
+		return "", err
+	}
+	unsigned := jwtHeader + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return unsigned + "." + signJWT(unsigned), nil
+}
+
+// parseAccessToken verifies an access token and returns its user ID
+func parseAccessToken(token string, now time.Time) (int, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 || parts[0] != jwtHeader {
+		return 0, errInvalidToken
+	}
+	if !hmac.Equal([]byte(parts[2]), []byte(signJWT(parts[0]+"."+parts[1]))) {
+		return 0, errInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return 0, errInvalidToken
+	}
+	var claims accessClaims
+	if err := json.Unmarshal(payload, &claims); err != nil || now.Unix() >= claims.ExpiresAt {
+		return 0, errInvalidToken
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil || userID <= 0 {
+		return 0, errInvalidToken
+	}
+	return userID, nil
+}
+
+// hashToken returns the hex SHA-256 of an opaque token for storage
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// issueRefreshToken stores a new random refresh token for the user and
+// returns it
+func issueRefreshToken(tx *gorm.DB, userID int, now time.Time) (string, error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(raw)
+	record := RefreshToken{UserID: userID, TokenHash: hashToken(token), ExpiresAt: now.Add(refreshTokenTTL())}
+	return token, tx.Create(&record).Error
+}
+
+// bearerToken returns the token from an "Authorization: Bearer" header
+func bearerToken(c *gin.Context) string {
+	header := c.GetHeader("Authorization")
+	if len(header) > 7 && strings.EqualFold(header[:7], "Bearer ") {
+		return strings.TrimSpace(header[7:])
+	}
+	return ""
+}
+
+// requireAuth rejects requests without a valid access token or personal API
+// token and stores the caller's user ID in the context. API tokens also store
+// their scopes for requireScope.
+func requireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, now := bearerToken(c), time.Now()
+		if strings.HasPrefix(token, apiTokenPrefix) {
+			apiToken, ok := findAPIToken(token, now)
+			if !ok {
+				c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+				return
+			}
+			c.Set(userIDKey, apiToken.UserID)
+			c.Set(tokenScopesKey, apiToken.Scopes)
+			c.Next()
+			return
+		}
+
+		userID, err := parseAccessToken(token, now)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+			return
+		}
+		c.Set(userIDKey, userID)
+		c.Next()
+	}
+}
+
+// requireScope limits API tokens on a route to those holding the resource's
+// read scope for GET requests or its write scope otherwise. Write implies
+// read. Logged in sessions hold every scope.
+func requireScope(resources ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		value, ok := c.Get(tokenScopesKey)
+		if !ok {
+			c.Next()
+			return
+		}
+
+		granted := map[string]bool{}
+		for _, scope := range value.([]string) {
+			granted[scope] = true
+		}
+		for _, resource := range resources {
+			if granted[resource+scopeWrite] || c.Request.Method == http.MethodGet && granted[resource+scopeRead] {
+				continue
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Token lacks scope for " + resource})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireSession rejects API tokens on routes that manage the account itself
+func requireSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if _, ok := c.Get(tokenScopesKey); ok {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "API tokens cannot manage the account"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// requireAdmin limits routes that change the shared movement and food
+// catalogs to admins
+func requireAdmin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var user User
+		if db.Where("id = ? AND admin = ?", currentUserID(c), true).Limit(1).Find(&user).RowsAffected == 0 {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			return
+		}
+		c.Next()
+	}
+}
+
+// currentUserID returns the ID of the user whose data the request works on
+func currentUserID(c *gin.Context) int {
+	return c.GetInt(userIDKey)
+}
+
+// actingUserID returns the user making the request, which is the coach when
+// one is acting on behalf of an athlete
+func actingUserID(c *gin.Context) int {
+	if actorID := c.GetInt(actorIDKey); actorID != 0 {
+		return actorID
+	}
+	return currentUserID(c)
+}
+
+// userScope restricts a query to rows owned by the authenticated user
+func userScope(c *gin.Context) *gorm.DB {
+	return db.Where("user_id = ?", currentUserID(c))
+}
+
+// assignOrphanedRows gives rows logged before user accounts existed to the
+// user, returning how many rows were updated
+func assignOrphanedRows(tx *gorm.DB, userID int) (int64, error) {
+	var total int64
+	err := tx.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&Exercise{}, &Workout{}, &Meal{}, &Recipe{}, &MacroTarget{}, &Weight{}, &Goal{}} {
+			result := tx.Unscoped().Model(model).Where("user_id IS NULL OR user_id = 0").Update("user_id", userID)
+			if result.Error != nil {
+				return result.Error
+			}
+			total += result.RowsAffected
+		}
+		return nil
+	})
+	return total, err
+}
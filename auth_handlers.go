@@ -0,0 +1,185 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+)
+
+// minPasswordLength is the shortest password accepted at registration
+const minPasswordLength = 8
+
+// credentials is the request body for registration and login
+type credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// refreshRequest is the request body for refreshing and revoking tokens
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// TokenPair is returned on login and refresh
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// normalizeEmail lowercases and trims an email address
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// validEmail reports whether email is a bare address
+func validEmail(email string) bool {
+	address, err := mail.ParseAddress(strings.TrimSpace(email))
+	return err == nil && address.Address == strings.TrimSpace(email)
+}
+
+// createUser registers a user with a bcrypt hash of the password
+func createUser(tx *gorm.DB, email, password string) (*User, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, err
+	}
+	user := &User{Email: normalizeEmail(email), PasswordHash: string(hash)}
+	return user, tx.Create(user).Error
+}
+
+// issueTokens creates a new access and refresh token pair for the user
+func issueTokens(tx *gorm.DB, userID int) (*TokenPair, error) {
+	now := time.Now()
+	access, err := issueAccessToken(userID, now)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := issueRefreshToken(tx, userID, now)
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{AccessToken: access, RefreshToken: refresh, TokenType: "Bearer", ExpiresIn: int(accessTokenTTL().Seconds())}, nil
+}
+
+// findRefreshToken loads an unrevoked, unexpired refresh token
+func findRefreshToken(tx *gorm.DB, token string) (*RefreshToken, bool) {
+	var record RefreshToken
+	found := tx.Where("token_hash = ? AND revoked_at IS NULL AND expires_at > ?", hashToken(token), time.Now()).
+		Limit(1).Find(&record).RowsAffected > 0
+	return &record, found
+}
+
+// register handles POST /auth/register
+func register(c *gin.Context) {
+	var req credentials
+
+	switch {
+	case c.ShouldBindJSON(&req) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !validEmail(req.Email) || len(req.Password) < minPasswordLength:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid email or password"})
+		return
+	case db.Where("email = ?", normalizeEmail(req.Email)).Limit(1).Find(&User{}).RowsAffected > 0:
+		c.JSON(http.StatusConflict, gin.H{"error": "Email already registered"})
+		return
+	}
+
+	user, err := createUser(db, req.Email, req.Password)
+	if err != nil {
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to register user"})
+		return
+	}
+	c.JSON(http.StatusCreated, user)
+}
+
+// login handles POST /auth/login
+func login(c *gin.Context) {
+	var req credentials
+	if c.ShouldBindJSON(&req) != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	var user User
+	found := db.Where("email = ?", normalizeEmail(req.Email)).Limit(1).Find(&user).RowsAffected > 0
+	if !found || bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(req.Password)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
+		return
+	}
+
+	tokens, err := issueTokens(db, user.ID)
+	if err != nil {
+		log.Println("Token Issue Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log in"})
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// refresh handles POST /auth/refresh. The refresh token is rotated: it is
+// revoked and a new pair is returned.
+func refresh(c *gin.Context) {
+	var req refreshRequest
+	if c.ShouldBindJSON(&req) != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	var tokens *TokenPair
+	err := db.Transaction(func(tx *gorm.DB) error {
+		record, ok := findRefreshToken(tx, req.RefreshToken)
+		if !ok {
+			return errInvalidToken
+		}
+		now := time.Now()
+		result := tx.Model(record).Where("revoked_at IS NULL").Update("revoked_at", &now)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			// Another request rotated the token first
+			return errInvalidToken
+		}
+		var err error
+		tokens, err = issueTokens(tx, record.UserID)
+		return err
+	})
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, tokens)
+	case errInvalidToken:
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid refresh token"})
+	default:
+		log.Println("Token Refresh Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh token"})
+	}
+}
+
+// logout handles POST /auth/logout, revoking a refresh token
+func logout(c *gin.Context) {
+	var req refreshRequest
+	if c.ShouldBindJSON(&req) != nil || req.RefreshToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	now := time.Now()
+	result := db.Model(&RefreshToken{}).Where("token_hash = ? AND revoked_at IS NULL", hashToken(req.RefreshToken)).Update("revoked_at", &now)
+	switch {
+	case result.Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Logged out successfully"})
+	}
+}
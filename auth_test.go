@@ -0,0 +1,219 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// postJSON sends a JSON POST request with an optional bearer token
+func postJSON(r *gin.Engine, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	reqBody, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", path, bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// registerAndLogin creates a user and returns its tokens
+func registerAndLogin(t *testing.T, r *gin.Engine, email string) TokenPair {
+	w := postJSON(r, "/auth/register", credentials{Email: email, Password: "correct horse"}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = postJSON(r, "/auth/login", credentials{Email: email, Password: "correct horse"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var tokens TokenPair
+	err := json.Unmarshal(w.Body.Bytes(), &tokens)
+	assert.NoError(t, err)
+	return tokens
+}
+
+func TestRegister_Success(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	w := postJSON(r, "/auth/register", credentials{Email: "Athlete@Example.com", Password: "correct horse"}, "")
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.NotContains(t, w.Body.String(), "correct horse")
+
+	var user User
+	db.Where("email = ?", "athlete@example.com").First(&user)
+	assert.NotEmpty(t, user.PasswordHash)
+	assert.NotEqual(t, "correct horse", user.PasswordHash)
+}
+
+func TestRegister_Invalid(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	w := postJSON(r, "/auth/register", credentials{Email: "not an email", Password: "correct horse"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postJSON(r, "/auth/register", credentials{Email: "athlete@example.com", Password: "short"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	registerAndLogin(t, r, "athlete@example.com")
+	w = postJSON(r, "/auth/register", credentials{Email: "athlete@example.com", Password: "another pass"}, "")
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestLogin_WrongPassword(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	registerAndLogin(t, r, "athlete@example.com")
+	w := postJSON(r, "/auth/login", credentials{Email: "athlete@example.com", Password: "wrong horse"}, "")
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRefresh_RotatesToken(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	tokens := registerAndLogin(t, r, "athlete@example.com")
+
+	w := postJSON(r, "/auth/refresh", refreshRequest{RefreshToken: tokens.RefreshToken}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rotated TokenPair
+	err := json.Unmarshal(w.Body.Bytes(), &rotated)
+	assert.NoError(t, err)
+	assert.NotEqual(t, tokens.RefreshToken, rotated.RefreshToken)
+
+	// The old refresh token cannot be used again
+	w = postJSON(r, "/auth/refresh", refreshRequest{RefreshToken: tokens.RefreshToken}, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	w = postJSON(r, "/auth/logout", refreshRequest{RefreshToken: rotated.RefreshToken}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = postJSON(r, "/auth/refresh", refreshRequest{RefreshToken: rotated.RefreshToken}, "")
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestRequireAuth_RejectsMissingAndInvalidTokens(t *testing.T) {
+	db = setupTestDB()
+
+	r := SetupRoutes()
+
+	req, _ := http.NewRequest("GET", "/weights", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+	tokens := registerAndLogin(t, r, "athlete@example.com")
+	for _, token := range []string{tokens.AccessToken + "x", "a.b.c"} {
+		req, _ = http.NewRequest("GET", "/weights", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w = httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	}
+
+	req, _ = http.NewRequest("GET", "/weights", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestParseAccessToken_Expired(t *testing.T) {
+	token, err := issueAccessToken(7, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+
+	_, err = parseAccessToken(token, time.Now())
+	assert.Equal(t, errInvalidToken, err)
+
+	userID, err := parseAccessToken(token, time.Now().Add(-time.Hour))
+	assert.NoError(t, err)
+	assert.Equal(t, 7, userID)
+}
+
+func TestUserScope_IsolatesUsers(t *testing.T) {
+	db = setupTestDB()
+
+	// Logged by the test user
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 80})
+	own := Weight{UserID: testUserID, Date: "2023-10-02", Weight: 81}
+	db.Create(&own)
+
+	r := setupRouter()
+
+	tokens := registerAndLogin(t, r, "athlete@example.com")
+	w := postJSON(r, "/weights", Weight{Date: "2023-10-01", Weight: 95}, tokens.AccessToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	req, _ := http.NewRequest("GET", "/weights", nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	var weights []Weight
+	err := json.Unmarshal(w.Body.Bytes(), &weights)
+	assert.NoError(t, err)
+	assert.Len(t, weights, 1)
+	assert.Equal(t, 95.0, weights[0].Weight)
+
+	// Another user's entry cannot be changed or deleted
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/weights/%d", own.ID), nil)
+	req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var count int64
+	db.Model(&Weight{}).Where("user_id = ?", testUserID).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestAssignOrphanedRows(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Meal{Name: "Breakfast", Date: "2023-10-01", Calories: 500})
+	db.Model(&Meal{}).Where("1 = 1").Update("user_id", nil)
+
+	user, err := createUser(db, "owner@example.com", "correct horse")
+	assert.NoError(t, err)
+
+	count, err := assignOrphanedRows(db, user.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+
+	var meal Meal
+	db.First(&meal)
+	assert.Equal(t, user.ID, meal.UserID)
+}
+
+func TestRequireAdmin_GuardsCatalogWrites(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	tokens := registerAndLogin(t, r, "athlete@example.com")
+
+	w := sendJSON(r, "POST", "/movements", gin.H{"name": "Zercher Squat", "category": CategoryCompound}, tokens.AccessToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendJSON(r, "DELETE", "/foods/1", nil, tokens.AccessToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = sendJSON(r, "GET", "/movements/Squat", nil, tokens.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	db.Model(&User{}).Where("email = ?", "athlete@example.com").Update("admin", true)
+	w = sendJSON(r, "POST", "/movements", gin.H{"name": "Zercher Squat", "category": CategoryCompound}, tokens.AccessToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+}
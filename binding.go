@@ -0,0 +1,39 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindUpdate binds the JSON body over an existing record while keeping its
+// primary key, so a client echoing "id" cannot redirect the update. Masses
+// are read in the request's unit and stored in kilograms.
+func bindUpdate(c *gin.Context, id *int, obj interface{}) error {
+	keep := *id
+	err := bindMasses(c, obj)
+	*id = keep
+	return err
+}
+
+// bindAudited binds like bindUpdate and stamps the record with the user
+// making the change. Clients cannot set the audit fields themselves.
+func bindAudited(c *gin.Context, id *int, audit *Audit, obj interface{}) error {
+	keep := *audit
+	err := bindUpdate(c, id, obj)
+	*audit = keep
+	audit.UpdatedBy = actingUserID(c)
+	if *id == 0 {
+		audit.CreatedBy = audit.UpdatedBy
+	}
+	return err
+}
+
+// rejectInvalid responds with a validator's error and reports true when the
+// record it checked broke a rule
+func rejectInvalid(c *gin.Context, problem gin.H) bool {
+	if problem != nil {
+		c.JSON(http.StatusBadRequest, problem)
+	}
+	return problem != nil
+}
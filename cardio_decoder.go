@@ -0,0 +1,627 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cardioDecoderVersion is bumped whenever decoding changes, so sessions
+// decoded by an older version are decoded again from their stored files by
+// the reparse-cardio command
+const cardioDecoderVersion = 2
+
+// Activity file formats
+const (
+	FormatFIT = "fit"
+	FormatTCX = "tcx"
+)
+
+// Sports recorded on cardio sessions
+const (
+	SportRunning  = "running"
+	SportCycling  = "cycling"
+	SportRowing   = "rowing"
+	SportSwimming = "swimming"
+	SportWalking  = "walking"
+	SportHiking   = "hiking"
+	SportOther    = "other"
+)
+
+// maxActivityBytes is the largest activity file accepted once decompressed
+const maxActivityBytes = 100 << 20
+
+// maxPauseSeconds is the longest gap between samples counted as moving time
+// when a file does not record its timer time
+const maxPauseSeconds = 30
+
+// cardioSample is one point of an activity's recorded track
+type cardioSample struct {
+	Time      time.Time
+	HeartRate *int
+	Distance  *float64
+	Altitude  *float64
+}
+
+// decodedActivity is a session decoded from a file with its track, which
+// fills in metrics the file does not summarise
+type decodedActivity struct {
+	Session CardioSession
+	Samples []cardioSample
+}
+
+// activityFormat recognises a FIT or TCX file from its content
+func activityFormat(data []byte) string {
+	switch {
+	case len(data) >= 12 && string(data[8:12]) == ".FIT":
+		return FormatFIT
+	case bytes.Contains(data[:min(len(data), 1024)], []byte("TrainingCenterDatabase")):
+		return FormatTCX
+	}
+	return ""
+}
+
+// decodeActivityFile decodes a FIT or TCX file, optionally gzipped as in
+// bulk exports, into its sessions with their metrics and lap splits
+func decodeActivityFile(data []byte) (string, []CardioSession, error) {
+	if len(data) > 2 && data[0] == 0x1f && data[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return "", nil, err
+		}
+		if data, err = io.ReadAll(io.LimitReader(reader, maxActivityBytes+1)); err != nil {
+			return "", nil, err
+		}
+		if len(data) > maxActivityBytes {
+			return "", nil, fmt.Errorf("file is larger than %d MB uncompressed", maxActivityBytes>>20)
+		}
+	}
+
+	var activities []decodedActivity
+	var err error
+	format := activityFormat(data)
+	switch format {
+	case FormatFIT:
+		activities, err = decodeFIT(data)
+	case FormatTCX:
+		activities, err = decodeTCX(data)
+	default:
+		return "", nil, fmt.Errorf("not a FIT or TCX file")
+	}
+	if err != nil {
+		return format, nil, err
+	}
+	if len(activities) == 0 {
+		return format, nil, fmt.Errorf("file holds no activity")
+	}
+
+	sessions := make([]CardioSession, len(activities))
+	for i, activity := range activities {
+		sessions[i] = summarizeActivity(activity)
+	}
+	return format, sessions, nil
+}
+
+// summarizeActivity fills the metrics of a session and its laps that the
+// file does not record from the samples in their time range, and derives
+// their pace
+func summarizeActivity(activity decodedActivity) CardioSession {
+	session := activity.Session
+	session.DecoderVersion = cardioDecoderVersion
+	sort.Slice(activity.Samples, func(i, j int) bool { return activity.Samples[i].Time.Before(activity.Samples[j].Time) })
+	fillCardioMetrics(&session.CardioMetrics, activity.Samples)
+
+	for i := range session.Laps {
+		lap := &session.Laps[i]
+		lap.LapNumber = i + 1
+		var samples []cardioSample
+		if lap.StartTime != nil {
+			end := lap.StartTime.Add(time.Duration(lap.ElapsedTime * float64(time.Second)))
+			for _, sample := range activity.Samples {
+				if !sample.Time.Before(*lap.StartTime) && !sample.Time.After(end) {
+					samples = append(samples, sample)
+				}
+			}
+		}
+		fillCardioMetrics(&lap.CardioMetrics, samples)
+	}
+	return session
+}
+
+// fillCardioMetrics fills metrics missing from m from a track of samples in
+// time order and derives the pace
+func fillCardioMetrics(m *CardioMetrics, samples []cardioSample) {
+	if len(samples) > 1 {
+		first, last := samples[0], samples[len(samples)-1]
+		if m.ElapsedTime == 0 {
+			m.ElapsedTime = last.Time.Sub(first.Time).Seconds()
+		}
+
+		var distances, altitudes []float64
+		var heartRates []int
+		var moving float64
+		for i, sample := range samples {
+			if sample.HeartRate != nil {
+				heartRates = append(heartRates, *sample.HeartRate)
+			}
+			if sample.Altitude != nil {
+				altitudes = append(altitudes, *sample.Altitude)
+			}
+			if sample.Distance == nil {
+				continue
+			}
+			if len(distances) > 0 && *sample.Distance > distances[len(distances)-1] {
+				if gap := sample.Time.Sub(samples[i-1].Time).Seconds(); gap <= maxPauseSeconds {
+					moving += gap
+				}
+			}
+			distances = append(distances, *sample.Distance)
+		}
+
+		if m.Distance == 0 && len(distances) > 1 {
+			m.Distance = distances[len(distances)-1] - distances[0]
+		}
+		if m.MovingTime == 0 {
+			m.MovingTime = moving
+			if len(distances) < 2 {
+				m.MovingTime = m.ElapsedTime
+			}
+		}
+		if len(heartRates) > 0 {
+			total, highest := 0, 0
+			for _, hr := range heartRates {
+				total += hr
+				highest = max(highest, hr)
+			}
+			if m.AvgHeartRate == nil {
+				avg := int(math.Round(float64(total) / float64(len(heartRates))))
+				m.AvgHeartRate = &avg
+			}
+			if m.MaxHeartRate == nil {
+				m.MaxHeartRate = &highest
+			}
+		}
+		if m.ElevationGain == nil && len(altitudes) > 1 {
+			var gain float64
+			for i := 1; i < len(altitudes); i++ {
+				gain += math.Max(0, altitudes[i]-altitudes[i-1])
+			}
+			gain = roundTo(gain, 1)
+			m.ElevationGain = &gain
+		}
+	}
+	if m.MovingTime == 0 {
+		m.MovingTime = m.ElapsedTime
+	}
+
+	m.Distance = roundTo(m.Distance, 1)
+	m.ElapsedTime = roundTo(m.ElapsedTime, 1)
+	m.MovingTime = roundTo(m.MovingTime, 1)
+	m.Pace = nil
+	if m.Distance > 0 && m.MovingTime > 0 {
+		pace := roundTo(m.MovingTime/m.Distance*1000, 1)
+		m.Pace = &pace
+	}
+}
+
+// FIT global message numbers read by the decoder
+const (
+	fitSession = 18
+	fitLap     = 19
+	fitRecord  = 20
+)
+
+// fitEpoch is the zero of FIT timestamps
+var fitEpoch = time.Date(1989, 12, 31, 0, 0, 0, 0, time.UTC)
+
+// fitSports maps FIT sport values to our sports
+var fitSports = map[uint64]string{
+	1:  SportRunning,
+	2:  SportCycling,
+	5:  SportSwimming,
+	11: SportWalking,
+	15: SportRowing,
+	17: SportHiking,
+}
+
+// fitIndoorRowing is the FIT sub sport of rowing machine sessions
+const fitIndoorRowing = 14
+
+// fitCRCTable is the nibble table of the FIT CRC-16
+var fitCRCTable = [16]uint16{
+	0x0000, 0xCC01, 0xD801, 0x1400, 0xF001, 0x3C00, 0x2800, 0xE401,
+	0xA001, 0x6C00, 0x7800, 0xB401, 0x5000, 0x9C01, 0x8801, 0x4400,
+}
+
+// fitCRC computes the FIT CRC-16 of data
+func fitCRC(data []byte) uint16 {
+	var crc uint16
+	for _, b := range data {
+		for _, nibble := range []byte{b & 0xF, b >> 4} {
+			tmp := fitCRCTable[crc&0xF]
+			crc = (crc >> 4) & 0x0FFF
+			crc = crc ^ tmp ^ fitCRCTable[nibble]
+		}
+	}
+	return crc
+}
+
+// fitDefinition describes the fields of a local FIT message type
+type fitDefinition struct {
+	global    uint16
+	bigEndian bool
+	fields    []fitField
+	devSize   int
+}
+
+// fitField is a field of a FIT message definition
+type fitField struct {
+	num  byte
+	size int
+}
+
+// fitMessage is a decoded FIT data message's raw field values by number
+type fitMessage struct {
+	bigEndian bool
+	fields    map[byte][]byte
+}
+
+// unsigned reads an unsigned field, reporting false when it is missing or holds
+// the FIT invalid value
+func (m fitMessage) unsigned(num byte) (uint64, bool) {
+	raw, ok := m.fields[num]
+	if !ok {
+		return 0, false
+	}
+	var v, invalid uint64
+	switch len(raw) {
+	case 1:
+		v, invalid = uint64(raw[0]), 0xFF
+	case 2:
+		if m.bigEndian {
+			v = uint64(binary.BigEndian.Uint16(raw))
+		} else {
+			v = uint64(binary.LittleEndian.Uint16(raw))
+		}
+		invalid = 0xFFFF
+	case 4:
+		if m.bigEndian {
+			v = uint64(binary.BigEndian.Uint32(raw))
+		} else {
+			v = uint64(binary.LittleEndian.Uint32(raw))
+		}
+		invalid = 0xFFFFFFFF
+	default:
+		return 0, false
+	}
+	return v, v != invalid
+}
+
+// scaled reads an unsigned field as value/scale - offset
+func (m fitMessage) scaled(num byte, scale, offset float64) (float64, bool) {
+	v, ok := m.unsigned(num)
+	return float64(v)/scale - offset, ok
+}
+
+// timestamp reads a FIT timestamp field
+func (m fitMessage) timestamp(num byte) (time.Time, bool) {
+	v, ok := m.unsigned(num)
+	return fitEpoch.Add(time.Duration(v) * time.Second), ok
+}
+
+// optionalInt reads an unsigned field as an optional whole number
+func (m fitMessage) optionalInt(num byte) *int {
+	if v, ok := m.unsigned(num); ok {
+		n := int(v)
+		return &n
+	}
+	return nil
+}
+
+// metrics reads the summary fields shared by FIT session and lap messages,
+// whose field numbers differ only for heart rate and ascent
+func (m fitMessage) metrics(avgHR, maxHR, ascent byte) CardioMetrics {
+	var metrics CardioMetrics
+	metrics.ElapsedTime, _ = m.scaled(7, 1000, 0)
+	metrics.MovingTime, _ = m.scaled(8, 1000, 0)
+	metrics.Distance, _ = m.scaled(9, 100, 0)
+	metrics.Calories = m.optionalInt(11)
+	metrics.AvgHeartRate = m.optionalInt(avgHR)
+	metrics.MaxHeartRate = m.optionalInt(maxHR)
+	if v, ok := m.scaled(ascent, 1, 0); ok {
+		metrics.ElevationGain = &v
+	}
+	return metrics
+}
+
+// readFITMessages checks a FIT file's header and CRC and passes each data
+// message to handle with its global message number
+func readFITMessages(data []byte, handle func(global uint16, msg fitMessage)) error {
+	if len(data) < 12 || int(data[0]) < 12 || len(data) < int(data[0]) {
+		return fmt.Errorf("FIT header is truncated")
+	}
+	start := int(data[0])
+	end := start + int(binary.LittleEndian.Uint32(data[4:8]))
+	if end+2 > len(data) {
+		return fmt.Errorf("FIT file is truncated")
+	}
+	if crc := binary.LittleEndian.Uint16(data[end : end+2]); crc != 0 && crc != fitCRC(data[:end]) {
+		return fmt.Errorf("FIT file is corrupt: CRC mismatch")
+	}
+
+	definitions := map[byte]*fitDefinition{}
+	var timestamp uint64
+	for pos := start; pos < end; {
+		header := data[pos]
+		pos++
+
+		var local byte
+		compressed := header&0x80 != 0
+		switch {
+		case compressed:
+			local = (header >> 5) & 0x3
+			// The offset replaces the low 5 bits of the last timestamp and
+			// rolls over into the next 32 seconds when it is below them
+			offset := uint64(header & 0x1F)
+			if offset < timestamp&0x1F {
+				timestamp += 0x20
+			}
+			timestamp = timestamp&^0x1F + offset
+		case header&0x40 != 0:
+			if pos+5 > end {
+				return fmt.Errorf("FIT definition is truncated")
+			}
+			def := &fitDefinition{bigEndian: data[pos+1] == 1}
+			if def.bigEndian {
+				def.global = binary.BigEndian.Uint16(data[pos+2 : pos+4])
+			} else {
+				def.global = binary.LittleEndian.Uint16(data[pos+2 : pos+4])
+			}
+			count := int(data[pos+4])
+			pos += 5
+			if pos+count*3 > end {
+				return fmt.Errorf("FIT definition is truncated")
+			}
+			for i := 0; i < count; i++ {
+				def.fields = append(def.fields, fitField{num: data[pos], size: int(data[pos+1])})
+				pos += 3
+			}
+			if header&0x20 != 0 {
+				if pos >= end {
+					return fmt.Errorf("FIT definition is truncated")
+				}
+				devCount := int(data[pos])
+				pos++
+				if pos+devCount*3 > end {
+					return fmt.Errorf("FIT definition is truncated")
+				}
+				for i := 0; i < devCount; i++ {
+					def.devSize += int(data[pos+1])
+					pos += 3
+				}
+			}
+			definitions[header&0x0F] = def
+			continue
+		default:
+			local = header & 0x0F
+		}
+
+		def, ok := definitions[local]
+		if !ok {
+			return fmt.Errorf("FIT data message %d has no definition", local)
+		}
+		msg := fitMessage{bigEndian: def.bigEndian, fields: map[byte][]byte{}}
+		for _, field := range def.fields {
+			if pos+field.size > end {
+				return fmt.Errorf("FIT data message is truncated")
+			}
+			msg.fields[field.num] = data[pos : pos+field.size]
+			pos += field.size
+		}
+		pos += def.devSize
+		if compressed {
+			raw := make([]byte, 4)
+			binary.LittleEndian.PutUint32(raw, uint32(timestamp))
+			msg.bigEndian = false
+			msg.fields[253] = raw
+		} else if v, ok := msg.unsigned(253); ok {
+			timestamp = v
+		}
+		handle(def.global, msg)
+	}
+	return nil
+}
+
+// decodeFIT decodes the sessions of a FIT activity file. Laps and records
+// belong to the last session started before them; files without session
+// messages are treated as one session over their records.
+func decodeFIT(data []byte) ([]decodedActivity, error) {
+	var activities []decodedActivity
+	var laps []CardioLap
+	var samples []cardioSample
+
+	err := readFITMessages(data, func(global uint16, msg fitMessage) {
+		switch global {
+		case fitSession:
+			start, ok := msg.timestamp(2)
+			if !ok {
+				return
+			}
+			sport := SportOther
+			if v, ok := msg.unsigned(5); ok && fitSports[v] != "" {
+				sport = fitSports[v]
+			}
+			if v, _ := msg.unsigned(6); v == fitIndoorRowing {
+				sport = SportRowing
+			}
+			session := CardioSession{CardioMetrics: msg.metrics(16, 17, 22), OccurredAt: &start, Sport: sport}
+			activities = append(activities, decodedActivity{Session: session})
+		case fitLap:
+			start, ok := msg.timestamp(2)
+			if !ok {
+				return
+			}
+			laps = append(laps, CardioLap{CardioMetrics: msg.metrics(15, 16, 21), StartTime: &start})
+		case fitRecord:
+			at, ok := msg.timestamp(253)
+			if !ok {
+				return
+			}
+			sample := cardioSample{Time: at, HeartRate: msg.optionalInt(3)}
+			if v, ok := msg.scaled(5, 100, 0); ok {
+				sample.Distance = &v
+			}
+			if v, ok := msg.scaled(78, 5, 500); ok {
+				sample.Altitude = &v
+			} else if v, ok := msg.scaled(2, 5, 500); ok {
+				sample.Altitude = &v
+			}
+			samples = append(samples, sample)
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if len(activities) == 0 && len(samples) > 0 {
+		start := samples[0].Time
+		activities = append(activities, decodedActivity{Session: CardioSession{OccurredAt: &start, Sport: SportOther}})
+	}
+	sort.Slice(activities, func(i, j int) bool {
+		return activities[i].Session.OccurredAt.Before(*activities[j].Session.OccurredAt)
+	})
+	owner := func(t time.Time) *decodedActivity {
+		for i := len(activities) - 1; i >= 0; i-- {
+			if !t.Before(*activities[i].Session.OccurredAt) {
+				return &activities[i]
+			}
+		}
+		if len(activities) > 0 {
+			return &activities[0]
+		}
+		return nil
+	}
+	for _, lap := range laps {
+		if activity := owner(*lap.StartTime); activity != nil {
+			activity.Session.Laps = append(activity.Session.Laps, lap)
+		}
+	}
+	for _, sample := range samples {
+		if activity := owner(sample.Time); activity != nil {
+			activity.Samples = append(activity.Samples, sample)
+		}
+	}
+	return activities, nil
+}
+
+// tcxDatabase is the part of a Garmin Training Center (TCX) file read by the
+// decoder
+type tcxDatabase struct {
+	Activities []struct {
+		Sport string `xml:"Sport,attr"`
+		Laps  []struct {
+			StartTime        string  `xml:"StartTime,attr"`
+			TotalTimeSeconds float64 `xml:"TotalTimeSeconds"`
+			DistanceMeters   float64 `xml:"DistanceMeters"`
+			Calories         *int    `xml:"Calories"`
+			AverageHeartRate *int    `xml:"AverageHeartRateBpm>Value"`
+			MaximumHeartRate *int    `xml:"MaximumHeartRateBpm>Value"`
+			Trackpoints      []struct {
+				Time      string   `xml:"Time"`
+				Altitude  *float64 `xml:"AltitudeMeters"`
+				Distance  *float64 `xml:"DistanceMeters"`
+				HeartRate *int     `xml:"HeartRateBpm>Value"`
+			} `xml:"Track>Trackpoint"`
+		} `xml:"Lap"`
+	} `xml:"Activities>Activity"`
+}
+
+// tcxSports maps TCX sports to ours
+var tcxSports = map[string]string{
+	"running": SportRunning,
+	"biking":  SportCycling,
+}
+
+// decodeTCX decodes the activities of a TCX file. Session totals are summed
+// from the laps, whose TotalTimeSeconds is their moving time.
+func decodeTCX(data []byte) ([]decodedActivity, error) {
+	var doc tcxDatabase
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("reading TCX: %w", err)
+	}
+
+	var activities []decodedActivity
+	for _, a := range doc.Activities {
+		sport, ok := tcxSports[strings.ToLower(a.Sport)]
+		if !ok {
+			sport = SportOther
+		}
+		activity := decodedActivity{Session: CardioSession{Sport: sport}}
+		session := &activity.Session
+		var weightedHR, calories float64
+		var hasHR, hasCalories bool
+
+		for _, l := range a.Laps {
+			start, err := time.Parse(time.RFC3339, l.StartTime)
+			if err != nil {
+				return nil, fmt.Errorf("reading TCX: lap start %q is not a time", l.StartTime)
+			}
+			lap := CardioLap{StartTime: &start}
+			lap.Distance, lap.MovingTime = l.DistanceMeters, l.TotalTimeSeconds
+			lap.Calories, lap.AvgHeartRate, lap.MaxHeartRate = l.Calories, l.AverageHeartRate, l.MaximumHeartRate
+
+			var lapSamples []cardioSample
+			for _, tp := range l.Trackpoints {
+				at, err := time.Parse(time.RFC3339, tp.Time)
+				if err != nil {
+					continue
+				}
+				lapSamples = append(lapSamples, cardioSample{Time: at, HeartRate: tp.HeartRate, Distance: tp.Distance, Altitude: tp.Altitude})
+			}
+			lap.ElapsedTime = l.TotalTimeSeconds
+			if n := len(lapSamples); n > 1 {
+				lap.ElapsedTime = math.Max(l.TotalTimeSeconds, lapSamples[n-1].Time.Sub(start).Seconds())
+			}
+			activity.Samples = append(activity.Samples, lapSamples...)
+
+			if session.OccurredAt == nil {
+				session.OccurredAt = &start
+			}
+			session.Distance += lap.Distance
+			session.MovingTime += lap.MovingTime
+			session.ElapsedTime = start.Add(time.Duration(lap.ElapsedTime * float64(time.Second))).Sub(*session.OccurredAt).Seconds()
+			if lap.AvgHeartRate != nil {
+				weightedHR += float64(*lap.AvgHeartRate) * lap.MovingTime
+				hasHR = true
+			}
+			if lap.MaxHeartRate != nil && (session.MaxHeartRate == nil || *lap.MaxHeartRate > *session.MaxHeartRate) {
+				session.MaxHeartRate = lap.MaxHeartRate
+			}
+			if lap.Calories != nil {
+				calories += float64(*lap.Calories)
+				hasCalories = true
+			}
+			session.Laps = append(session.Laps, lap)
+		}
+		if session.OccurredAt == nil {
+			continue
+		}
+		if hasHR && session.MovingTime > 0 {
+			avg := int(math.Round(weightedHR / session.MovingTime))
+			session.AvgHeartRate = &avg
+		}
+		if hasCalories {
+			total := int(calories)
+			session.Calories = &total
+		}
+		activities = append(activities, activity)
+	}
+	return activities, nil
+}
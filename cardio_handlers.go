@@ -0,0 +1,248 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// preloadLaps loads a cardio session's lap splits in order
+func preloadLaps(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Laps", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("lap_number ASC")
+	})
+}
+
+// saveCardioSessions stores the sessions decoded from a file, taking the
+// place of the sessions previously decoded from it in order so their IDs are
+// kept. Sessions the user deleted stay deleted, and their laps are replaced.
+// Dates are taken in the user's time zone loc.
+func saveCardioSessions(tx *gorm.DB, file *CardioFile, decoded []CardioSession, loc *time.Location) ([]CardioSession, error) {
+	var existing []CardioSession
+	if err := tx.Unscoped().Where("file_id = ?", file.ID).Order("id ASC").Find(&existing).Error; err != nil {
+		return nil, err
+	}
+	for _, session := range existing {
+		if err := tx.Unscoped().Where("session_id = ?", session.ID).Delete(&CardioLap{}).Error; err != nil {
+			return nil, err
+		}
+	}
+
+	var saved []CardioSession
+	for i, session := range decoded {
+		session.UserID, session.FileID = file.UserID, file.ID
+		normalizeDate(&session.Date, session.OccurredAt, loc)
+		laps := session.Laps
+		session.Laps = nil
+
+		var err error
+		switch {
+		case i >= len(existing):
+			err = tx.Create(&session).Error
+		case existing[i].DeletedAt.Valid:
+			continue
+		default:
+			session.ID, session.CreatedAt = existing[i].ID, existing[i].CreatedAt
+			err = tx.Save(&session).Error
+		}
+		if err != nil {
+			return nil, err
+		}
+		for j := range laps {
+			laps[j].SessionID = session.ID
+		}
+		if len(laps) > 0 {
+			if err := tx.Create(&laps).Error; err != nil {
+				return nil, err
+			}
+		}
+		session.Laps = laps
+		saved = append(saved, session)
+	}
+
+	for _, session := range existing[min(len(existing), len(decoded)):] {
+		if err := tx.Unscoped().Delete(&session).Error; err != nil {
+			return nil, err
+		}
+	}
+	return saved, nil
+}
+
+// uploadCardio handles POST /cardio with a FIT or TCX file, sent as the
+// "file" field of a multipart form or as the request body with an optional
+// ?filename=. The original file is stored alongside the decoded sessions.
+func uploadCardio(c *gin.Context) {
+	var data []byte
+	err := readUpload(c, func(body io.Reader) (err error) {
+		data, err = io.ReadAll(body)
+		return err
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid upload", "message": err.Error()})
+		return
+	}
+
+	format, decoded, err := decodeActivityFile(data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid activity file", "message": err.Error()})
+		return
+	}
+
+	sum := sha256.Sum256(data)
+	file := CardioFile{UserID: currentUserID(c), Filename: c.Query("filename"), Format: format, SHA256: hex.EncodeToString(sum[:]), Data: data}
+	if upload, err := c.FormFile("file"); err == nil {
+		file.Filename = upload.Filename
+	}
+	var existing CardioFile
+	if userScope(c).Select("id").Where("sha256 = ?", file.SHA256).Limit(1).Find(&existing).RowsAffected > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "File already uploaded", "file_id": existing.ID})
+		return
+	}
+
+	var sessions []CardioSession
+	loc := currentLocation(c)
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&file).Error; err != nil {
+			return err
+		}
+		sessions, err = saveCardioSessions(tx, &file, decoded, loc)
+		return err
+	})
+	switch err {
+	case nil:
+		c.JSON(http.StatusCreated, sessions)
+	default:
+		log.Println("DB Insert Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save cardio sessions"})
+	}
+}
+
+// cardioList is the filtering and sorting accepted by GET /cardio
+var cardioList = listSpec{
+	DateColumn:  "date",
+	Sorts:       map[string]string{"date": "date", "created_at": "created_at", "distance": "distance", "moving_time": "moving_time"},
+	DefaultSort: "-date",
+	Filters:     map[string]string{"sport": "sport"},
+}
+
+// getCardioSessions handles GET /cardio
+func getCardioSessions(c *gin.Context) {
+	var sessions []CardioSession
+	err := paginate(c, userScope(c).Model(&CardioSession{}), cardioList, &sessions, nil)
+	switch err.(type) {
+	case nil:
+		c.JSON(http.StatusOK, sessions)
+	case listParamError:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch cardio sessions"})
+	}
+}
+
+// getCardioSession handles GET /cardio/:id with its lap splits
+func getCardioSession(c *gin.Context) {
+	id := c.Param("id")
+	var session CardioSession
+	switch err := preloadLaps(userScope(c)).First(&session, id).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, session)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cardio session not found"})
+	}
+}
+
+// getCardioFile handles GET /cardio/:id/file, downloading the file the
+// session was decoded from as it was uploaded
+func getCardioFile(c *gin.Context) {
+	id := c.Param("id")
+	var session CardioSession
+	var file CardioFile
+	if userScope(c).First(&session, id).Error != nil || userScope(c).First(&file, session.FileID).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cardio session not found"})
+		return
+	}
+
+	filename := file.Filename
+	if filename == "" {
+		filename = fmt.Sprintf("activity-%d.%s", file.ID, file.Format)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/octet-stream", file.Data)
+}
+
+// deleteCardioSession handles DELETE /cardio/:id, deleting the stored file
+// along with the last session decoded from it
+func deleteCardioSession(c *gin.Context) {
+	id := c.Param("id")
+	var session CardioSession
+	if err := userScope(c).First(&session, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Cardio session not found"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("session_id = ?", session.ID).Delete(&CardioLap{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&session).Error; err != nil {
+			return err
+		}
+		var remaining int64
+		if err := tx.Model(&CardioSession{}).Where("file_id = ?", session.FileID).Count(&remaining).Error; err != nil || remaining > 0 {
+			return err
+		}
+		return tx.Delete(&CardioFile{}, session.FileID).Error
+	})
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Cardio session deleted successfully"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete cardio session"})
+	}
+}
+
+// reparseCardioFiles decodes again the stored files with sessions decoded by
+// an older decoder version, or every stored file when all is set, and
+// reports how many files were decoded. Files that no longer decode are
+// logged and left as they are.
+func reparseCardioFiles(tx *gorm.DB, all bool) (int, error) {
+	query := tx.Model(&CardioFile{})
+	if !all {
+		stale := tx.Model(&CardioSession{}).Select("file_id").Where("decoder_version < ?", cardioDecoderVersion)
+		query = query.Where("id IN (?)", stale)
+	}
+	var ids []int
+	if err := query.Pluck("id", &ids).Error; err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, id := range ids {
+		var file CardioFile
+		if err := tx.First(&file, id).Error; err != nil {
+			return count, err
+		}
+		_, decoded, err := decodeActivityFile(file.Data)
+		if err != nil {
+			log.Printf("Cardio file %d no longer decodes: %v", file.ID, err)
+			continue
+		}
+		loc := userLocation(file.UserID)
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			_, err := saveCardioSessions(tx, &file, decoded, loc)
+			return err
+		})
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	return count, nil
+}
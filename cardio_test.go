@@ -0,0 +1,262 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fitTestField is a field of a FIT message written by encodeFIT
+type fitTestField struct {
+	num   byte
+	size  int
+	value uint64
+}
+
+// fitTestMessage is a FIT data message written by encodeFIT
+type fitTestMessage struct {
+	global uint16
+	fields []fitTestField
+}
+
+// wrapFIT adds the FIT file header and CRC around the records in body
+func wrapFIT(body []byte) []byte {
+	header := []byte{14, 0x20, 0x54, 0x08, 0, 0, 0, 0, '.', 'F', 'I', 'T', 0, 0}
+	binary.LittleEndian.PutUint32(header[4:8], uint32(len(body)))
+	binary.LittleEndian.PutUint16(header[12:14], fitCRC(header[:12]))
+	data := append(header, body...)
+	return binary.LittleEndian.AppendUint16(data, fitCRC(data))
+}
+
+// encodeFIT writes a little-endian FIT file with a definition before each
+// data message
+func encodeFIT(messages []fitTestMessage) []byte {
+	var body []byte
+	for _, msg := range messages {
+		body = append(body, 0x40, 0, 0, byte(msg.global), byte(msg.global>>8), byte(len(msg.fields)))
+		for _, field := range msg.fields {
+			body = append(body, field.num, byte(field.size), 0)
+		}
+		body = append(body, 0)
+		for _, field := range msg.fields {
+			raw := make([]byte, 8)
+			binary.LittleEndian.PutUint64(raw, field.value)
+			body = append(body, raw[:field.size]...)
+		}
+	}
+	return wrapFIT(body)
+}
+
+// fitSeconds converts a time to a FIT timestamp
+func fitSeconds(t time.Time) uint64 {
+	return uint64(t.Sub(fitEpoch).Seconds())
+}
+
+// testRunFIT is a 10 minute, 1800 m run with two laps, recorded every 10 s
+// at 150 bpm while climbing 0.5 m per sample. The session does not record
+// its average heart rate or ascent.
+func testRunFIT() []byte {
+	start := time.Date(2023, 10, 1, 6, 0, 0, 0, time.UTC)
+	messages := []fitTestMessage{{global: fitSession, fields: []fitTestField{
+		{2, 4, fitSeconds(start)}, {5, 1, 1}, {7, 4, 600000}, {8, 4, 600000}, {9, 4, 180000},
+		{11, 2, 150}, {16, 1, 0xFF}, {17, 1, 160},
+	}}}
+	for lap := 0; lap < 2; lap++ {
+		messages = append(messages, fitTestMessage{global: fitLap, fields: []fitTestField{
+			{2, 4, fitSeconds(start.Add(time.Duration(lap) * 5 * time.Minute))}, {7, 4, 300000}, {8, 4, 300000}, {9, 4, 90000},
+		}})
+	}
+	for i := 0; i <= 60; i++ {
+		messages = append(messages, fitTestMessage{global: fitRecord, fields: []fitTestField{
+			{253, 4, fitSeconds(start.Add(time.Duration(i) * 10 * time.Second))},
+			{2, 2, uint64((100 + float64(i)*0.5 + 500) * 5)}, {3, 1, 150}, {5, 4, uint64(i * 3000)},
+		}})
+	}
+	return encodeFIT(messages)
+}
+
+func TestUploadCardio_FIT(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/cardio?filename=run.fit", string(testRunFIT()))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var sessions []CardioSession
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	assert.Len(t, sessions, 1)
+	session := sessions[0]
+	assert.Equal(t, SportRunning, session.Sport)
+	assert.Equal(t, Date("2023-10-01"), session.Date)
+	assert.Equal(t, 1800.0, session.Distance)
+	assert.Equal(t, 600.0, session.MovingTime)
+	assert.Equal(t, 333.3, *session.Pace)
+	assert.Equal(t, 150, *session.AvgHeartRate)
+	assert.Equal(t, 160, *session.MaxHeartRate)
+	assert.Equal(t, 30.0, *session.ElevationGain)
+	assert.Equal(t, 150, *session.Calories)
+	assert.Len(t, session.Laps, 2)
+	assert.Equal(t, 2, session.Laps[1].LapNumber)
+	assert.Equal(t, 900.0, session.Laps[1].Distance)
+	assert.Equal(t, 150, *session.Laps[1].AvgHeartRate)
+
+	var file CardioFile
+	db.First(&file)
+	assert.Equal(t, "run.fit", file.Filename)
+	assert.Equal(t, FormatFIT, file.Format)
+}
+
+func TestUploadCardio_DatesInUserTimeZone(t *testing.T) {
+	db = setupTestDB()
+	db.Model(&User{}).Where("id = ?", testUserID).Update("time_zone", "America/Los_Angeles")
+
+	// The run starts at 06:00 UTC, still the evening before in Los Angeles
+	w := postCSV("/cardio", string(testRunFIT()))
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var sessions []CardioSession
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	assert.Equal(t, Date("2023-09-30"), sessions[0].Date)
+}
+
+func TestUploadCardio_TCX(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/cardio", `<?xml version="1.0" encoding="UTF-8"?>
+<TrainingCenterDatabase xmlns="http://www.garmin.com/xmlschemas/TrainingCenterDatabase/v2">
+ <Activities>
+  <Activity Sport="Other">
+   <Id>2023-10-02T17:00:00Z</Id>
+   <Lap StartTime="2023-10-02T17:00:00Z">
+    <TotalTimeSeconds>240</TotalTimeSeconds><DistanceMeters>1000</DistanceMeters><Calories>60</Calories>
+    <AverageHeartRateBpm><Value>150</Value></AverageHeartRateBpm><MaximumHeartRateBpm><Value>165</Value></MaximumHeartRateBpm>
+    <Track>
+     <Trackpoint><Time>2023-10-02T17:00:00Z</Time><DistanceMeters>0</DistanceMeters></Trackpoint>
+     <Trackpoint><Time>2023-10-02T17:04:00Z</Time><DistanceMeters>1000</DistanceMeters></Trackpoint>
+    </Track>
+   </Lap>
+   <Lap StartTime="2023-10-02T17:05:00Z">
+    <TotalTimeSeconds>120</TotalTimeSeconds><DistanceMeters>500</DistanceMeters><Calories>40</Calories>
+    <AverageHeartRateBpm><Value>165</Value></AverageHeartRateBpm><MaximumHeartRateBpm><Value>172</Value></MaximumHeartRateBpm>
+   </Lap>
+  </Activity>
+ </Activities>
+</TrainingCenterDatabase>`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var sessions []CardioSession
+	json.Unmarshal(w.Body.Bytes(), &sessions)
+	session := sessions[0]
+	assert.Equal(t, SportOther, session.Sport)
+	assert.Equal(t, 1500.0, session.Distance)
+	assert.Equal(t, 360.0, session.MovingTime)
+	assert.Equal(t, 420.0, session.ElapsedTime)
+	assert.Equal(t, 240.0, *session.Pace)
+	assert.Equal(t, 155, *session.AvgHeartRate)
+	assert.Equal(t, 172, *session.MaxHeartRate)
+	assert.Equal(t, 100, *session.Calories)
+	assert.Nil(t, session.ElevationGain)
+	assert.Len(t, session.Laps, 2)
+}
+
+func TestUploadCardio_RejectsDuplicatesAndCorruptFiles(t *testing.T) {
+	db = setupTestDB()
+	data := testRunFIT()
+
+	assert.Equal(t, http.StatusCreated, postCSV("/cardio", string(data)).Code)
+	assert.Equal(t, http.StatusConflict, postCSV("/cardio", string(data)).Code)
+
+	data[20] ^= 0xFF
+	w := postCSV("/cardio", string(data))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "CRC mismatch")
+	assert.Equal(t, http.StatusBadRequest, postCSV("/cardio", "id,date\n1,2023-10-01\n").Code)
+}
+
+func TestReadFITMessages_CompressedTimestampRollsOver(t *testing.T) {
+	// A record at 30 s past a 32 s boundary, then one with a compressed
+	// timestamp offset of 2, which is 4 s later in the next 32 s
+	base := fitSeconds(time.Date(2023, 10, 1, 6, 0, 0, 0, time.UTC))&^0x1F + 30
+	body := []byte{0x40, 0, 0, fitRecord, 0, 2, 253, 4, 0x86, 3, 1, 2, 0}
+	body = binary.LittleEndian.AppendUint32(body, uint32(base))
+	body = append(body, 150)
+	body = append(body, 0x41, 0, 0, fitRecord, 0, 1, 3, 1, 2)
+	body = append(body, 0x80|1<<5|2, 151)
+
+	var times []uint64
+	err := readFITMessages(wrapFIT(body), func(global uint16, msg fitMessage) {
+		v, _ := msg.unsigned(253)
+		times = append(times, v)
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{base, base + 4}, times)
+}
+
+func TestDecodeActivityFile_LimitsDecompressedSize(t *testing.T) {
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	writer.Write(make([]byte, maxActivityBytes+1))
+	writer.Close()
+
+	_, _, err := decodeActivityFile(buf.Bytes())
+	assert.ErrorContains(t, err, "larger than")
+}
+
+func TestGetCardioFile_ReturnsOriginal(t *testing.T) {
+	db = setupTestDB()
+	data := testRunFIT()
+	postCSV("/cardio?filename=run.fit", string(data))
+
+	r := setupRouter()
+	req, _ := http.NewRequest("GET", "/cardio/1/file", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, data, w.Body.Bytes())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "run.fit")
+}
+
+func TestDeleteCardioSession_RemovesFile(t *testing.T) {
+	db = setupTestDB()
+	postCSV("/cardio", string(testRunFIT()))
+
+	r := setupRouter()
+	req, _ := http.NewRequest("DELETE", "/cardio/1", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var files, laps int64
+	db.Model(&CardioFile{}).Count(&files)
+	db.Model(&CardioLap{}).Count(&laps)
+	assert.Equal(t, int64(0), files)
+	assert.Equal(t, int64(0), laps)
+}
+
+func TestReparseCardioFiles_KeepsSessionIDs(t *testing.T) {
+	db = setupTestDB()
+	postCSV("/cardio", string(testRunFIT()))
+	db.Model(&CardioSession{}).Where("id = ?", 1).Updates(map[string]interface{}{"decoder_version": 0, "distance": 1})
+
+	count, err := reparseCardioFiles(db, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var sessions []CardioSession
+	preloadLaps(db).Find(&sessions)
+	assert.Len(t, sessions, 1)
+	assert.Equal(t, 1, sessions[0].ID)
+	assert.Equal(t, 1800.0, sessions[0].Distance)
+	assert.Equal(t, cardioDecoderVersion, sessions[0].DecoderVersion)
+	assert.Len(t, sessions[0].Laps, 2)
+
+	count, err = reparseCardioFiles(db, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+}
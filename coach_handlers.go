@@ -0,0 +1,201 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// validCoachScope reports whether scope is one an athlete can grant
+func validCoachScope(scope string) bool {
+	return scope == CoachScopeRead || scope == CoachScopeWrite
+}
+
+// preloadLinkUsers loads the athlete and coach on coach links
+func preloadLinkUsers(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Athlete").Preload("Coach")
+}
+
+// coachAccess lets a coach work on an athlete's data by passing
+// ?athlete_id=. Read-only coaches may only make GET requests. Without the
+// parameter the request works on the caller's own data.
+func coachAccess() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		raw := c.Query("athlete_id")
+		if raw == "" {
+			c.Next()
+			return
+		}
+
+		athleteID, err := strconv.Atoi(raw)
+		if err != nil || athleteID <= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid athlete_id"})
+			return
+		}
+
+		coachID := currentUserID(c)
+		if athleteID == coachID {
+			c.Next()
+			return
+		}
+
+		var link CoachLink
+		found := db.Where("athlete_id = ? AND coach_id = ? AND accepted_at IS NOT NULL", athleteID, coachID).
+			Limit(1).Find(&link).RowsAffected > 0
+		switch {
+		case !found:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Not a coach for this athlete"})
+			return
+		case c.Request.Method != http.MethodGet && link.Scope != CoachScopeWrite:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Write access not granted"})
+			return
+		}
+
+		c.Set(actorIDKey, coachID)
+		c.Set(userIDKey, athleteID)
+		c.Next()
+	}
+}
+
+// inviteCoach handles POST /coaches, inviting a registered user to coach the
+// caller
+func inviteCoach(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+		Scope string `json:"scope"`
+	}
+	var coach User
+
+	switch {
+	case c.ShouldBindJSON(&req) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !validCoachScope(req.Scope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope"})
+		return
+	case db.Where("email = ?", normalizeEmail(req.Email)).Limit(1).Find(&coach).RowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	case coach.ID == currentUserID(c):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot coach yourself"})
+		return
+	}
+
+	link := CoachLink{AthleteID: currentUserID(c), CoachID: coach.ID, Scope: req.Scope}
+	var count int64
+	db.Model(&CoachLink{}).Where("athlete_id = ? AND coach_id = ?", link.AthleteID, link.CoachID).Count(&count)
+	switch {
+	case count > 0:
+		c.JSON(http.StatusConflict, gin.H{"error": "Coach already invited"})
+	case db.Create(&link).Error != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to invite coach"})
+	default:
+		preloadLinkUsers(db).First(&link, link.ID)
+		c.JSON(http.StatusCreated, link)
+	}
+}
+
+// getCoaches handles GET /coaches, listing the caller's coaches and pending
+// invitations
+func getCoaches(c *gin.Context) {
+	var links []CoachLink
+	switch err := preloadLinkUsers(db).Where("athlete_id = ?", currentUserID(c)).Order("id ASC").Find(&links).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, links)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch coaches"})
+	}
+}
+
+// updateCoach handles PUT /coaches/:id, changing the scope granted to a coach
+func updateCoach(c *gin.Context) {
+	id := c.Param("id")
+	var link CoachLink
+	var req struct {
+		Scope string `json:"scope"`
+	}
+
+	switch {
+	case preloadLinkUsers(db).Where("athlete_id = ?", currentUserID(c)).First(&link, id).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Coach not found"})
+		return
+	case c.ShouldBindJSON(&req) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !validCoachScope(req.Scope):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope"})
+		return
+	case db.Model(&link).Update("scope", req.Scope).Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update coach"})
+		return
+	default:
+		c.JSON(http.StatusOK, link)
+	}
+}
+
+// deleteCoach handles DELETE /coaches/:id, revoking a coach's access
+func deleteCoach(c *gin.Context) {
+	deleteCoachLink(c, "athlete_id", "Coach")
+}
+
+// getAthletes handles GET /athletes, listing the athletes the caller coaches
+// or has been invited to coach
+func getAthletes(c *gin.Context) {
+	var links []CoachLink
+	switch err := preloadLinkUsers(db).Where("coach_id = ?", currentUserID(c)).Order("id ASC").Find(&links).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, links)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch athletes"})
+	}
+}
+
+// acceptAthlete handles POST /athletes/:id/accept, accepting an invitation
+// to coach an athlete
+func acceptAthlete(c *gin.Context) {
+	id := c.Param("id")
+	var link CoachLink
+
+	if err := preloadLinkUsers(db).Where("coach_id = ?", currentUserID(c)).First(&link, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Invitation not found"})
+		return
+	}
+	if link.AcceptedAt == nil {
+		now := time.Now()
+		link.AcceptedAt = &now
+		if err := db.Model(&link).Update("accepted_at", link.AcceptedAt).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to accept invitation"})
+			return
+		}
+	}
+	c.JSON(http.StatusOK, link)
+}
+
+// deleteAthlete handles DELETE /athletes/:id, declining an invitation or
+// no longer coaching an athlete
+func deleteAthlete(c *gin.Context) {
+	deleteCoachLink(c, "coach_id", "Athlete")
+}
+
+// deleteCoachLink removes a coach link the caller is party to through column
+func deleteCoachLink(c *gin.Context, column, label string) {
+	id := c.Param("id")
+
+	// Links are removed outright so the pair can be linked again later
+	result := db.Unscoped().Where("id = ? AND "+column+" = ?", id, currentUserID(c)).Delete(&CoachLink{})
+	switch {
+	case result.Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove " + strings.ToLower(label)})
+	case result.RowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": label + " not found"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": label + " removed successfully"})
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// sendJSON sends a request with a JSON body and an optional bearer token
+func sendJSON(r *gin.Engine, method, path string, body interface{}, token string) *httptest.ResponseRecorder {
+	var reqBody []byte
+	if body != nil {
+		reqBody, _ = json.Marshal(body)
+	}
+	req, _ := http.NewRequest(method, path, bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// linkCoach has the athlete invite the coach with scope and returns the link
+func linkCoach(t *testing.T, r *gin.Engine, athlete TokenPair, coachEmail, scope string) CoachLink {
+	w := sendJSON(r, "POST", "/coaches", gin.H{"email": coachEmail, "scope": scope}, athlete.AccessToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var link CoachLink
+	err := json.Unmarshal(w.Body.Bytes(), &link)
+	assert.NoError(t, err)
+	return link
+}
+
+func TestCoachAccess_ReadScope(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	athlete := registerAndLogin(t, r, "athlete@example.com")
+	coach := registerAndLogin(t, r, "coach@example.com")
+	sendJSON(r, "POST", "/weights", Weight{Date: "2023-10-01", Weight: 80}, athlete.AccessToken)
+
+	link := linkCoach(t, r, athlete, "coach@example.com", CoachScopeRead)
+	path := fmt.Sprintf("/weights?athlete_id=%d", link.AthleteID)
+
+	// Not usable until the coach accepts
+	w := sendJSON(r, "GET", path, nil, coach.AccessToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = sendJSON(r, "POST", fmt.Sprintf("/athletes/%d/accept", link.ID), nil, coach.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendJSON(r, "GET", path, nil, coach.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var weights []Weight
+	err := json.Unmarshal(w.Body.Bytes(), &weights)
+	assert.NoError(t, err)
+	assert.Len(t, weights, 1)
+
+	w = sendJSON(r, "POST", path, Weight{Date: "2023-10-02", Weight: 79}, coach.AccessToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCoachAccess_WriteScopeRecordsAuthor(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	athlete := registerAndLogin(t, r, "athlete@example.com")
+	coach := registerAndLogin(t, r, "coach@example.com")
+
+	link := linkCoach(t, r, athlete, "coach@example.com", CoachScopeRead)
+	sendJSON(r, "POST", fmt.Sprintf("/athletes/%d/accept", link.ID), nil, coach.AccessToken)
+
+	w := sendJSON(r, "PUT", fmt.Sprintf("/coaches/%d", link.ID), gin.H{"scope": CoachScopeWrite}, athlete.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	path := fmt.Sprintf("/meals?athlete_id=%d", link.AthleteID)
+	w = sendJSON(r, "POST", path, Meal{Name: "Refeed", Date: "2023-10-01", Carbs: 100, Protein: 50, Fats: 10, Calories: 690}, coach.AccessToken)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var meal Meal
+	err := json.Unmarshal(w.Body.Bytes(), &meal)
+	assert.NoError(t, err)
+	assert.Equal(t, link.CoachID, meal.CreatedBy)
+	assert.Equal(t, link.CoachID, meal.UpdatedBy)
+
+	// The meal belongs to the athlete
+	var stored Meal
+	db.First(&stored, meal.ID)
+	assert.Equal(t, link.AthleteID, stored.UserID)
+
+	// Athlete edits their own meal; the audit keeps the coach as author
+	w = sendJSON(r, "PUT", fmt.Sprintf("/meals/%d", meal.ID), Meal{Name: "Refeed", Date: "2023-10-01", Carbs: 120, Protein: 50, Fats: 10, Calories: 770, Audit: Audit{CreatedBy: 99}}, athlete.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	db.First(&stored, meal.ID)
+	assert.Equal(t, link.CoachID, stored.CreatedBy)
+	assert.Equal(t, link.AthleteID, stored.UpdatedBy)
+
+	// Revoking access locks the coach out
+	w = sendJSON(r, "DELETE", fmt.Sprintf("/coaches/%d", link.ID), nil, athlete.AccessToken)
+	assert.Equal(t, http.StatusOK, w.Code)
+	w = sendJSON(r, "GET", path, nil, coach.AccessToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestInviteCoach_Invalid(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	athlete := registerAndLogin(t, r, "athlete@example.com")
+	registerAndLogin(t, r, "coach@example.com")
+
+	w := sendJSON(r, "POST", "/coaches", gin.H{"email": "coach@example.com", "scope": "admin"}, athlete.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = sendJSON(r, "POST", "/coaches", gin.H{"email": "nobody@example.com", "scope": CoachScopeRead}, athlete.AccessToken)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	linkCoach(t, r, athlete, "coach@example.com", CoachScopeRead)
+	w = sendJSON(r, "POST", "/coaches", gin.H{"email": "coach@example.com", "scope": CoachScopeWrite}, athlete.AccessToken)
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestCoachAccess_UnlinkedUser(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	registerAndLogin(t, r, "athlete@example.com")
+	stranger := registerAndLogin(t, r, "stranger@example.com")
+
+	w := sendJSON(r, "GET", "/exercises?athlete_id=2", nil, stranger.AccessToken)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = sendJSON(r, "GET", "/exercises?athlete_id=abc", nil, stranger.AccessToken)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
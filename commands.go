@@ -0,0 +1,338 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+)
+
+// runCommand runs a command-line subcommand of the server binary against the
+// configured database
+func runCommand(args []string) error {
+	switch args[0] {
+	case "load-foods":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s load-foods <foods.csv>", os.Args[0])
+		}
+		return runLoadFoods(args[1])
+	case "assign-default-user":
+		if len(args) < 2 || len(args) > 3 {
+			return fmt.Errorf("usage: %s assign-default-user <email> [password]", os.Args[0])
+		}
+		return runAssignDefaultUser(args[1], args[2:])
+	case "grant-admin":
+		if len(args) != 2 {
+			return fmt.Errorf("usage: %s grant-admin <email>", os.Args[0])
+		}
+		return runGrantAdmin(args[1])
+	case "convert-units":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: %s convert-units <email> <kg|lb>", os.Args[0])
+		}
+		return runConvertUnits(args[1], args[2])
+	case "date-issues":
+		return runDateIssues()
+	case "import-workouts":
+		if len(args) < 3 || len(args) > 4 {
+			return fmt.Errorf("usage: %s import-workouts <email> <export.csv> [kg|lb]", os.Args[0])
+		}
+		return runImportWorkouts(args[1], args[2], args[3:])
+	case "import-myfitnesspal":
+		if len(args) < 3 || len(args) > 4 {
+			return fmt.Errorf("usage: %s import-myfitnesspal <email> <nutrition.csv> [mdy|dmy]", os.Args[0])
+		}
+		return runImportMyFitnessPal(args[1], args[2], args[3:])
+	case "import-apple-health":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: %s import-apple-health <email> <export.xml|export.zip>", os.Args[0])
+		}
+		return runImportAppleHealth(args[1], args[2])
+	case "reparse-cardio":
+		if len(args) > 2 || len(args) == 2 && args[1] != "all" {
+			return fmt.Errorf("usage: %s reparse-cardio [all]", os.Args[0])
+		}
+		return runReparseCardio(len(args) == 2)
+	case "export-archive":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: %s export-archive <email> <archive.json>", os.Args[0])
+		}
+		return runExportArchive(args[1], args[2])
+	case "import-archive":
+		if len(args) < 3 || len(args) > 4 || len(args) == 4 && args[3] != ConflictSkip && args[3] != ConflictFail {
+			return fmt.Errorf("usage: %s import-archive <email> <archive.json> [skip|fail]", os.Args[0])
+		}
+		return runImportArchive(args[1], args[2], args[3:])
+	default:
+		return fmt.Errorf("unknown command %q", args[0])
+	}
+}
+
+// runLoadFoods seeds the food database from a local CSV file
+func runLoadFoods(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := loadFoodsCSV(db, file)
+	if err != nil {
+		return err
+	}
+	for _, skipped := range result.Skipped {
+		log.Println("Skipped", skipped)
+	}
+	log.Printf("Loaded foods: %d created, %d updated, %d skipped", result.Created, result.Updated, len(result.Skipped))
+	return nil
+}
+
+// runAssignDefaultUser gives every row logged before user accounts existed to
+// the user with the email, creating that user when a password is given
+func runAssignDefaultUser(email string, password []string) error {
+	var user User
+	if db.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).RowsAffected == 0 {
+		if len(password) == 0 {
+			return fmt.Errorf("no user with email %s; pass a password to create one", email)
+		}
+		if !validEmail(email) || len(password[0]) < minPasswordLength {
+			return fmt.Errorf("invalid email or password shorter than %d characters", minPasswordLength)
+		}
+		created, err := createUser(db, email, password[0])
+		if err != nil {
+			return err
+		}
+		user = *created
+		log.Println("Created user", user.Email)
+	}
+
+	count, err := assignOrphanedRows(db, user.ID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Assigned %d rows to %s", count, user.Email)
+	return nil
+}
+
+// runGrantAdmin lets the user with the email change the movement and food
+// catalogs
+func runGrantAdmin(email string) error {
+	result := db.Model(&User{}).Where("email = ?", normalizeEmail(email)).Update("admin", true)
+	switch {
+	case result.Error != nil:
+		return result.Error
+	case result.RowsAffected == 0:
+		return fmt.Errorf("no user with email %s", email)
+	}
+	log.Println("Granted admin to", normalizeEmail(email))
+	return nil
+}
+
+// runDateIssues lists the rows whose dates could not be converted when the
+// date columns were migrated
+func runDateIssues() error {
+	var issues []DateMigrationIssue
+	if err := db.Order("table_name ASC, row_id ASC").Find(&issues).Error; err != nil {
+		return err
+	}
+	for _, issue := range issues {
+		fmt.Printf("%s\t%d\t%s\n", issue.TableName, issue.RowID, issue.Value)
+	}
+	log.Printf("%d rows with unconverted dates", len(issues))
+	return nil
+}
+
+// runConvertUnits converts the masses a user logged before units existed from
+// the unit they were logged in to kilograms
+func runConvertUnits(email, unit string) error {
+	unit = strings.ToLower(unit)
+	if !validUnit(unit) {
+		return fmt.Errorf("unknown unit %q; use %s or %s", unit, UnitKg, UnitLb)
+	}
+	var user User
+	if db.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).RowsAffected == 0 {
+		return fmt.Errorf("no user with email %s", email)
+	}
+	switch err := convertLegacyMasses(db, user.ID, unit); err {
+	case nil:
+	case errUnitsConverted:
+		return fmt.Errorf("masses of %s were already converted", user.Email)
+	default:
+		return err
+	}
+	log.Printf("Converted masses of %s from %s to kilograms", user.Email, unit)
+	return nil
+}
+
+// runImportWorkouts imports a Strong or Hevy CSV export for the user with the
+// email. Strong loads are read in the given unit when the export names none,
+// and in the user's own unit otherwise.
+func runImportWorkouts(email, path string, unit []string) error {
+	var user User
+	if db.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).RowsAffected == 0 {
+		return fmt.Errorf("no user with email %s", email)
+	}
+	defaultUnit := userUnit(user.ID)
+	if len(unit) > 0 {
+		defaultUnit = strings.ToLower(unit[0])
+	}
+	if !validUnit(defaultUnit) {
+		return fmt.Errorf("unknown unit %q; use %s or %s", defaultUnit, UnitKg, UnitLb)
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := importWorkoutLog(file, user.ID, user.ID, defaultUnit, false)
+	if err != nil {
+		return err
+	}
+	for _, problem := range result.Errors {
+		log.Println("Invalid row:", problem)
+	}
+	if len(result.Errors) > 0 {
+		return fmt.Errorf("%d invalid rows; nothing imported", len(result.Errors))
+	}
+	log.Printf("Imported %s export for %s: %d workouts, %d exercises, %d already imported, %d sets without reps skipped",
+		result.Source, user.Email, result.Workouts, result.Exercises, result.Duplicates, result.SkippedSets)
+	return nil
+}
+
+// runImportMyFitnessPal imports a MyFitnessPal nutrition export for the user
+// with the email, reading numeric dates in the given day and month order or
+// the order the export's dates show
+func runImportMyFitnessPal(email, path string, order []string) error {
+	var user User
+	if db.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).RowsAffected == 0 {
+		return fmt.Errorf("no user with email %s", email)
+	}
+	dateOrder := ""
+	if len(order) > 0 {
+		dateOrder = strings.ToLower(order[0])
+		if dateOrder != DateOrderMDY && dateOrder != DateOrderDMY {
+			return fmt.Errorf("unknown date order %q; use %s or %s", dateOrder, DateOrderMDY, DateOrderDMY)
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	result, err := importMyFitnessPal(file, user.ID, user.ID, dateOrder, false)
+	if err != nil {
+		return err
+	}
+	for _, skipped := range result.Skipped {
+		log.Println("Skipped", skipped)
+	}
+	for _, ambiguous := range result.Ambiguous {
+		log.Println("Ambiguous", ambiguous)
+	}
+	log.Printf("Imported %d meals for %s: %d skipped, %d ambiguous", result.Imported, user.Email, len(result.Skipped), len(result.Ambiguous))
+	return nil
+}
+
+// runImportAppleHealth imports the weigh-ins, body fat readings and workouts
+// of an Apple Health export for the user with the email
+func runImportAppleHealth(email, path string) error {
+	var user User
+	if db.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).RowsAffected == 0 {
+		return fmt.Errorf("no user with email %s", email)
+	}
+
+	export, err := openAppleHealthExport(path)
+	if err != nil {
+		return err
+	}
+	defer export.Close()
+
+	result, err := importAppleHealth(export, user.ID)
+	if err != nil {
+		return err
+	}
+	log.Printf("Imported Apple Health export for %s: %d weigh-ins, %d updated, %d workouts, %d already imported, %d skipped",
+		user.Email, result.Weights, result.Updated, result.Workouts, result.Duplicates, result.Skipped)
+	return nil
+}
+
+// runReparseCardio decodes stored cardio files again with the current
+// decoder, either those decoded by an older version or all of them
+func runReparseCardio(all bool) error {
+	count, err := reparseCardioFiles(db, all)
+	if err != nil {
+		return err
+	}
+	log.Printf("Decoded %d cardio files again", count)
+	return nil
+}
+
+// runExportArchive writes the archive of the user with the email to a file
+func runExportArchive(email, path string) error {
+	var user User
+	if db.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).RowsAffected == 0 {
+		return fmt.Errorf("no user with email %s", email)
+	}
+
+	archive, err := buildArchive(user.ID)
+	if err != nil {
+		return err
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := json.NewEncoder(file).Encode(archive); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	log.Printf("Exported archive for %s to %s", user.Email, path)
+	return nil
+}
+
+// runImportArchive restores an archive file into the account of the user with
+// the email, skipping records it already has unless conflict is fail
+func runImportArchive(email, path string, conflict []string) error {
+	var user User
+	if db.Where("email = ?", normalizeEmail(email)).Limit(1).Find(&user).RowsAffected == 0 {
+		return fmt.Errorf("no user with email %s", email)
+	}
+	onConflict := ConflictSkip
+	if len(conflict) > 0 {
+		onConflict = conflict[0]
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	var archive Archive
+	if err := json.NewDecoder(file).Decode(&archive); err != nil {
+		return fmt.Errorf("reading archive: %w", err)
+	}
+
+	result, err := restoreArchive(&archive, user.ID, user.ID, onConflict, false)
+	if err != nil {
+		return err
+	}
+	for _, problem := range result.Errors {
+		log.Println("Invalid record:", problem)
+	}
+	switch {
+	case len(result.Errors) > 0:
+		return fmt.Errorf("%d invalid records; nothing imported", len(result.Errors))
+	case onConflict == ConflictFail && result.Conflicts.total() > 0:
+		return fmt.Errorf("%d records already in the account; nothing imported", result.Conflicts.total())
+	}
+	log.Printf("Imported archive for %s: %+v, %d records already in the account", user.Email, result.Imported, result.Conflicts.total())
+	return nil
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Strictness levels for validation checks that can be skipped, flagged with
+// a warning or enforced
+const (
+	StrictnessOff    = "off"
+	StrictnessFlag   = "flag"
+	StrictnessReject = "reject"
+)
+
+// envString returns the value of the environment variable key, or fallback
+// when it is unset or empty
+func envString(key, fallback string) string {
+	if value := strings.TrimSpace(os.Getenv(key)); value != "" {
+		return value
+	}
+	return fallback
+}
+
+// envFloat returns the environment variable key parsed as a float, or
+// fallback when it is unset or not a number
+func envFloat(key string, fallback float64) float64 {
+	value, err := strconv.ParseFloat(envString(key, ""), 64)
+	if err != nil {
+		return fallback
+	}
+	return value
+}
+
+// envStrictness returns the strictness level set in the environment variable
+// key, or fallback when it is unset or unknown
+func envStrictness(key, fallback string) string {
+	switch strictness := strings.ToLower(envString(key, fallback)); strictness {
+	case StrictnessOff, StrictnessFlag, StrictnessReject:
+		return strictness
+	default:
+		return fallback
+	}
+}
+
+// movementStrictness reports how createExercise treats movements missing from
+// the catalog, set through MOVEMENT_STRICTNESS
+func movementStrictness() string {
+	return envStrictness("MOVEMENT_STRICTNESS", StrictnessFlag)
+}
+
+// calorieCheck reports how meals whose calories disagree with their macros
+// are handled, set through MEAL_CALORIE_CHECK
+func calorieCheck() string {
+	return envStrictness("MEAL_CALORIE_CHECK", StrictnessFlag)
+}
+
+// calorieTolerance is the percentage meal calories may differ from the
+// calories implied by their macros, set through MEAL_CALORIE_TOLERANCE
+func calorieTolerance() float64 {
+	return envFloat("MEAL_CALORIE_TOLERANCE", 10)
+}
+
+// energyDensity is the energy in kcal stored or released per kilogram of
+// bodyweight change, set through ENERGY_DENSITY
+func energyDensity() float64 {
+	return envFloat("ENERGY_DENSITY", 7700)
+}
+
+// goalRate is the target bodyweight change in kilograms per week, negative
+// for loss, set through GOAL_RATE
+func goalRate() float64 {
+	return envFloat("GOAL_RATE", 0)
+}
+
+// goalRateTolerance is how far in kilograms per week the actual rate of
+// change may stray from a goal's required rate before the goal is off track,
+// set through GOAL_RATE_TOLERANCE
+func goalRateTolerance() float64 {
+	return envFloat("GOAL_RATE_TOLERANCE", 0.25)
+}
+
+// accessTokenTTL is how long an access token is valid, set in minutes
+// through ACCESS_TOKEN_TTL
+func accessTokenTTL() time.Duration {
+	return time.Duration(envFloat("ACCESS_TOKEN_TTL", 15) * float64(time.Minute))
+}
+
+// refreshTokenTTL is how long a refresh token is valid, set in days through
+// REFRESH_TOKEN_TTL
+func refreshTokenTTL() time.Duration {
+	return time.Duration(envFloat("REFRESH_TOKEN_TTL", 30) * float64(24*time.Hour))
+}
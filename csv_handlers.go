@@ -0,0 +1,587 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Column layouts of the CSV exports. Imports accept the same columns in any
+// order and ignore columns they do not know.
+var (
+	exerciseCSVColumns = []string{"id", "date", "occurred_at", "movement", "type", "set_number", "reps", "load", "unit", "rpe", "set_type"}
+	mealCSVColumns     = []string{"id", "date", "occurred_at", "name", "carbs", "protein", "fat", "calories"}
+	weightCSVColumns   = []string{"id", "date", "occurred_at", "weight", "unit"}
+)
+
+// csvBatchSize is the number of rows read or written at a time
+const csvBatchSize = 500
+
+// maxImportBytes limits the size of an uploaded CSV
+const maxImportBytes = 10 << 20
+
+// csvFieldError is a CSV value that cannot be parsed
+type csvFieldError struct {
+	Field   string
+	Message string
+}
+
+func (e csvFieldError) Error() string { return e.Message }
+
+// csvRow reads the values of one CSV record by column name
+type csvRow struct {
+	Line    int
+	record  []string
+	columns map[string]int
+}
+
+// value returns the trimmed value of a column, or "" when it is missing
+func (r csvRow) value(column string) string {
+	if i, ok := r.columns[column]; ok && i < len(r.record) {
+		return strings.TrimSpace(r.record[i])
+	}
+	return ""
+}
+
+// number parses a column as a number, treating an empty value as zero
+func (r csvRow) number(column string) (float64, error) {
+	raw := r.value(column)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return 0, csvFieldError{Field: column, Message: fmt.Sprintf("%s must be a number, got %q", column, raw)}
+	}
+	return n, nil
+}
+
+// integer parses a column as a whole number, treating an empty value as zero
+func (r csvRow) integer(column string) (int, error) {
+	raw := r.value(column)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, csvFieldError{Field: column, Message: fmt.Sprintf("%s must be a whole number, got %q", column, raw)}
+	}
+	return n, nil
+}
+
+// timestamp parses a column as an RFC 3339 timestamp, returning nil when it
+// is empty
+func (r csvRow) timestamp(column string) (*time.Time, error) {
+	raw := r.value(column)
+	if raw == "" {
+		return nil, nil
+	}
+	t, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return nil, csvFieldError{Field: column, Message: fmt.Sprintf("%s must be an RFC 3339 timestamp, got %q", column, raw)}
+	}
+	return &t, nil
+}
+
+// unit returns the mass unit of the row, or fallback when it names none
+func (r csvRow) unit(fallback string) (string, error) {
+	unit := strings.ToLower(r.value("unit"))
+	switch {
+	case unit == "":
+		return fallback, nil
+	case !validUnit(unit):
+		return "", csvFieldError{Field: "unit", Message: fmt.Sprintf("unit must be %s or %s, got %q", UnitKg, UnitLb, unit)}
+	}
+	return unit, nil
+}
+
+// rowError is the per-row error reported by an import: the validator's error
+// response, or a field error for a value that could not be parsed, with the
+// line it was found on
+func rowError(line int, problem gin.H) gin.H {
+	row := gin.H{"line": line}
+	for key, value := range problem {
+		row[key] = value
+	}
+	return row
+}
+
+// parseProblem is the error response for a CSV value that cannot be parsed
+func parseProblem(err error) gin.H {
+	if fieldErr, ok := err.(csvFieldError); ok {
+		return gin.H{"error": "Invalid value", "field": fieldErr.Field, "message": fieldErr.Message}
+	}
+	return gin.H{"error": "Invalid value", "message": err.Error()}
+}
+
+// formatCSVNumber formats a number without trailing zeros
+func formatCSVNumber(n float64) string {
+	return strconv.FormatFloat(n, 'f', -1, 64)
+}
+
+// formatCSVTime formats an optional timestamp in the user's time zone
+func formatCSVTime(t *time.Time, loc *time.Location) string {
+	if t == nil {
+		return ""
+	}
+	return t.In(loc).Format(time.RFC3339)
+}
+
+// exportQuery selects the rows of a user's table for export, oldest first,
+// within the optional from and to dates
+func exportQuery(c *gin.Context, from, to string) *gorm.DB {
+	query := userScope(c)
+	if from != "" {
+		query = query.Where("date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("date <= ?", to)
+	}
+	return query
+}
+
+// streamCSV sends a CSV download. rows is called with a function writing one
+// record, which is flushed to the client as the buffer fills.
+func streamCSV(c *gin.Context, filename string, header []string, rows func(write func([]string) error) error) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Status(http.StatusOK)
+
+	writer := csv.NewWriter(c.Writer)
+	err := writer.Write(header)
+	if err == nil {
+		err = rows(writer.Write)
+	}
+	writer.Flush()
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		// The status has been sent, so the download is cut short
+		log.Println("CSV export failed:", err)
+	}
+}
+
+// readUpload passes the uploaded file, sent either as the "file" field of a
+// multipart form or as the request body, to read
+func readUpload(c *gin.Context, read func(body io.Reader) error) error {
+	return readUploadLimit(c, maxImportBytes, read)
+}
+
+// readUploadLimit is readUpload for uploads of up to limit bytes
+func readUploadLimit(c *gin.Context, limit int64, read func(body io.Reader) error) error {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, limit)
+	if !strings.HasPrefix(c.ContentType(), "multipart/") {
+		return read(c.Request.Body)
+	}
+	upload, err := c.FormFile("file")
+	if err != nil {
+		return fmt.Errorf("missing file upload")
+	}
+	file, err := upload.Open()
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	return read(file)
+}
+
+// readCSVRows reads a CSV whose values are separated by commas or, as some
+// spreadsheet exports do, by semicolons. columns maps its header to the
+// column index of each known field; rows are numbered by line.
+func readCSVRows(body io.Reader, columns func(header []string) (map[string]int, error)) ([]csvRow, error) {
+	buffered := bufio.NewReader(body)
+	reader := csv.NewReader(buffered)
+	reader.Comma = csvDelimiter(buffered)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+	header, err := reader.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading header: %w", err)
+	}
+	mapped, err := columns(header)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []csvRow
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		switch {
+		case err == io.EOF:
+			return rows, nil
+		case err != nil:
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		rows = append(rows, csvRow{Line: line, record: record, columns: mapped})
+	}
+}
+
+// csvDelimiter guesses the separator of a CSV from its header line without
+// consuming it
+func csvDelimiter(r *bufio.Reader) rune {
+	head, _ := r.Peek(r.Size())
+	if end := bytes.IndexByte(head, '\n'); end >= 0 {
+		head = head[:end]
+	}
+	if bytes.Count(head, []byte(";")) > bytes.Count(head, []byte(",")) {
+		return ';'
+	}
+	return ','
+}
+
+// readImportCSV reads the uploaded CSV, mapping its header to the columns of
+// layout
+func readImportCSV(c *gin.Context, layout []string) ([]csvRow, error) {
+	known := map[string]string{}
+	for _, column := range layout {
+		known[csvHeaderKey(column)] = column
+	}
+
+	var rows []csvRow
+	err := readUpload(c, func(body io.Reader) (err error) {
+		rows, err = readCSVRows(body, func(header []string) (map[string]int, error) {
+			columns := map[string]int{}
+			for i, name := range header {
+				if column, ok := known[csvHeaderKey(name)]; ok {
+					columns[column] = i
+				}
+			}
+			return columns, nil
+		})
+		return err
+	})
+	return rows, err
+}
+
+// ImportResult reports how many records a CSV import holds and the errors
+// found in its rows. Nothing is saved unless every row is valid.
+type ImportResult struct {
+	DryRun  bool    `json:"dry_run"`
+	Records int     `json:"records"`
+	Errors  []gin.H `json:"errors"`
+}
+
+// readImport reads the uploaded CSV and parses each row or group of rows
+// into a record with parse, which returns the record count and the row errors
+func readImport(c *gin.Context, layout []string, parse func(rows []csvRow) (int, []gin.H)) (*ImportResult, bool) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+		return nil, false
+	}
+
+	rows, err := readImportCSV(c, layout)
+	switch {
+	case err != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV", "message": err.Error()})
+		return nil, false
+	case len(rows) == 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "CSV has no rows"})
+		return nil, false
+	}
+
+	count, errs := parse(rows)
+	return &ImportResult{DryRun: dryRun, Records: count, Errors: errs}, true
+}
+
+// finishImport reports a parsed import and, when it is not a dry run and
+// every row is valid, creates its records with create in one transaction
+func finishImport(c *gin.Context, result *ImportResult, create func(tx *gorm.DB) error) {
+	switch {
+	case len(result.Errors) > 0:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case result.DryRun:
+		c.JSON(http.StatusOK, result)
+	case db.Transaction(create) != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import rows"})
+	default:
+		c.JSON(http.StatusCreated, result)
+	}
+}
+
+// exportExercises handles GET /export/exercises.csv with one row per set
+func exportExercises(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+		return
+	}
+	display, loc := displayMasses(c), currentLocation(c)
+
+	streamCSV(c, "exercises.csv", exerciseCSVColumns, func(write func([]string) error) error {
+		var batch []Exercise
+		return preloadSets(exportQuery(c, from, to)).FindInBatches(&batch, csvBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, exercise := range batch {
+				exercise.convertMass(display)
+				sets := exercise.SetDetails
+				if len(sets) == 0 {
+					sets = flatSets(&exercise)
+				}
+				for _, set := range sets {
+					rpe := ""
+					if set.RPE != nil {
+						rpe = formatCSVNumber(*set.RPE)
+					}
+					err := write([]string{
+						strconv.Itoa(exercise.ID), string(exercise.Date), formatCSVTime(exercise.OccurredAt, loc),
+						exercise.Movement, exercise.Type, strconv.Itoa(set.SetNumber), strconv.Itoa(set.Reps),
+						formatCSVNumber(set.Load), display.To, rpe, set.SetType,
+					})
+					if err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// importExercises handles POST /import/exercises. Rows sharing an id are the
+// sets of one exercise; rows without one are single-set exercises.
+func importExercises(c *gin.Context) {
+	loc, unit := currentLocation(c), displayUnit(c)
+	owner, audit := currentUserID(c), Audit{CreatedBy: actingUserID(c), UpdatedBy: actingUserID(c)}
+	var exercises []Exercise
+
+	result, ok := readImport(c, exerciseCSVColumns, func(rows []csvRow) (int, []gin.H) {
+		var groups [][]csvRow
+		byID := map[string]int{}
+		for _, row := range rows {
+			id := row.value("id")
+			if i, seen := byID[id]; seen && id != "" {
+				groups[i] = append(groups[i], row)
+				continue
+			}
+			byID[id] = len(groups)
+			groups = append(groups, []csvRow{row})
+		}
+
+		errs := []gin.H{}
+		for _, group := range groups {
+			exercise, err := parseExerciseRows(group, unit)
+			if err != nil {
+				errs = append(errs, rowError(group[0].Line, parseProblem(err)))
+				continue
+			}
+			exercise.UserID, exercise.Audit = owner, audit
+			if problem := validateExercise(exercise, loc); problem != nil {
+				errs = append(errs, rowError(group[0].Line, problem))
+				continue
+			}
+			exercises = append(exercises, *exercise)
+		}
+		return len(groups), errs
+	})
+	if ok {
+		finishImport(c, result, func(tx *gorm.DB) error {
+			return tx.CreateInBatches(&exercises, csvBatchSize).Error
+		})
+	}
+}
+
+// parseExerciseRows builds an exercise from the rows holding its sets. The
+// exercise's date, movement and type come from the first row; loads are
+// converted to kilograms from each row's unit.
+func parseExerciseRows(rows []csvRow, defaultUnit string) (*Exercise, error) {
+	first := rows[0]
+	occurredAt, err := first.timestamp("occurred_at")
+	if err != nil {
+		return nil, err
+	}
+	exercise := &Exercise{
+		Date:       Date(first.value("date")),
+		OccurredAt: occurredAt,
+		Movement:   first.value("movement"),
+		Type:       first.value("type"),
+	}
+
+	for _, row := range rows {
+		var set ExerciseSet
+		var unit string
+		if set.SetNumber, err = row.integer("set_number"); err != nil {
+			return nil, err
+		}
+		if set.Reps, err = row.integer("reps"); err != nil {
+			return nil, err
+		}
+		if set.Load, err = row.number("load"); err != nil {
+			return nil, err
+		}
+		if unit, err = row.unit(defaultUnit); err != nil {
+			return nil, err
+		}
+		if row.value("rpe") != "" {
+			rpe, err := row.number("rpe")
+			if err != nil {
+				return nil, err
+			}
+			set.RPE = &rpe
+		}
+		set.SetType = strings.ToLower(row.value("set_type"))
+		set.Load = massConverter{From: unit, To: UnitKg}.mass(set.Load)
+		exercise.SetDetails = append(exercise.SetDetails, set)
+	}
+	return exercise, nil
+}
+
+// exportMeals handles GET /export/meals.csv with one row per meal
+func exportMeals(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+		return
+	}
+	loc := currentLocation(c)
+
+	streamCSV(c, "meals.csv", mealCSVColumns, func(write func([]string) error) error {
+		var batch []Meal
+		return exportQuery(c, from, to).FindInBatches(&batch, csvBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, meal := range batch {
+				err := write([]string{
+					strconv.Itoa(meal.ID), string(meal.Date), formatCSVTime(meal.OccurredAt, loc), meal.Name,
+					strconv.Itoa(meal.Carbs), strconv.Itoa(meal.Protein), strconv.Itoa(meal.Fats), strconv.Itoa(meal.Calories),
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// importMeals handles POST /import/meals with one row per meal
+func importMeals(c *gin.Context) {
+	loc := currentLocation(c)
+	owner, audit := currentUserID(c), Audit{CreatedBy: actingUserID(c), UpdatedBy: actingUserID(c)}
+	var meals []Meal
+
+	result, ok := readImport(c, mealCSVColumns, func(rows []csvRow) (int, []gin.H) {
+		errs := []gin.H{}
+		for _, row := range rows {
+			meal, err := parseMealRow(row)
+			if err != nil {
+				errs = append(errs, rowError(row.Line, parseProblem(err)))
+				continue
+			}
+			meal.UserID, meal.Audit = owner, audit
+			if problem := validateMeal(meal, loc); problem != nil {
+				errs = append(errs, rowError(row.Line, problem))
+				continue
+			}
+			meals = append(meals, *meal)
+		}
+		return len(rows), errs
+	})
+	if ok {
+		finishImport(c, result, func(tx *gorm.DB) error {
+			return tx.CreateInBatches(&meals, csvBatchSize).Error
+		})
+	}
+}
+
+// parseMealRow builds a meal from one CSV row
+func parseMealRow(row csvRow) (*Meal, error) {
+	occurredAt, err := row.timestamp("occurred_at")
+	if err != nil {
+		return nil, err
+	}
+	meal := &Meal{Date: Date(row.value("date")), OccurredAt: occurredAt, Name: row.value("name")}
+	targets := map[string]*int{"carbs": &meal.Carbs, "protein": &meal.Protein, "fat": &meal.Fats, "calories": &meal.Calories}
+	for _, column := range mealCSVColumns {
+		if target, ok := targets[column]; ok {
+			if *target, err = row.integer(column); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return meal, nil
+}
+
+// exportWeights handles GET /export/weights.csv with one row per weigh-in
+func exportWeights(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+		return
+	}
+	display, loc := displayMasses(c), currentLocation(c)
+
+	streamCSV(c, "weights.csv", weightCSVColumns, func(write func([]string) error) error {
+		var batch []Weight
+		return exportQuery(c, from, to).FindInBatches(&batch, csvBatchSize, func(tx *gorm.DB, _ int) error {
+			for _, weight := range batch {
+				weight.convertMass(display)
+				err := write([]string{
+					strconv.Itoa(weight.ID), string(weight.Date), formatCSVTime(weight.OccurredAt, loc),
+					formatCSVNumber(weight.Weight), display.To,
+				})
+				if err != nil {
+					return err
+				}
+			}
+			return nil
+		}).Error
+	})
+}
+
+// importWeights handles POST /import/weights with one row per weigh-in
+func importWeights(c *gin.Context) {
+	loc, unit := currentLocation(c), displayUnit(c)
+	owner, audit := currentUserID(c), Audit{CreatedBy: actingUserID(c), UpdatedBy: actingUserID(c)}
+	var weights []Weight
+
+	result, ok := readImport(c, weightCSVColumns, func(rows []csvRow) (int, []gin.H) {
+		errs := []gin.H{}
+		for _, row := range rows {
+			weight, err := parseWeightRow(row, unit)
+			if err != nil {
+				errs = append(errs, rowError(row.Line, parseProblem(err)))
+				continue
+			}
+			weight.UserID, weight.Audit = owner, audit
+			if problem := validateWeight(weight, loc); problem != nil {
+				errs = append(errs, rowError(row.Line, problem))
+				continue
+			}
+			weights = append(weights, *weight)
+		}
+		return len(rows), errs
+	})
+	if ok {
+		finishImport(c, result, func(tx *gorm.DB) error {
+			return tx.CreateInBatches(&weights, csvBatchSize).Error
+		})
+	}
+}
+
+// parseWeightRow builds a weight entry in kilograms from one CSV row
+func parseWeightRow(row csvRow, defaultUnit string) (*Weight, error) {
+	occurredAt, err := row.timestamp("occurred_at")
+	if err != nil {
+		return nil, err
+	}
+	weight := &Weight{Date: Date(row.value("date")), OccurredAt: occurredAt}
+	if weight.Weight, err = row.number("weight"); err != nil {
+		return nil, err
+	}
+	unit, err := row.unit(defaultUnit)
+	if err != nil {
+		return nil, err
+	}
+	weight.Weight = massConverter{From: unit, To: UnitKg}.mass(weight.Weight)
+	return weight, nil
+}
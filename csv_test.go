@@ -0,0 +1,170 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// postCSV uploads a CSV as the request body
+func postCSV(path, body string) *httptest.ResponseRecorder {
+	r := setupRouter()
+	req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+// getCSV downloads a CSV export and parses its records
+func getCSV(t *testing.T, path string) [][]string {
+	r := setupRouter()
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+	records, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	return records
+}
+
+func TestExportWeights(t *testing.T) {
+	db = setupTestDB()
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 80.5})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-02", Weight: 80.25})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-11-01", Weight: 79})
+
+	records := getCSV(t, "/export/weights.csv?to=2023-10-31")
+	assert.Equal(t, [][]string{
+		{"id", "date", "occurred_at", "weight", "unit"},
+		{"1", "2023-10-01", "", "80.5", "kg"},
+		{"2", "2023-10-02", "", "80.25", "kg"},
+	}, records)
+}
+
+func TestExportExercises_RowPerSet(t *testing.T) {
+	db = setupTestDB()
+	setDisplayUnit(UnitLb)
+	rpe := 8.5
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 5, Load: 100, SetType: SetTypeWorking},
+		{SetNumber: 2, Reps: 3, Load: 110, RPE: &rpe, SetType: SetTypeWorking},
+	}})
+
+	records := getCSV(t, "/export/exercises.csv")
+	assert.Equal(t, exerciseCSVColumns, records[0])
+	assert.Equal(t, []string{"1", "2023-10-01", "", "Squat", "", "2", "3", "242.51", "lb", "8.5", "working"}, records[2])
+}
+
+func TestExportExercises_ExpandsFlatFields(t *testing.T) {
+	db = setupTestDB()
+	// Logged before sets were recorded one by one
+	db.Omit("SetDetails").Create(&Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", Sets: 2, Reps: 5, Weight: 100})
+
+	records := getCSV(t, "/export/exercises.csv")
+	assert.Len(t, records, 3)
+	assert.Equal(t, []string{"1", "2023-10-01", "", "Squat", "", "2", "5", "100", "kg", "", "working"}, records[2])
+}
+
+func TestImportExercises_GroupsSetsByID(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/import/exercises", `id,date,movement,set_number,reps,load,unit,set_type
+a,2023-10-01,Bench Press,1,5,225,lb,
+a,2023-10-01,,2,5,225,lb,
+,2023-10-02,Squat,,5,100,,working
+`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var result ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 2, result.Records)
+	assert.Empty(t, result.Errors)
+
+	var exercises []Exercise
+	preloadSets(db).Order("id ASC").Find(&exercises)
+	if assert.Len(t, exercises, 2) {
+		assert.Equal(t, 2, exercises[0].Sets)
+		assert.InDelta(t, 102.058, exercises[0].SetDetails[1].Load, 0.001)
+		assert.Equal(t, testUserID, exercises[1].UserID)
+		assert.Equal(t, testUserID, exercises[1].CreatedBy)
+	}
+}
+
+func TestImportWeights_DryRun(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/import/weights?dry_run=true", "date,weight\n2023-10-01,80\n2023-10-02,79.5\n")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var result ImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.True(t, result.DryRun)
+	assert.Equal(t, 2, result.Records)
+
+	var count int64
+	db.Model(&Weight{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestImportWeights_RowErrors(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/import/weights", `date,weight,unit
+2023-10-01,80,kg
+10/2/23,79.5,kg
+2023-10-03,heavy,kg
+2023-10-04,0,kg
+2023-10-05,80,stone
+`)
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var result struct {
+		Errors []map[string]interface{} `json:"errors"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &result)
+	if assert.Len(t, result.Errors, 4) {
+		assert.Equal(t, float64(3), result.Errors[0]["line"])
+		assert.Equal(t, "date", result.Errors[0]["field"])
+		assert.Equal(t, "weight", result.Errors[1]["field"])
+		assert.Equal(t, "Invalid weight input value", result.Errors[2]["error"])
+		assert.Equal(t, "unit", result.Errors[3]["field"])
+	}
+
+	// The valid row is not saved on its own
+	var count int64
+	db.Model(&Weight{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestImportMeals_MultipartUpload(t *testing.T) {
+	db = setupTestDB()
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	file, _ := form.CreateFormFile("file", "meals.csv")
+	file.Write([]byte("Date,Name,Carbs,Protein,Fat,Calories\n2023-10-01,Oats,60,10,5,325\n"))
+	form.Close()
+
+	r := setupRouter()
+	req, _ := http.NewRequest("POST", "/import/meals", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var meal Meal
+	db.First(&meal)
+	assert.Equal(t, "Oats", meal.Name)
+	assert.Equal(t, 325, meal.Calories)
+	assert.Equal(t, Date("2023-10-01"), meal.Date)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gorm.io/driver/mysql"
@@ -23,16 +24,71 @@ func InitDatabase() {
 	username := os.Getenv("DB_USERNAME")
 	password := os.Getenv("DB_PASSWORD")
 
-	// Database connection
-	dsn := fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/exercise_db?charset=utf8mb4&parseTime=True&loc=Local", username, password)
+	// Database connection. Instants are stored in UTC; the calendar day they
+	// fall on depends on each user's time zone, not the server's. Databases
+	// written with loc=Local hold server local times and need the one-off
+	// conversion described in the README before this connects to them.
+	dsn := fmt.Sprintf("%s:%s@tcp(127.0.0.1:3306)/exercise_db?charset=utf8mb4&parseTime=True&loc=UTC", username, password)
 	var err error
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{})
+	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{NowFunc: func() time.Time { return time.Now().UTC() }})
 	if err != nil {
 		log.Fatal("Failed to connect to database:", err)
 	}
 
+	// Convert free-form dates before their columns become DATE
+	result, err := migrateLegacyDates(db)
+	if err != nil {
+		log.Fatal("Failed to migrate dates:", err)
+	}
+	logDateMigration(result)
+
 	// Auto migrate the schema
+	db.AutoMigrate(&User{}, &RefreshToken{}, &CoachLink{}, &APIToken{})
+	db.AutoMigrate(&MigrationMark{})
 	db.AutoMigrate(&Exercise{})
-	db.AutoMigrate(&Meal{})
+	db.AutoMigrate(&ExerciseSet{})
+	db.AutoMigrate(&Workout{})
+	db.AutoMigrate(&Movement{}, &MovementAlias{}, &MovementMuscle{})
+	db.AutoMigrate(&Meal{}, &MealItem{})
+	db.AutoMigrate(&Food{}, &FoodServing{})
+	db.AutoMigrate(&Recipe{}, &RecipeIngredient{})
+	db.AutoMigrate(&MacroTarget{})
+	// Targets were once shared by everyone, one per day type
+	if db.Migrator().HasIndex(&MacroTarget{}, "idx_macro_targets_day_type") {
+		if err := db.Migrator().DropIndex(&MacroTarget{}, "idx_macro_targets_day_type"); err != nil {
+			log.Println("Failed to drop the shared macro target index:", err)
+		}
+	}
 	db.AutoMigrate(&Weight{})
+	db.AutoMigrate(&Goal{})
+	db.AutoMigrate(&CardioFile{}, &CardioSession{}, &CardioLap{})
+
+	// Masses written from now on are in kilograms; convert-units only
+	// converts rows logged before this
+	if _, err := markMigration(db, unitsMigration); err != nil {
+		log.Fatal("Failed to record the units migration:", err)
+	}
+
+	// Give exercises logged before per-set logging their sets
+	if count, err := backfillExerciseSets(db); err != nil {
+		log.Println("Failed to backfill exercise sets:", err)
+	} else if count > 0 {
+		log.Printf("Backfilled sets for %d exercises", count)
+	}
+
+	// Seed the movement catalog and link exercises logged before it existed
+	if err := seedMovements(db); err != nil {
+		log.Fatal("Failed to seed movement catalog:", err)
+	}
+	if err := linkExerciseMovements(db); err != nil {
+		log.Println("Failed to link exercises to movements:", err)
+	}
+}
+
+// markMigration records the first time the named migration ran and returns
+// that time
+func markMigration(tx *gorm.DB, name string) (time.Time, error) {
+	mark := MigrationMark{Name: name, AppliedAt: time.Now().UTC()}
+	err := tx.Where(MigrationMark{Name: name}).FirstOrCreate(&mark).Error
+	return mark.AppliedAt, err
 }
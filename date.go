@@ -0,0 +1,92 @@
+package main
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Date is a calendar date stored in a DATE column and exchanged as an
+// ISO-8601 YYYY-MM-DD string
+type Date string
+
+// Valid reports whether the date is an ISO-8601 calendar date
+func (d Date) Valid() bool {
+	_, err := time.Parse(dateLayout, string(d))
+	return err == nil
+}
+
+// GormDataType stores dates in DATE columns
+func (Date) GormDataType() string {
+	return "date"
+}
+
+// Value stores the date, or NULL when it is empty
+func (d Date) Value() (driver.Value, error) {
+	if d == "" {
+		return nil, nil
+	}
+	return string(d), nil
+}
+
+// Scan reads a date from a DATE column, which drivers return as a time, or
+// from the text column it was stored in before the column type changed
+func (d *Date) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*d = ""
+	case time.Time:
+		*d = Date(v.Format(dateLayout))
+	case []byte:
+		*d = dateFromText(string(v))
+	case string:
+		*d = dateFromText(v)
+	default:
+		return fmt.Errorf("cannot scan %T into Date", value)
+	}
+	return nil
+}
+
+// dateFromText keeps the date part of text such as "2023-10-01 00:00:00"
+func dateFromText(text string) Date {
+	if len(text) > len(dateLayout) && Date(text[:len(dateLayout)]).Valid() {
+		return Date(text[:len(dateLayout)])
+	}
+	return Date(text)
+}
+
+// normalizeDate stores occurredAt in UTC, fills in the date from the day
+// occurredAt falls on in loc when only the timestamp was given, and reports
+// whether the date is an ISO-8601 calendar date matching that day
+func normalizeDate(date *Date, occurredAt *time.Time, loc *time.Location) bool {
+	*date = Date(strings.TrimSpace(string(*date)))
+	if occurredAt == nil {
+		return date.Valid()
+	}
+	*occurredAt = occurredAt.UTC()
+	day := Date(occurredAt.In(loc).Format(dateLayout))
+	if *date == "" {
+		*date = day
+	}
+	return *date == day
+}
+
+// dateFieldError is the field-level error for a date normalizeDate rejected
+func dateFieldError(date Date, occurredAt *time.Time) gin.H {
+	message := "date must be an ISO-8601 calendar date (YYYY-MM-DD)"
+	switch {
+	case date == "":
+		message = "date is required"
+	case date.Valid():
+		message = "date does not match the day of occurred_at in the user's time zone"
+	}
+	return gin.H{
+		"error":   "Invalid date",
+		"field":   "date",
+		"value":   date,
+		"message": message,
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"database/sql"
+	"log"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// legacyDateLayouts are the formats accepted when converting dates stored as
+// free-form strings. Slashed dates are read month first.
+var legacyDateLayouts = []string{
+	dateLayout,
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-1-2",
+	"1/2/2006",
+	"1/2/06",
+	"2006/1/2",
+	"Jan 2, 2006",
+	"January 2, 2006",
+	"2 Jan 2006",
+}
+
+// parseLegacyDate converts a free-form date string to a calendar date
+func parseLegacyDate(value string) (Date, bool) {
+	value = strings.TrimSpace(value)
+	for _, layout := range legacyDateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return Date(t.Format(dateLayout)), true
+		}
+	}
+	return "", false
+}
+
+// DateMigrationResult summarises a legacy date migration
+type DateMigrationResult struct {
+	Converted int
+	Issues    []DateMigrationIssue
+}
+
+// migrateLegacyDates rewrites the date columns of tables created before dates
+// were stored as DATE into ISO-8601 form so AutoMigrate can change the column
+// type. Values that cannot be parsed are cleared and recorded as
+// DateMigrationIssue rows. Tables whose date column is already a DATE are
+// left alone.
+func migrateLegacyDates(tx *gorm.DB) (DateMigrationResult, error) {
+	result := DateMigrationResult{Issues: []DateMigrationIssue{}}
+	if err := tx.AutoMigrate(&DateMigrationIssue{}); err != nil {
+		return result, err
+	}
+
+	for _, model := range []interface{}{&Exercise{}, &Meal{}, &Weight{}, &Workout{}} {
+		legacy, table, err := hasLegacyDateColumn(tx, model)
+		if err != nil {
+			return result, err
+		}
+		if !legacy {
+			continue
+		}
+
+		type row struct {
+			ID   int
+			Date sql.NullString
+		}
+		var rows []row
+		if err := tx.Table(table).Select("id, date").Where("date IS NOT NULL").Find(&rows).Error; err != nil {
+			return result, err
+		}
+
+		err = tx.Transaction(func(tx *gorm.DB) error {
+			for _, r := range rows {
+				date, ok := parseLegacyDate(r.Date.String)
+				var value interface{} = string(date)
+				if !ok {
+					value = nil
+					issue := DateMigrationIssue{TableName: table, RowID: r.ID, Value: r.Date.String}
+					if err := tx.Create(&issue).Error; err != nil {
+						return err
+					}
+					result.Issues = append(result.Issues, issue)
+				} else if string(date) != r.Date.String {
+					result.Converted++
+				}
+				if err := tx.Table(table).Where("id = ?", r.ID).Update("date", value).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// hasLegacyDateColumn reports whether the model's table has a date column
+// that is not yet a DATE
+func hasLegacyDateColumn(tx *gorm.DB, model interface{}) (bool, string, error) {
+	stmt := &gorm.Statement{DB: tx}
+	if err := stmt.Parse(model); err != nil {
+		return false, "", err
+	}
+	table := stmt.Schema.Table
+	if !tx.Migrator().HasTable(table) {
+		return false, table, nil
+	}
+
+	columns, err := tx.Migrator().ColumnTypes(table)
+	if err != nil {
+		return false, table, err
+	}
+	for _, column := range columns {
+		if column.Name() == "date" {
+			return !strings.EqualFold(column.DatabaseTypeName(), "date"), table, nil
+		}
+	}
+	return false, table, nil
+}
+
+// logDateMigration reports the outcome of a legacy date migration
+func logDateMigration(result DateMigrationResult) {
+	for _, issue := range result.Issues {
+		log.Printf("Could not convert date %q of %s row %d; cleared", issue.Value, issue.TableName, issue.RowID)
+	}
+	if result.Converted > 0 || len(result.Issues) > 0 {
+		log.Printf("Migrated dates: %d converted, %d could not be converted", result.Converted, len(result.Issues))
+	}
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestCreateWeightEntry_InvalidDate(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	for value, message := range map[string]string{
+		"10/1/23":    "date must be an ISO-8601 calendar date (YYYY-MM-DD)",
+		"2023-02-30": "date must be an ISO-8601 calendar date (YYYY-MM-DD)",
+		"":           "date is required",
+	} {
+		w := postJSON(r, "/weights", gin.H{"date": value, "weight": 80}, "")
+		assert.Equal(t, http.StatusBadRequest, w.Code, value)
+
+		var response map[string]interface{}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		assert.Equal(t, "date", response["field"])
+		assert.Equal(t, message, response["message"])
+	}
+
+	var count int64
+	db.Model(&Weight{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
+
+func TestCreateMeal_DateFromOccurredAt(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	db.Model(&User{}).Where("id = ?", testUserID).Update("time_zone", "America/New_York")
+
+	// Late evening in New York is already the next day in UTC
+	w := postJSON(r, "/meals", gin.H{"name": "Dinner", "occurred_at": "2023-10-01T22:30:00-04:00", "calories": 0}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var meal Meal
+	json.Unmarshal(w.Body.Bytes(), &meal)
+	assert.Equal(t, Date("2023-10-01"), meal.Date)
+
+	db.First(&meal, meal.ID)
+	assert.Equal(t, Date("2023-10-01"), meal.Date)
+	if assert.NotNil(t, meal.OccurredAt) {
+		assert.Equal(t, "2023-10-02T02:30:00Z", meal.OccurredAt.UTC().Format(time.RFC3339))
+	}
+}
+
+func TestUpdateExercise_DateMismatch(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	exercise := Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", Sets: 3, Reps: 5, Weight: 100}
+	db.Create(&exercise)
+
+	body := gin.H{"date": "2023-10-02", "occurred_at": "2023-10-01T07:00:00Z", "movement": "Squat", "sets": 3, "reps": 5, "weight": 100}
+	w := sendJSON(r, "PUT", fmt.Sprintf("/exercises/%d", exercise.ID), body, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var response map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &response)
+	assert.Equal(t, "date does not match the day of occurred_at in the user's time zone", response["message"])
+}
+
+func TestGetExercises_DateRangeOnDateColumn(t *testing.T) {
+	db = setupTestDB()
+
+	for _, date := range []Date{"2023-09-30", "2023-10-01", "2023-10-15", "2023-11-01"} {
+		db.Create(&Exercise{UserID: testUserID, Date: date, Movement: "Squat", Sets: 3, Reps: 5, Weight: 100})
+	}
+
+	var exercises []Exercise
+	getList(t, "/exercises?from=2023-10-01&to=2023-10-31&sort=date", &exercises)
+	if assert.Len(t, exercises, 2) {
+		assert.Equal(t, Date("2023-10-01"), exercises[0].Date)
+		assert.Equal(t, Date("2023-10-15"), exercises[1].Date)
+	}
+}
+
+func TestMigrateLegacyDates(t *testing.T) {
+	legacy, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	assert.NoError(t, err)
+
+	// The weights table as it was when dates were free-form strings
+	legacy.Exec("CREATE TABLE weights (id integer PRIMARY KEY, created_at datetime, updated_at datetime, deleted_at datetime, created_by integer, updated_by integer, user_id integer, date text, weight real)")
+	for i, value := range []string{"2023-10-01", "10/2/23", "10/03/2023", "Oct 4, 2023", "", "someday"} {
+		legacy.Exec("INSERT INTO weights (id, user_id, date, weight) VALUES (?, 1, ?, 80)", i+1, value)
+	}
+
+	result, err := migrateLegacyDates(legacy)
+	assert.NoError(t, err)
+	assert.NoError(t, legacy.AutoMigrate(&Weight{}))
+	assert.Equal(t, 3, result.Converted)
+	assert.Len(t, result.Issues, 2)
+
+	var weights []Weight
+	legacy.Order("id ASC").Find(&weights)
+	var dates []Date
+	for _, weight := range weights {
+		dates = append(dates, weight.Date)
+	}
+	assert.Equal(t, []Date{"2023-10-01", "2023-10-02", "2023-10-03", "2023-10-04", "", ""}, dates)
+
+	var issues []DateMigrationIssue
+	legacy.Order("row_id ASC").Find(&issues)
+	if assert.Len(t, issues, 2) {
+		assert.Equal(t, "weights", issues[0].TableName)
+		assert.Equal(t, 5, issues[0].RowID)
+		assert.Equal(t, "someday", issues[1].Value)
+	}
+
+	// Once the column is a DATE the migration leaves it alone
+	result, err = migrateLegacyDates(legacy)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Converted)
+	assert.Empty(t, result.Issues)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Limits for the TDEE estimation window in days
+const (
+	defaultTDEEWindow = 28
+	minTDEEWindow     = 7
+	maxTDEEWindow     = 365
+)
+
+// Confidence levels reported with a TDEE estimate
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// TDEEEstimate is the maintenance calorie estimate for a window of days,
+// derived from average intake and the change in trend weight over the window
+type TDEEEstimate struct {
+	From                string  `json:"from"`
+	To                  string  `json:"to"`
+	Window              int     `json:"window"`
+	AverageIntake       float64 `json:"average_intake"`
+	StartTrendWeight    float64 `json:"start_trend_weight"`
+	EndTrendWeight      float64 `json:"end_trend_weight"`
+	WeeklyWeightChange  float64 `json:"weekly_weight_change"`
+	EnergyDensity       float64 `json:"energy_density"`
+	TDEE                float64 `json:"tdee"`
+	IntakeDays          int     `json:"intake_days"`
+	WeighInDays         int     `json:"weigh_in_days"`
+	Completeness        float64 `json:"completeness"`
+	Confidence          string  `json:"confidence"`
+	GoalRate            float64 `json:"goal_rate"`
+	RecommendedCalories float64 `json:"recommended_calories"`
+	Unit                string  `json:"unit"`
+}
+
+// errNotEnoughData is returned when the window lacks the intake or weight
+// data needed for an estimate
+type errNotEnoughData string
+
+func (e errNotEnoughData) Error() string { return string(e) }
+
+// confidenceFor maps the share of the window with logged data to a level
+func confidenceFor(completeness float64) string {
+	switch {
+	case completeness >= 0.8:
+		return ConfidenceHigh
+	case completeness >= 0.5:
+		return ConfidenceMedium
+	default:
+		return ConfidenceLow
+	}
+}
+
+// estimateTDEE estimates the user's maintenance calories over the window days
+// ending on to. Days without any logged meals are treated as unlogged rather than as
+// fasting, so the intake average only covers logged days.
+func estimateTDEE(userID int, to time.Time, window int, density, rate float64) (*TDEEEstimate, error) {
+	estimate := &TDEEEstimate{
+		From:          to.AddDate(0, 0, 1-window).Format(dateLayout),
+		To:            to.Format(dateLayout),
+		Window:        window,
+		EnergyDensity: density,
+		GoalRate:      rate,
+	}
+
+	var intake []int
+	err := db.Model(&Meal{}).Where("user_id = ?", userID).
+		Where("date >= ? AND date <= ?", estimate.From, estimate.To).
+		Group("date").Pluck("SUM(calories)", &intake).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(intake) == 0 {
+		return nil, errNotEnoughData("No meals logged in window")
+	}
+	var total int
+	for _, calories := range intake {
+		total += calories
+	}
+	estimate.IntakeDays = len(intake)
+	estimate.AverageIntake = float64(total) / float64(len(intake))
+
+	points, err := buildWeightTrend(userID, defaultTrendAlpha, estimate.From, estimate.To)
+	if err != nil {
+		return nil, err
+	}
+	for _, point := range points {
+		if !point.Interpolated {
+			estimate.WeighInDays++
+		}
+	}
+	if len(points) < minTDEEWindow {
+		return nil, errNotEnoughData("Weight trend covers too little of the window")
+	}
+
+	first, last := points[0], points[len(points)-1]
+	days := float64(len(points) - 1)
+	dailyChange := (last.Trend - first.Trend) / days
+	estimate.StartTrendWeight = first.Trend
+	estimate.EndTrendWeight = last.Trend
+	estimate.WeeklyWeightChange = roundTo(dailyChange*7, 2)
+
+	// Energy balance: intake minus the energy stored in the weight change
+	estimate.TDEE = math.Round(estimate.AverageIntake - dailyChange*density)
+	estimate.RecommendedCalories = math.Round(estimate.TDEE + rate*density/7)
+	estimate.AverageIntake = roundTo(estimate.AverageIntake, 1)
+
+	completeness := (float64(estimate.IntakeDays) + float64(estimate.WeighInDays)) / float64(2*window)
+	estimate.Completeness = roundTo(completeness, 2)
+	estimate.Confidence = confidenceFor(completeness)
+	return estimate, nil
+}
+
+// getTDEE handles GET /energy/tdee
+func getTDEE(c *gin.Context) {
+	window, err := strconv.Atoi(c.DefaultQuery("window", strconv.Itoa(defaultTDEEWindow)))
+	if err != nil || window < minTDEEWindow || window > maxTDEEWindow {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window"})
+		return
+	}
+
+	to := today(currentLocation(c))
+	if value := c.Query("to"); value != "" {
+		if to, err = time.Parse(dateLayout, value); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+			return
+		}
+	}
+
+	// goal_rate is in the user's display unit per week; GOAL_RATE is in kilograms
+	rate := goalRate()
+	if value := c.Query("goal_rate"); value != "" {
+		if rate, err = strconv.ParseFloat(value, 64); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid goal_rate"})
+			return
+		}
+		rate = massConverter{From: displayUnit(c), To: UnitKg}.mass(rate)
+	}
+
+	estimate, err := estimateTDEE(currentUserID(c), to, window, energyDensity(), rate)
+	switch err.(type) {
+	case nil:
+		c.JSON(http.StatusOK, inDisplayUnit(c, estimate))
+	case errNotEnoughData:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		log.Println("DB Query Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to estimate TDEE"})
+	}
+}
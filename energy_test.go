@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetTDEE_Success(t *testing.T) {
+	db = setupTestDB()
+
+	// 2500 kcal a day while losing 0.05 kg a day (0.35 kg a week)
+	for i := 0; i < 28; i++ {
+		date := Date(fmt.Sprintf("2023-10-%02d", i+1))
+		db.Create(&Meal{UserID: testUserID, Name: "Lunch", Date: date, Calories: 2500})
+		db.Create(&Weight{UserID: testUserID, Date: date, Weight: 80 - 0.05*float64(i)})
+	}
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/energy/tdee?window=14&to=2023-10-28&goal_rate=-0.5", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var estimate TDEEEstimate
+	err := json.Unmarshal(w.Body.Bytes(), &estimate)
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-10-15", estimate.From)
+	assert.Equal(t, 2500.0, estimate.AverageIntake)
+	assert.Equal(t, 14, estimate.IntakeDays)
+	assert.Equal(t, 14, estimate.WeighInDays)
+	assert.Equal(t, ConfidenceHigh, estimate.Confidence)
+
+	// The trend lags the scale but settles on the same rate of loss
+	assert.InDelta(t, -0.35, estimate.WeeklyWeightChange, 0.05)
+	assert.InDelta(t, 2500+0.05*7700, estimate.TDEE, 60)
+	assert.Equal(t, estimate.TDEE-550, estimate.RecommendedCalories)
+}
+
+func TestGetTDEE_LowConfidence(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Meal{UserID: testUserID, Name: "Dinner", Date: "2023-10-10", Calories: 2000})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 80})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-28", Weight: 80})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/energy/tdee?to=2023-10-28", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var estimate TDEEEstimate
+	err := json.Unmarshal(w.Body.Bytes(), &estimate)
+	assert.NoError(t, err)
+	assert.Equal(t, 2000.0, estimate.TDEE)
+	assert.Equal(t, ConfidenceLow, estimate.Confidence)
+}
+
+func TestGetTDEE_NotEnoughData(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 80})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/energy/tdee?to=2023-10-28", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
+
+func TestGetTDEE_InvalidWindow(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/energy/tdee?window=3", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -1,25 +1,179 @@
 package main
 
 import (
+	"encoding/json"
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// validSetTypes lists the set types accepted on an ExerciseSet
+var validSetTypes = map[string]bool{
+	SetTypeWarmup:  true,
+	SetTypeWorking: true,
+	SetTypeDrop:    true,
+	SetTypeFailure: true,
+}
+
+// preloadSets loads an exercise's sets in the order they were performed
+func preloadSets(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("SetDetails", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("set_number ASC")
+	})
+}
+
+// flatSets returns the identical working sets described by an exercise's
+// flat Sets, Reps and Weight, for exercises logged without per-set detail
+func flatSets(exercise *Exercise) []ExerciseSet {
+	var sets []ExerciseSet
+	for i := 0; i < exercise.Sets; i++ {
+		sets = append(sets, ExerciseSet{ExerciseID: exercise.ID, SetNumber: i + 1, Reps: exercise.Reps, Load: exercise.Weight, SetType: SetTypeWorking})
+	}
+	return sets
+}
+
+// prepareExerciseSets expands the flat Sets/Reps/Weight shorthand into
+// identical sets when no per-set detail was sent, syncs the flat fields back
+// from the sets and reports whether every set is valid
+func prepareExerciseSets(exercise *Exercise) bool {
+	if len(exercise.SetDetails) == 0 {
+		for i := 0; i < exercise.Sets; i++ {
+			exercise.SetDetails = append(exercise.SetDetails, ExerciseSet{
+				Reps: exercise.Reps,
+				Load: exercise.Weight,
+			})
+		}
+	}
+	if len(exercise.SetDetails) == 0 {
+		return false
+	}
+
+	top := -1
+	for i := range exercise.SetDetails {
+		set := &exercise.SetDetails[i]
+		// Sets are always rewritten as new rows for their exercise
+		set.Model = gorm.Model{}
+		set.ID = 0
+		set.ExerciseID = exercise.ID
+		if set.SetNumber == 0 {
+			set.SetNumber = i + 1
+		}
+		if set.SetType == "" {
+			set.SetType = SetTypeWorking
+		}
+
+		switch {
+		case set.Reps <= 0 || set.Load < 0 || !validSetTypes[set.SetType]:
+			return false
+		case set.RPE != nil && (*set.RPE < 0 || *set.RPE > 10):
+			return false
+		}
+
+		if top < 0 || isHeavierSet(*set, exercise.SetDetails[top]) {
+			top = i
+		}
+	}
+
+	// The flat fields summarise the top set
+	exercise.Sets = len(exercise.SetDetails)
+	exercise.Reps = exercise.SetDetails[top].Reps
+	exercise.Weight = exercise.SetDetails[top].Load
+	return true
+}
+
+// isHeavierSet reports whether a outranks b as the top set of an exercise,
+// preferring non-warmup sets, then load, then reps
+func isHeavierSet(a, b ExerciseSet) bool {
+	aWarmup, bWarmup := a.SetType == SetTypeWarmup, b.SetType == SetTypeWarmup
+	switch {
+	case aWarmup != bWarmup:
+		return bWarmup
+	case a.Load != b.Load:
+		return a.Load > b.Load
+	default:
+		return a.Reps > b.Reps
+	}
+}
+
+// saveExercise updates an exercise and replaces its sets in one transaction
+func saveExercise(exercise *Exercise) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("exercise_id = ?", exercise.ID).Delete(&ExerciseSet{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("SetDetails").Save(exercise).Error; err != nil {
+			return err
+		}
+		return tx.Create(&exercise.SetDetails).Error
+	})
+}
+
+// backfillExerciseSets gives exercises logged before sets were recorded one
+// by one a set row for each of their sets, copied from the flat sets, reps and
+// weight, so records and reports include them. Exercises that already have
+// sets are left alone, so running it again does nothing. It reports how many
+// exercises were given sets.
+func backfillExerciseSets(tx *gorm.DB) (int, error) {
+	withSets := tx.Model(&ExerciseSet{}).Select("exercise_id")
+	var batch []Exercise
+	count := 0
+	err := tx.Where("id NOT IN (?) AND sets > 0 AND reps > 0", withSets).FindInBatches(&batch, csvBatchSize, func(tx *gorm.DB, _ int) error {
+		var sets []ExerciseSet
+		for i := range batch {
+			sets = append(sets, flatSets(&batch[i])...)
+		}
+		count += len(batch)
+		return tx.CreateInBatches(&sets, csvBatchSize).Error
+	}).Error
+	return count, err
+}
+
+// validateExercise applies the rules every logged exercise must meet, with
+// dates read in the user's time zone loc. It returns the error response for
+// the first rule broken, or nil when the exercise is valid.
+func validateExercise(exercise *Exercise, loc *time.Location) gin.H {
+	switch {
+	case !normalizeDate(&exercise.Date, exercise.OccurredAt, loc):
+		return dateFieldError(exercise.Date, exercise.OccurredAt)
+	case !prepareExerciseSets(exercise):
+		return gin.H{"error": "Invalid input values"}
+	case !resolveExerciseMovement(exercise):
+		return gin.H{"error": "Unknown movement"}
+	}
+	return nil
+}
+
+// unknownWorkout returns the error response for an exercise placed in a
+// workout the user does not have, or nil when its workout is theirs
+func unknownWorkout(exercise *Exercise) gin.H {
+	if exercise.WorkoutID == nil {
+		return nil
+	}
+	var count int64
+	db.Model(&Workout{}).Where("id = ? AND user_id = ?", *exercise.WorkoutID, exercise.UserID).Count(&count)
+	if count == 0 {
+		return gin.H{"error": "Unknown workout", "field": "workout_id", "value": *exercise.WorkoutID}
+	}
+	return nil
+}
+
 // createExercise handles POST /exercises
 func createExercise(c *gin.Context) {
-	var exercise Exercise
+	exercise := Exercise{UserID: currentUserID(c)}
 
 	log.Println("Received request to create exercise")
 
 	switch {
-	case c.ShouldBindJSON(&exercise) != nil:
+	case bindAudited(c, &exercise.ID, &exercise.Audit, &exercise) != nil:
 		log.Println("JSON Bind Error")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
-	case exercise.Sets <= 0 || exercise.Reps <= 0 || exercise.Weight < 0:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+	case rejectInvalid(c, validateExercise(&exercise, currentLocation(c))):
+		return
+	case rejectInvalid(c, unknownWorkout(&exercise)):
 		return
 	case db.Create(&exercise).Error != nil:
 		log.Println("DB Insert Error")
@@ -27,16 +181,41 @@ func createExercise(c *gin.Context) {
 		return
 	default:
 		log.Printf("Parsed Data: %+v\n", exercise)
-		c.JSON(http.StatusCreated, exercise)
+		formula := c.DefaultQuery("formula", FormulaEpley)
+		if !validFormula(formula) {
+			formula = FormulaEpley
+		}
+		prs, err := detectPersonalRecords(&exercise, formula)
+		if err != nil {
+			log.Println("Personal record check failed:", err)
+		}
+		exercise.PersonalRecords = prs
+		c.JSON(http.StatusCreated, inDisplayUnit(c, &exercise))
 	}
 }
 
+// exerciseList is the filtering and sorting accepted by GET /exercises
+var exerciseList = listSpec{
+	DateColumn:  "date",
+	Sorts:       map[string]string{"date": "date", "created_at": "created_at", "movement": "movement", "weight": "weight"},
+	DefaultSort: "-created_at",
+	Filters:     map[string]string{"movement": "movement", "movement_id": "movement_id", "type": "type", "workout_id": "workout_id"},
+	Contains:    map[string]string{"movement": "movement"},
+}
+
 // getExercises handles GET /exercises
 func getExercises(c *gin.Context) {
 	var exercises []Exercise
-	switch err := db.Order("created_at DESC").Find(&exercises).Error; err {
+	err := paginate(c, userScope(c).Model(&Exercise{}), exerciseList, &exercises, preloadSets)
+	switch err.(type) {
 	case nil:
+		display := displayMasses(c)
+		for i := range exercises {
+			exercises[i].convertMass(display)
+		}
 		c.JSON(http.StatusOK, exercises)
+	case listParamError:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch exercises"})
 	}
@@ -46,31 +225,59 @@ func getExercises(c *gin.Context) {
 func getExercise(c *gin.Context) {
 	id := c.Param("id")
 	var exercise Exercise
-	switch err := db.First(&exercise, id).Error; err {
+	switch err := preloadSets(userScope(c)).First(&exercise, id).Error; err {
 	case nil:
-		c.JSON(http.StatusOK, exercise)
+		c.JSON(http.StatusOK, inDisplayUnit(c, &exercise))
 	default:
 		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
 	}
 }
 
+// bindExerciseUpdate binds an update onto a stored exercise and its sets.
+// Sets sent in the body replace the stored ones; flat sets, reps or weight
+// sent without them rebuild the sets, and otherwise the sets are kept.
+func bindExerciseUpdate(c *gin.Context, exercise *Exercise) error {
+	if err := bindAudited(c, &exercise.ID, &exercise.Audit, exercise); err != nil {
+		return err
+	}
+
+	var fields map[string]json.RawMessage
+	body, _ := c.Get(gin.BodyBytesKey)
+	if raw, ok := body.([]byte); !ok || json.Unmarshal(raw, &fields) != nil {
+		return nil
+	}
+	if isJSONArray(fields["sets"]) || isJSONArray(fields["set_details"]) {
+		return nil
+	}
+	for _, field := range []string{"sets", "reps", "weight"} {
+		if _, ok := fields[field]; ok {
+			exercise.SetDetails = nil
+		}
+	}
+	return nil
+}
+
 // updateExercise handles PUT /exercises/:id
 func updateExercise(c *gin.Context) {
 	id := c.Param("id")
 	var exercise Exercise
 	
 	switch {
-	case db.First(&exercise, id).Error != nil:
+	case preloadSets(userScope(c)).First(&exercise, id).Error != nil:
 		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
 		return
-	case c.ShouldBindJSON(&exercise) != nil:
+	case bindExerciseUpdate(c, &exercise) != nil:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
-	case db.Save(&exercise).Error != nil:
+	case rejectInvalid(c, validateExercise(&exercise, currentLocation(c))):
+		return
+	case rejectInvalid(c, unknownWorkout(&exercise)):
+		return
+	case saveExercise(&exercise) != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update exercise"})
 		return
 	default:
-		c.JSON(http.StatusOK, exercise)
+		c.JSON(http.StatusOK, inDisplayUnit(c, &exercise))
 	}
 }
 
@@ -78,7 +285,19 @@ func updateExercise(c *gin.Context) {
 func deleteExercise(c *gin.Context) {
 	log.Println("Received request to delete exercise")
 	id := c.Param("id")
-	switch err := db.Delete(&Exercise{}, id).Error; err {
+	var exercise Exercise
+	if err := userScope(c).First(&exercise, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("exercise_id = ?", exercise.ID).Delete(&ExerciseSet{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&exercise).Error
+	})
+	switch err {
 	case nil:
 		c.JSON(http.StatusOK, gin.H{"message": "Exercise deleted successfully"})
 	default:
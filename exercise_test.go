@@ -70,6 +70,7 @@ func TestGetExercises_Success(t *testing.T) {
 
 	// Add test data
 	exercise := Exercise{
+		UserID:   testUserID,
 		Date:     "2023-10-01",
 		Movement: "Push-ups",
 		Reps:     10,
@@ -99,6 +100,7 @@ func TestUpdateExercise_Success(t *testing.T) {
 
 	// Create a test exercise
 	exercise := Exercise{
+		UserID:   testUserID,
 		Date:     "2023-10-01",
 		Movement: "Push-ups",
 		Reps:     10,
@@ -159,6 +161,7 @@ func TestDeleteExercise_Success(t *testing.T) {
 
 	// Create a test exercise
 	exercise := Exercise{
+		UserID:   testUserID,
 		Date:     "2023-10-01",
 		Movement: "Push-ups",
 		Reps:     10,
@@ -180,3 +183,278 @@ func TestDeleteExercise_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Exercise deleted successfully", response["message"])
 }
+
+func TestCreateExercise_PerSetDetail(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{
+		"date": "2023-10-01",
+		"movement": "Bench Press",
+		"type": "Barbell",
+		"sets": [
+			{"reps": 10, "load": 135, "set_type": "warmup"},
+			{"reps": 8, "load": 185, "rpe": 8},
+			{"reps": 5, "load": 225, "rpe": 9.5}
+		]
+	}`)
+	req, _ := http.NewRequest("POST", "/exercises", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var m Exercise
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, m.Sets)
+	assert.Equal(t, 5, m.Reps)
+	assert.Equal(t, 225.0, m.Weight)
+	assert.Len(t, m.SetDetails, 3)
+	assert.Equal(t, SetTypeWarmup, m.SetDetails[0].SetType)
+	assert.Equal(t, SetTypeWorking, m.SetDetails[1].SetType)
+	assert.Equal(t, 3, m.SetDetails[2].SetNumber)
+	assert.Equal(t, 9.5, *m.SetDetails[2].RPE)
+}
+
+func TestCreateExercise_FlatShorthandExpandsSets(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(Exercise{
+		Date:     "2023-10-01",
+		Movement: "Squat",
+		Reps:     5,
+		Sets:     3,
+		Weight:   225,
+		Type:     "Barbell",
+	})
+	req, _ := http.NewRequest("POST", "/exercises", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var sets []ExerciseSet
+	db.Order("set_number ASC").Find(&sets)
+	assert.Len(t, sets, 3)
+	for i, set := range sets {
+		assert.Equal(t, i+1, set.SetNumber)
+		assert.Equal(t, 5, set.Reps)
+		assert.Equal(t, 225.0, set.Load)
+	}
+}
+
+func TestCreateExercise_InvalidSetType(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"date": "2023-10-01", "movement": "Squat", "sets": [{"reps": 5, "load": 225, "set_type": "cluster"}]}`)
+	req, _ := http.NewRequest("POST", "/exercises", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateExercise_ReplacesSets(t *testing.T) {
+	db = setupTestDB()
+
+	exercise := Exercise{
+		UserID:     testUserID,
+		Date:       "2023-10-01",
+		Movement:   "Deadlift",
+		Sets:       2,
+		Reps:       5,
+		Weight:     315,
+		SetDetails: []ExerciseSet{{SetNumber: 1, Reps: 5, Load: 315}, {SetNumber: 2, Reps: 5, Load: 315}},
+	}
+	db.Create(&exercise)
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"sets": [{"reps": 3, "load": 365}]}`)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/exercises/%d", exercise.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated Exercise
+	preloadSets(db).First(&updated, exercise.ID)
+	assert.Equal(t, "Deadlift", updated.Movement)
+	assert.Equal(t, 1, updated.Sets)
+	assert.Equal(t, 365.0, updated.Weight)
+	assert.Len(t, updated.SetDetails, 1)
+}
+
+func TestUpdateExercise_FlatFieldsRebuildSets(t *testing.T) {
+	db = setupTestDB()
+
+	exercise := Exercise{
+		UserID:     testUserID,
+		Date:       "2023-10-01",
+		Movement:   "Squat",
+		Sets:       3,
+		Reps:       5,
+		Weight:     100,
+		SetDetails: []ExerciseSet{{SetNumber: 1, Reps: 5, Load: 100}, {SetNumber: 2, Reps: 5, Load: 100}, {SetNumber: 3, Reps: 5, Load: 100}},
+	}
+	db.Create(&exercise)
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"weight": 110, "reps": 4}`)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/exercises/%d", exercise.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated Exercise
+	preloadSets(db).First(&updated, exercise.ID)
+	assert.Equal(t, 110.0, updated.Weight)
+	assert.Equal(t, 4, updated.Reps)
+	assert.Len(t, updated.SetDetails, 3)
+	assert.Equal(t, 110.0, updated.SetDetails[2].Load)
+	assert.Equal(t, 4, updated.SetDetails[2].Reps)
+}
+
+func TestUpdateExercise_PartialUpdateKeepsSets(t *testing.T) {
+	db = setupTestDB()
+
+	exercise := Exercise{
+		UserID:   testUserID,
+		Date:     "2023-10-01",
+		Movement: "Squat",
+		SetDetails: []ExerciseSet{
+			{SetNumber: 1, Reps: 8, Load: 100, SetType: SetTypeWorking},
+			{SetNumber: 2, Reps: 5, Load: 120, SetType: SetTypeWorking},
+			{SetNumber: 3, Reps: 10, Load: 80, SetType: SetTypeDrop},
+		},
+	}
+	db.Create(&exercise)
+
+	r := setupRouter()
+
+	for _, body := range []string{`{"date": "2023-10-02"}`, `{"movement": "Front Squat"}`} {
+		req, _ := http.NewRequest("PUT", fmt.Sprintf("/exercises/%d", exercise.ID), bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+
+	var updated Exercise
+	preloadSets(db).First(&updated, exercise.ID)
+	assert.Equal(t, Date("2023-10-02"), updated.Date)
+	assert.Equal(t, "Front Squat", updated.Movement)
+	if assert.Len(t, updated.SetDetails, 3) {
+		assert.Equal(t, 100.0, updated.SetDetails[0].Load)
+		assert.Equal(t, 120.0, updated.SetDetails[1].Load)
+		assert.Equal(t, 80.0, updated.SetDetails[2].Load)
+		assert.Equal(t, SetTypeDrop, updated.SetDetails[2].SetType)
+	}
+}
+
+func TestBackfillExerciseSets(t *testing.T) {
+	db = setupTestDB()
+
+	legacy := Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", Sets: 3, Reps: 5, Weight: 100}
+	db.Create(&legacy)
+	logged := Exercise{UserID: testUserID, Date: "2023-10-02", Movement: "Squat", Sets: 1, Reps: 3, Weight: 120, SetDetails: []ExerciseSet{{SetNumber: 1, Reps: 3, Load: 120}}}
+	db.Create(&logged)
+
+	count, err := backfillExerciseSets(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	var exercise Exercise
+	preloadSets(db).First(&exercise, legacy.ID)
+	assert.Len(t, exercise.SetDetails, 3)
+	assert.Equal(t, 100.0, exercise.SetDetails[2].Load)
+	assert.Equal(t, SetTypeWorking, exercise.SetDetails[2].SetType)
+
+	count, err = backfillExerciseSets(db)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, count)
+
+	r := setupRouter()
+	req, _ := http.NewRequest("GET", "/movements/Squat/records", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+}
+
+func TestGetExercises_PreloadsSets(t *testing.T) {
+	db = setupTestDB()
+
+	exercise := Exercise{
+		UserID:     testUserID,
+		Date:       "2023-10-01",
+		Movement:   "Bench Press",
+		Sets:       2,
+		Reps:       8,
+		Weight:     185,
+		SetDetails: []ExerciseSet{{SetNumber: 2, Reps: 6, Load: 185}, {SetNumber: 1, Reps: 8, Load: 185}},
+	}
+	db.Create(&exercise)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/exercises", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var exercises []Exercise
+	err := json.Unmarshal(w.Body.Bytes(), &exercises)
+	assert.NoError(t, err)
+	assert.Len(t, exercises, 1)
+	assert.Len(t, exercises[0].SetDetails, 2)
+	assert.Equal(t, 1, exercises[0].SetDetails[0].SetNumber)
+}
+
+func TestCreateExercise_RejectsOtherUsersWorkout(t *testing.T) {
+	db = setupTestDB()
+	other := User{Email: "other@example.com"}
+	db.Create(&other)
+	theirs := Workout{UserID: other.ID, Date: "2023-10-01", Name: "Legs"}
+	ours := Workout{UserID: testUserID, Date: "2023-10-01", Name: "Legs"}
+	db.Create(&theirs)
+	db.Create(&ours)
+
+	r := setupRouter()
+
+	w := sendJSON(r, "POST", "/exercises", map[string]interface{}{"date": "2023-10-01", "movement": "Squat", "sets": 3, "reps": 5, "weight": 100, "workout_id": theirs.ID}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Unknown workout")
+
+	w = sendJSON(r, "POST", "/exercises", map[string]interface{}{"date": "2023-10-01", "movement": "Squat", "sets": 3, "reps": 5, "weight": 100, "workout_id": ours.ID}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var exercise Exercise
+	json.Unmarshal(w.Body.Bytes(), &exercise)
+
+	w = sendJSON(r, "PUT", fmt.Sprintf("/exercises/%d", exercise.ID), map[string]interface{}{"workout_id": theirs.ID}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var count int64
+	db.Model(&Exercise{}).Where("workout_id = ?", theirs.ID).Count(&count)
+	assert.Equal(t, int64(0), count)
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Page sizes for food searches
+const (
+	defaultFoodLimit = 50
+	maxFoodLimit     = 500
+)
+
+// prepareFood tidies a food's name and servings and reports whether all of
+// its nutrition values are valid
+func prepareFood(food *Food) bool {
+	food.Name = strings.TrimSpace(food.Name)
+	food.Brand = strings.TrimSpace(food.Brand)
+
+	switch {
+	case food.Name == "":
+		return false
+	case food.Carbs < 0 || food.Protein < 0 || food.Fats < 0 || food.Calories < 0:
+		return false
+	case food.Fiber < 0 || food.Sugar < 0 || food.Sodium < 0:
+		return false
+	}
+
+	// Servings are always rewritten as new rows for their food
+	for i := range food.Servings {
+		serving := &food.Servings[i]
+		serving.Model = gorm.Model{}
+		serving.ID = 0
+		serving.FoodID = food.ID
+		serving.Name = strings.TrimSpace(serving.Name)
+		if serving.Name == "" || serving.Grams <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// saveFood updates a food and replaces its servings in one transaction
+func saveFood(tx *gorm.DB, food *Food) error {
+	return tx.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("food_id = ?", food.ID).Delete(&FoodServing{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Servings").Save(food).Error; err != nil {
+			return err
+		}
+		if len(food.Servings) > 0 {
+			return tx.Create(&food.Servings).Error
+		}
+		return nil
+	})
+}
+
+// prepareMealItems computes each item's nutrition from its food or recipe
+// and sets the meal's totals from the items. It reports false when an item
+// is invalid or refers to an unknown food or recipe.
+func prepareMealItems(meal *Meal) bool {
+	if len(meal.Items) == 0 {
+		return true
+	}
+
+	var carbs, protein, fats, calories float64
+	for i := range meal.Items {
+		item := &meal.Items[i]
+		item.Model = gorm.Model{}
+		item.ID = 0
+		item.MealID = meal.ID
+
+		var ok bool
+		switch {
+		case item.FoodID != nil && item.RecipeID == nil:
+			ok = applyFoodItem(item)
+		case item.RecipeID != nil && item.FoodID == nil:
+			ok = applyRecipeItem(item, meal.UserID)
+		}
+		if !ok {
+			return false
+		}
+
+		carbs += item.Carbs
+		protein += item.Protein
+		fats += item.Fats
+		calories += item.Calories
+	}
+
+	meal.Carbs = int(math.Round(carbs))
+	meal.Protein = int(math.Round(protein))
+	meal.Fats = int(math.Round(fats))
+	meal.Calories = int(math.Round(calories))
+	return true
+}
+
+// applyFoodItem computes a food item's nutrition, either from grams or from a
+// serving and quantity
+func applyFoodItem(item *MealItem) bool {
+	var food Food
+	if db.Preload("Servings").Where("id = ?", *item.FoodID).Limit(1).Find(&food).RowsAffected == 0 {
+		return false
+	}
+
+	if item.ServingID != nil {
+		serving := findServing(&food, *item.ServingID)
+		if serving == nil || item.Quantity < 0 {
+			return false
+		}
+		if item.Quantity == 0 {
+			item.Quantity = 1
+		}
+		item.Grams = serving.Grams * item.Quantity
+	}
+	if item.Grams <= 0 {
+		return false
+	}
+
+	scale := item.Grams / 100
+	item.Carbs = roundTo(food.Carbs*scale, 1)
+	item.Protein = roundTo(food.Protein*scale, 1)
+	item.Fats = roundTo(food.Fats*scale, 1)
+	item.Calories = roundTo(food.Calories*scale, 1)
+	return true
+}
+
+// findServing returns the food's serving with the given ID, or nil
+func findServing(food *Food, id int) *FoodServing {
+	for i := range food.Servings {
+		if food.Servings[i].ID == id {
+			return &food.Servings[i]
+		}
+	}
+	return nil
+}
+
+// createFood handles POST /foods
+func createFood(c *gin.Context) {
+	var food Food
+
+	switch {
+	case c.ShouldBindJSON(&food) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !prepareFood(&food):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case db.Create(&food).Error != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create food"})
+		return
+	default:
+		c.JSON(http.StatusCreated, food)
+	}
+}
+
+// getFoods handles GET /foods, optionally searching name and brand with ?q=
+func getFoods(c *gin.Context) {
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(defaultFoodLimit)))
+	if err != nil || limit <= 0 || limit > maxFoodLimit {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid limit"})
+		return
+	}
+
+	query := db.Preload("Servings").Order("name ASC").Limit(limit)
+	if q := strings.ToLower(strings.TrimSpace(c.Query("q"))); q != "" {
+		pattern := "%" + q + "%"
+		query = query.Where("LOWER(name) LIKE ? OR LOWER(brand) LIKE ?", pattern, pattern)
+	}
+
+	var foods []Food
+	switch err := query.Find(&foods).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, foods)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch foods"})
+	}
+}
+
+// getFood handles GET /foods/:id
+func getFood(c *gin.Context) {
+	id := c.Param("id")
+	var food Food
+	switch err := db.Preload("Servings").First(&food, id).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, food)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Food not found"})
+	}
+}
+
+// updateFood handles PUT /foods/:id
+func updateFood(c *gin.Context) {
+	id := c.Param("id")
+	var food Food
+
+	switch {
+	case db.Preload("Servings").First(&food, id).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Food not found"})
+		return
+	case bindUpdate(c, &food.ID, &food) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !prepareFood(&food):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case saveFood(db, &food) != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update food"})
+		return
+	default:
+		c.JSON(http.StatusOK, food)
+	}
+}
+
+// deleteFood handles DELETE /foods/:id. Meals logged with the food keep the
+// nutrition computed when they were logged.
+func deleteFood(c *gin.Context) {
+	id := c.Param("id")
+
+	result := db.Where("id = ?", id).Delete(&Food{})
+	switch {
+	case result.Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete food"})
+	case result.RowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Food not found"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Food deleted successfully"})
+	}
+}
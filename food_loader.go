@@ -0,0 +1,205 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"gorm.io/gorm"
+)
+
+// foodColumns maps the normalized header names accepted by the food loader,
+// including those of a flattened USDA FoodData Central export, to the Food
+// field they fill
+var foodColumns = map[string]string{
+	"fdc id":                        "external_id",
+	"external id":                   "external_id",
+	"id":                            "external_id",
+	"name":                          "name",
+	"description":                   "name",
+	"brand":                         "brand",
+	"brand owner":                   "brand",
+	"brand name":                    "brand",
+	"calories":                      "calories",
+	"energy":                        "calories",
+	"energy kcal":                   "calories",
+	"protein":                       "protein",
+	"protein g":                     "protein",
+	"carbs":                         "carbs",
+	"carbohydrate":                  "carbs",
+	"carbohydrates":                 "carbs",
+	"carbohydrate by difference":    "carbs",
+	"carbohydrate by difference g":  "carbs",
+	"fat":                           "fat",
+	"fats":                          "fat",
+	"total fat":                     "fat",
+	"total lipid fat":               "fat",
+	"total lipid fat g":             "fat",
+	"fiber":                         "fiber",
+	"fiber total dietary":           "fiber",
+	"fiber total dietary g":         "fiber",
+	"sugar":                         "sugar",
+	"sugars":                        "sugar",
+	"sugars total":                  "sugar",
+	"sugars total including nlea":   "sugar",
+	"sugars total including nlea g": "sugar",
+	"sodium":                        "sodium",
+	"sodium mg":                     "sodium",
+	"sodium na":                     "sodium",
+	"sodium na mg":                  "sodium",
+	"serving size":                  "serving_grams",
+	"serving grams":                 "serving_grams",
+	"serving name":                  "serving_name",
+	"household serving fulltext":    "serving_name",
+}
+
+// csvHeaderKey normalizes a CSV header so "Total lipid (fat)" and
+// "total_lipid_fat" compare equal
+func csvHeaderKey(header string) string {
+	fields := strings.FieldsFunc(strings.ToLower(header), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// FoodLoadResult summarises a bulk food load
+type FoodLoadResult struct {
+	Created int      `json:"created"`
+	Updated int      `json:"updated"`
+	Skipped []string `json:"skipped"`
+}
+
+// loadFoodsCSV reads foods with per-100g nutrition from CSV and upserts them
+// in one transaction. Rows match existing foods on external ID when present
+// and on name and brand otherwise; rows that cannot be parsed are skipped
+// and reported by line number.
+func loadFoodsCSV(tx *gorm.DB, r io.Reader) (FoodLoadResult, error) {
+	result := FoodLoadResult{Skipped: []string{}}
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err != nil {
+		return result, fmt.Errorf("reading header: %w", err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		if field, ok := foodColumns[csvHeaderKey(name)]; ok {
+			if _, seen := columns[field]; !seen {
+				columns[field] = i
+			}
+		}
+	}
+	if _, ok := columns["name"]; !ok {
+		return result, fmt.Errorf("CSV has no name or description column")
+	}
+
+	err = tx.Transaction(func(tx *gorm.DB) error {
+		for line := 2; ; line++ {
+			record, err := reader.Read()
+			switch {
+			case err == io.EOF:
+				return nil
+			case err != nil:
+				return fmt.Errorf("line %d: %w", line, err)
+			}
+
+			food, err := parseFoodRecord(record, columns)
+			if err != nil {
+				result.Skipped = append(result.Skipped, fmt.Sprintf("line %d: %v", line, err))
+				continue
+			}
+
+			created, err := upsertFood(tx, food)
+			switch {
+			case err != nil:
+				return fmt.Errorf("line %d: %w", line, err)
+			case created:
+				result.Created++
+			default:
+				result.Updated++
+			}
+		}
+	})
+	return result, err
+}
+
+// parseFoodRecord builds a Food from one CSV record
+func parseFoodRecord(record []string, columns map[string]int) (*Food, error) {
+	value := func(field string) string {
+		if i, ok := columns[field]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	number := func(field string) (float64, error) {
+		raw := value(field)
+		if raw == "" {
+			return 0, nil
+		}
+		n, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid %s %q", field, raw)
+		}
+		return n, nil
+	}
+
+	food := &Food{ExternalID: value("external_id"), Name: value("name"), Brand: value("brand")}
+	targets := map[string]*float64{
+		"calories": &food.Calories,
+		"protein":  &food.Protein,
+		"carbs":    &food.Carbs,
+		"fat":      &food.Fats,
+		"fiber":    &food.Fiber,
+		"sugar":    &food.Sugar,
+		"sodium":   &food.Sodium,
+	}
+	for field, target := range targets {
+		n, err := number(field)
+		if err != nil {
+			return nil, err
+		}
+		*target = n
+	}
+
+	grams, err := number("serving_grams")
+	if err != nil {
+		return nil, err
+	}
+	if grams > 0 {
+		name := value("serving_name")
+		if name == "" {
+			name = fmt.Sprintf("%g g", grams)
+		}
+		food.Servings = []FoodServing{{Name: name, Grams: grams}}
+	}
+
+	if !prepareFood(food) {
+		return nil, fmt.Errorf("missing name or negative nutrition values")
+	}
+	return food, nil
+}
+
+// upsertFood creates food or updates the existing food it matches, reporting
+// whether a new row was created
+func upsertFood(tx *gorm.DB, food *Food) (bool, error) {
+	var existing Food
+	query := tx.Where("name = ? AND brand = ?", food.Name, food.Brand)
+	if food.ExternalID != "" {
+		query = tx.Where("external_id = ?", food.ExternalID)
+	}
+	if query.Limit(1).Find(&existing).RowsAffected == 0 {
+		return true, tx.Create(food).Error
+	}
+
+	food.ID = existing.ID
+	food.CreatedAt = existing.CreatedAt
+	for i := range food.Servings {
+		food.Servings[i].FoodID = food.ID
+	}
+	return false, saveFood(tx, food)
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoadFoodsCSV_USDAHeaders(t *testing.T) {
+	db = setupTestDB()
+
+	csvData := `fdc_id,description,brand_owner,Energy (kcal),Protein (g),"Carbohydrate, by difference (g)",Total lipid (fat) (g),"Fiber, total dietary (g)","Sodium, Na (mg)",serving_size,household_serving_fulltext
+171705,"Oats, rolled",,379,13.2,67.7,6.5,10.1,6,40,1/2 cup
+173944,"Rice, white, cooked",,130,2.7,28.2,0.3,0.4,1,,
+999999,Broken row,,abc,1,1,1,,,,
+`
+	result, err := loadFoodsCSV(db, strings.NewReader(csvData))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.Created)
+	assert.Len(t, result.Skipped, 1)
+	assert.Contains(t, result.Skipped[0], "line 4")
+
+	var oats Food
+	db.Preload("Servings").Where("external_id = ?", "171705").First(&oats)
+	assert.Equal(t, "Oats, rolled", oats.Name)
+	assert.Equal(t, 67.7, oats.Carbs)
+	assert.Equal(t, 6.5, oats.Fats)
+	assert.Equal(t, 6.0, oats.Sodium)
+	assert.Len(t, oats.Servings, 1)
+	assert.Equal(t, "1/2 cup", oats.Servings[0].Name)
+}
+
+func TestLoadFoodsCSV_ReloadUpdates(t *testing.T) {
+	db = setupTestDB()
+
+	first := "name,brand,calories,protein,carbs,fat\nGreek Yogurt,Fage,97,9,4,5\n"
+	second := "name,brand,calories,protein,carbs,fat\nGreek Yogurt,Fage,73,10,3.6,1.9\n"
+
+	_, err := loadFoodsCSV(db, strings.NewReader(first))
+	assert.NoError(t, err)
+	result, err := loadFoodsCSV(db, strings.NewReader(second))
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.Created)
+	assert.Equal(t, 1, result.Updated)
+
+	var foods []Food
+	db.Find(&foods)
+	assert.Len(t, foods, 1)
+	assert.Equal(t, 73.0, foods[0].Calories)
+}
+
+func TestLoadFoodsCSV_MissingNameColumn(t *testing.T) {
+	db = setupTestDB()
+
+	_, err := loadFoodsCSV(db, strings.NewReader("calories,protein\n100,10\n"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestFood stores a food with one serving for meal item tests
+func createTestFood() Food {
+	food := Food{
+		Name:     "Rolled Oats",
+		Brand:    "Generic",
+		Carbs:    66,
+		Protein:  17,
+		Fats:     7,
+		Calories: 380,
+		Fiber:    10,
+		Servings: []FoodServing{{Name: "1/2 cup", Grams: 40}},
+	}
+	db.Create(&food)
+	return food
+}
+
+func TestCreateFood_Success(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"name": "Chicken Breast", "protein": 31, "fat": 3.6, "calories": 165, "sodium_mg": 74, "servings": [{"name": "1 breast", "grams": 172}]}`)
+	req, _ := http.NewRequest("POST", "/foods", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var food Food
+	err := json.Unmarshal(w.Body.Bytes(), &food)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, food.ID)
+	assert.Equal(t, 3.6, food.Fats)
+	assert.Len(t, food.Servings, 1)
+}
+
+func TestCreateFood_InvalidInput(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"name": "Mystery", "protein": -1}`)
+	req, _ := http.NewRequest("POST", "/foods", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetFoods_Search(t *testing.T) {
+	db = setupTestDB()
+
+	createTestFood()
+	db.Create(&Food{Name: "White Rice", Carbs: 28, Protein: 2.7, Calories: 130})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/foods?q=oat", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var foods []Food
+	err := json.Unmarshal(w.Body.Bytes(), &foods)
+	assert.NoError(t, err)
+	assert.Len(t, foods, 1)
+	assert.Equal(t, "Rolled Oats", foods[0].Name)
+}
+
+func TestCreateMeal_FromFoodItems(t *testing.T) {
+	db = setupTestDB()
+
+	oats := createTestFood()
+	rice := Food{Name: "White Rice", Carbs: 28, Protein: 2.7, Fats: 0.3, Calories: 130}
+	db.Create(&rice)
+
+	r := setupRouter()
+
+	reqBody := []byte(fmt.Sprintf(`{"date": "2023-10-01", "name": "Breakfast", "items": [
+		{"food_id": %d, "serving_id": %d, "quantity": 2},
+		{"food_id": %d, "grams": 200}
+	]}`, oats.ID, oats.Servings[0].ID, rice.ID))
+	req, _ := http.NewRequest("POST", "/meals", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var m Meal
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	assert.Len(t, m.Items, 2)
+	assert.Equal(t, 80.0, m.Items[0].Grams)
+	assert.Equal(t, 52.8, m.Items[0].Carbs)
+	// 52.8 + 56 carbs, 13.6 + 5.4 protein, 5.6 + 0.6 fat, 304 + 260 calories
+	assert.Equal(t, 109, m.Carbs)
+	assert.Equal(t, 19, m.Protein)
+	assert.Equal(t, 6, m.Fats)
+	assert.Equal(t, 564, m.Calories)
+	assert.Empty(t, m.Warnings)
+}
+
+func TestCreateMeal_UnknownFood(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"date": "2023-10-01", "name": "Breakfast", "items": [{"food_id": 999, "grams": 100}]}`)
+	req, _ := http.NewRequest("POST", "/meals", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Invalid meal items", response["error"])
+}
+
+func TestUpdateFood_ReplacesServings(t *testing.T) {
+	db = setupTestDB()
+
+	food := createTestFood()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"servings": [{"name": "1 cup", "grams": 80}, {"name": "1 tbsp", "grams": 5}]}`)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/foods/%d", food.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var servings []FoodServing
+	db.Where("food_id = ?", food.ID).Find(&servings)
+	assert.Len(t, servings, 2)
+}
+
+func TestDeleteFood_Success(t *testing.T) {
+	db = setupTestDB()
+
+	food := createTestFood()
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/foods/%d", food.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Food deleted successfully", response["message"])
+}
@@ -0,0 +1,237 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// GoalProjection compares the current bodyweight trend with what a goal needs.
+// Rates are in Unit per week and measured as of the latest weigh-in. A
+// goal is off track when the actual rate trails the required rate by more
+// than GOAL_RATE_TOLERANCE, or when a maintain goal drifts by more than it.
+type GoalProjection struct {
+	GoalID             int      `json:"goal_id"`
+	AsOf               string   `json:"as_of"`
+	CurrentWeight      float64  `json:"current_weight"`
+	TargetWeight       float64  `json:"target_weight"`
+	TargetDate         string   `json:"target_date"`
+	RemainingWeight    float64  `json:"remaining_weight"`
+	DaysRemaining      int      `json:"days_remaining"`
+	RequiredWeeklyRate *float64 `json:"required_weekly_rate"`
+	ActualWeeklyRate   *float64 `json:"actual_weekly_rate"`
+	ProjectedDate      *string  `json:"projected_date"`
+	Reached            bool     `json:"reached"`
+	OffTrack           bool     `json:"off_track"`
+	Unit               string   `json:"unit"`
+}
+
+// validGoal tidies a goal and reports whether its type, weight and date are valid
+func validGoal(goal *Goal) bool {
+	goal.Name = strings.TrimSpace(goal.Name)
+	goal.Type = strings.ToLower(strings.TrimSpace(goal.Type))
+	if _, err := time.Parse(dateLayout, goal.TargetDate); err != nil {
+		return false
+	}
+	switch goal.Type {
+	case GoalTypeCut, GoalTypeBulk, GoalTypeMaintain:
+		return goal.TargetWeight > 0
+	default:
+		return false
+	}
+}
+
+// projectGoal projects when the goal will be reached at its owner's current
+// trend rate. It returns nil when no weight has been logged yet.
+func projectGoal(goal *Goal, tolerance float64) (*GoalProjection, error) {
+	points, err := buildWeightTrend(goal.UserID, defaultTrendAlpha, "", "")
+	if err != nil || len(points) == 0 {
+		return nil, err
+	}
+
+	latest := points[len(points)-1]
+	asOf, _ := time.Parse(dateLayout, string(latest.Date))
+	targetDate, _ := time.Parse(dateLayout, goal.TargetDate)
+	projection := &GoalProjection{
+		GoalID:          goal.ID,
+		AsOf:            string(latest.Date),
+		CurrentWeight:   latest.Trend,
+		TargetWeight:    goal.TargetWeight,
+		TargetDate:      goal.TargetDate,
+		RemainingWeight: roundTo(goal.TargetWeight-latest.Trend, 2),
+		DaysRemaining:   int(targetDate.Sub(asOf).Hours() / 24),
+	}
+
+	// Prefer the trend's change over the last week, falling back to the
+	// average rate over all history when there is less than a week of it
+	switch {
+	case latest.WeeklyChange != nil:
+		projection.ActualWeeklyRate = latest.WeeklyChange
+	case len(points) > 1:
+		rate := roundTo((latest.Trend-points[0].Trend)/float64(len(points)-1)*7, 2)
+		projection.ActualWeeklyRate = &rate
+	}
+
+	switch goal.Type {
+	case GoalTypeCut:
+		projection.Reached = projection.RemainingWeight >= 0
+	case GoalTypeBulk:
+		projection.Reached = projection.RemainingWeight <= 0
+	}
+
+	if projection.DaysRemaining > 0 {
+		required := 0.0
+		if goal.Type != GoalTypeMaintain {
+			required = roundTo(projection.RemainingWeight/float64(projection.DaysRemaining)*7, 2)
+		}
+		projection.RequiredWeeklyRate = &required
+	}
+
+	actual := projection.ActualWeeklyRate
+	if goal.Type != GoalTypeMaintain && !projection.Reached && actual != nil && *actual != 0 &&
+		math.Signbit(*actual) == math.Signbit(projection.RemainingWeight) {
+		days := int(math.Ceil(projection.RemainingWeight / *actual * 7))
+		date := asOf.AddDate(0, 0, days).Format(dateLayout)
+		projection.ProjectedDate = &date
+	}
+
+	switch {
+	case projection.Reached:
+	case projection.RequiredWeeklyRate == nil:
+		// The target date has passed without the goal being reached
+		projection.OffTrack = goal.Type != GoalTypeMaintain || math.Abs(projection.RemainingWeight) > tolerance
+	case actual != nil && goal.Type == GoalTypeMaintain:
+		projection.OffTrack = math.Abs(*actual) > tolerance
+	case actual != nil:
+		// Only falling behind the required rate counts; getting there early does not
+		behind := *projection.RequiredWeeklyRate - *actual
+		if goal.Type == GoalTypeCut {
+			behind = -behind
+		}
+		projection.OffTrack = behind > tolerance
+	}
+	return projection, nil
+}
+
+// createGoal handles POST /goals
+func createGoal(c *gin.Context) {
+	goal := Goal{UserID: currentUserID(c)}
+
+	switch {
+	case bindUpdate(c, &goal.ID, &goal) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !validGoal(&goal):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case db.Create(&goal).Error != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create goal"})
+		return
+	default:
+		c.JSON(http.StatusCreated, inDisplayUnit(c, &goal))
+	}
+}
+
+// goalList is the filtering and sorting accepted by GET /goals
+var goalList = listSpec{
+	DateColumn:  "target_date",
+	Sorts:       map[string]string{"target_date": "target_date", "created_at": "created_at", "target_weight": "target_weight"},
+	DefaultSort: "target_date",
+	Filters:     map[string]string{"type": "type"},
+	Contains:    map[string]string{"name": "name"},
+}
+
+// getGoals handles GET /goals
+func getGoals(c *gin.Context) {
+	var goals []Goal
+	err := paginate(c, userScope(c).Model(&Goal{}), goalList, &goals, nil)
+	switch err.(type) {
+	case nil:
+		display := displayMasses(c)
+		for i := range goals {
+			goals[i].convertMass(display)
+		}
+		c.JSON(http.StatusOK, goals)
+	case listParamError:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch goals"})
+	}
+}
+
+// getGoal handles GET /goals/:id
+func getGoal(c *gin.Context) {
+	id := c.Param("id")
+	var goal Goal
+	switch err := userScope(c).First(&goal, id).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, inDisplayUnit(c, &goal))
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+	}
+}
+
+// updateGoal handles PUT /goals/:id
+func updateGoal(c *gin.Context) {
+	id := c.Param("id")
+	var goal Goal
+
+	switch {
+	case userScope(c).First(&goal, id).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+		return
+	case bindUpdate(c, &goal.ID, &goal) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !validGoal(&goal):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case db.Save(&goal).Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update goal"})
+		return
+	default:
+		c.JSON(http.StatusOK, inDisplayUnit(c, &goal))
+	}
+}
+
+// deleteGoal handles DELETE /goals/:id
+func deleteGoal(c *gin.Context) {
+	id := c.Param("id")
+
+	result := userScope(c).Where("id = ?", id).Delete(&Goal{})
+	switch {
+	case result.Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete goal"})
+	case result.RowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Goal deleted successfully"})
+	}
+}
+
+// getGoalProjection handles GET /goals/:id/projection
+func getGoalProjection(c *gin.Context) {
+	id := c.Param("id")
+	var goal Goal
+	if userScope(c).First(&goal, id).Error != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Goal not found"})
+		return
+	}
+
+	projection, err := projectGoal(&goal, goalRateTolerance())
+	switch {
+	case err != nil:
+		log.Println("DB Query Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to project goal"})
+	case projection == nil:
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "No weight entries logged"})
+	default:
+		c.JSON(http.StatusOK, inDisplayUnit(c, projection))
+	}
+}
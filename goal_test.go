@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// seedCut logs three weeks of weigh-ins losing 0.1 kg a day from 90 kg
+func seedCut() {
+	for i := 0; i < 21; i++ {
+		db.Create(&Weight{UserID: testUserID, Date: Date(fmt.Sprintf("2023-10-%02d", i+1)), Weight: 90 - 0.1*float64(i)})
+	}
+}
+
+func TestCreateGoal_Success(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(Goal{Name: "Show day", Type: "Cut", TargetWeight: 82.5, TargetDate: "2024-03-01"})
+	req, _ := http.NewRequest("POST", "/goals", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var goal Goal
+	err := json.Unmarshal(w.Body.Bytes(), &goal)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, goal.ID)
+	assert.Equal(t, GoalTypeCut, goal.Type)
+}
+
+func TestCreateGoal_InvalidInput(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	for _, goal := range []Goal{
+		{Type: "recomp", TargetWeight: 80, TargetDate: "2024-03-01"},
+		{Type: GoalTypeBulk, TargetWeight: 0, TargetDate: "2024-03-01"},
+		{Type: GoalTypeBulk, TargetWeight: 95, TargetDate: "March"},
+	} {
+		reqBody, _ := json.Marshal(goal)
+		req, _ := http.NewRequest("POST", "/goals", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	}
+}
+
+func TestUpdateGoal_Success(t *testing.T) {
+	db = setupTestDB()
+
+	goal := Goal{UserID: testUserID, Type: GoalTypeCut, TargetWeight: 85, TargetDate: "2023-12-01"}
+	db.Create(&goal)
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(Goal{Type: GoalTypeCut, TargetWeight: 84, TargetDate: "2023-12-15"})
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/goals/%d", goal.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated Goal
+	db.First(&updated, goal.ID)
+	assert.Equal(t, 84.0, updated.TargetWeight)
+}
+
+func TestDeleteGoal_NotFound(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("DELETE", "/goals/99", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestGetGoalProjection_OnTrack(t *testing.T) {
+	db = setupTestDB()
+
+	seedCut()
+	goal := Goal{UserID: testUserID, Type: GoalTypeCut, TargetWeight: 85, TargetDate: "2023-12-31"}
+	db.Create(&goal)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/goals/%d/projection", goal.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var projection GoalProjection
+	err := json.Unmarshal(w.Body.Bytes(), &projection)
+	assert.NoError(t, err)
+	assert.Equal(t, "2023-10-21", projection.AsOf)
+	assert.Equal(t, 71, projection.DaysRemaining)
+	assert.Less(t, *projection.ActualWeeklyRate, *projection.RequiredWeeklyRate)
+	assert.NotNil(t, projection.ProjectedDate)
+	assert.Less(t, *projection.ProjectedDate, goal.TargetDate)
+	assert.False(t, projection.Reached)
+	assert.False(t, projection.OffTrack)
+}
+
+func TestGetGoalProjection_OffTrack(t *testing.T) {
+	db = setupTestDB()
+
+	seedCut()
+	goal := Goal{UserID: testUserID, Type: GoalTypeCut, TargetWeight: 80, TargetDate: "2023-11-01"}
+	db.Create(&goal)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/goals/%d/projection", goal.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var projection GoalProjection
+	err := json.Unmarshal(w.Body.Bytes(), &projection)
+	assert.NoError(t, err)
+	assert.Greater(t, *projection.ProjectedDate, goal.TargetDate)
+	assert.True(t, projection.OffTrack)
+}
+
+func TestGetGoalProjection_BulkGoingWrongWay(t *testing.T) {
+	db = setupTestDB()
+
+	seedCut()
+	goal := Goal{UserID: testUserID, Type: GoalTypeBulk, TargetWeight: 92, TargetDate: "2024-01-01"}
+	db.Create(&goal)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/goals/%d/projection", goal.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var projection GoalProjection
+	err := json.Unmarshal(w.Body.Bytes(), &projection)
+	assert.NoError(t, err)
+	assert.Nil(t, projection.ProjectedDate)
+	assert.True(t, projection.OffTrack)
+}
+
+func TestGetGoalProjection_NoWeights(t *testing.T) {
+	db = setupTestDB()
+
+	goal := Goal{UserID: testUserID, Type: GoalTypeMaintain, TargetWeight: 80, TargetDate: "2024-01-01"}
+	db.Create(&goal)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/goals/%d/projection", goal.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+}
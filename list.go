@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// maxListLimit is the largest page size list endpoints accept
+const maxListLimit = 1000
+
+// Response headers carrying list metadata, so list bodies stay plain arrays
+const (
+	totalCountHeader = "X-Total-Count"
+	nextCursorHeader = "X-Next-Cursor"
+)
+
+// listSpec describes the filters and sorts a list endpoint accepts. Sorts
+// map a sort parameter to its column; "-" in front of it sorts descending.
+// Filters match a column exactly and Contains match it case-insensitively
+// when given as param~=value.
+type listSpec struct {
+	DateColumn  string
+	Sorts       map[string]string
+	DefaultSort string
+	Filters     map[string]string
+	Contains    map[string]string
+}
+
+// listParamError is returned for malformed list parameters
+type listParamError string
+
+func (e listParamError) Error() string { return string(e) }
+
+// listCursor marks the last row of a page by its sort value and ID
+type listCursor struct {
+	Value string `json:"v"`
+	ID    int    `json:"id"`
+}
+
+// encode returns the cursor as an opaque URL-safe string
+func (cursor listCursor) encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeCursor parses a cursor returned in X-Next-Cursor
+func decodeCursor(raw string) (*listCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(raw)
+	if err != nil {
+		return nil, listParamError("Invalid cursor")
+	}
+	var cursor listCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, listParamError("Invalid cursor")
+	}
+	return &cursor, nil
+}
+
+// applyListFilters narrows query by the date range and field filters in the
+// request
+func applyListFilters(c *gin.Context, query *gorm.DB, spec listSpec) (*gorm.DB, error) {
+	if spec.DateColumn != "" {
+		from, to, ok := parseDateRange(c)
+		if !ok {
+			return nil, listParamError("Invalid date range")
+		}
+		if from != "" {
+			query = query.Where(spec.DateColumn+" >= ?", from)
+		}
+		if to != "" {
+			query = query.Where(spec.DateColumn+" <= ?", to)
+		}
+	}
+	for param, column := range spec.Filters {
+		if value, ok := c.GetQuery(param); ok {
+			query = query.Where(column+" = ?", value)
+		}
+	}
+	for param, column := range spec.Contains {
+		if value := strings.ToLower(strings.TrimSpace(c.Query(param + "~"))); value != "" {
+			query = query.Where("LOWER("+column+") LIKE ?", "%"+value+"%")
+		}
+	}
+	return query, nil
+}
+
+// paginate loads one page of query into dest, a pointer to a slice, using
+// the limit, cursor, sort and filter parameters in the request. Without a
+// limit every matching row is loaded, as before lists were paged. It sets
+// the total number of matching rows and the cursor for the next page, if
+// any, in response headers. preload, when set, adds preloads to the page
+// query.
+func paginate(c *gin.Context, query *gorm.DB, spec listSpec, dest interface{}, preload func(*gorm.DB) *gorm.DB) error {
+	var limit int
+	if raw, ok := c.GetQuery("limit"); ok {
+		var err error
+		if limit, err = strconv.Atoi(raw); err != nil || limit <= 0 || limit > maxListLimit {
+			return listParamError("Invalid limit")
+		}
+	}
+
+	sort := c.DefaultQuery("sort", spec.DefaultSort)
+	desc := strings.HasPrefix(sort, "-")
+	column, ok := spec.Sorts[strings.TrimPrefix(sort, "-")]
+	if !ok {
+		return listParamError("Invalid sort")
+	}
+
+	query, err := applyListFilters(c, query, spec)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		return err
+	}
+
+	direction, compare := "ASC", ">"
+	if desc {
+		direction, compare = "DESC", "<"
+	}
+	if raw := c.Query("cursor"); raw != "" {
+		cursor, err := decodeCursor(raw)
+		if err != nil {
+			return err
+		}
+		var value interface{} = cursor.Value
+		if column == "created_at" {
+			if value, err = time.Parse(time.RFC3339Nano, cursor.Value); err != nil {
+				return listParamError("Invalid cursor")
+			}
+		}
+		query = query.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", column, compare), value, value, cursor.ID)
+	}
+
+	query = query.Order(column + " " + direction).Order("id " + direction)
+	if limit > 0 {
+		query = query.Limit(limit + 1)
+	}
+	if preload != nil {
+		query = preload(query)
+	}
+	result := query.Find(dest)
+	if result.Error != nil {
+		return result.Error
+	}
+
+	c.Header(totalCountHeader, strconv.FormatInt(total, 10))
+	rows := reflect.ValueOf(dest).Elem()
+	if limit == 0 || rows.Len() <= limit {
+		return nil
+	}
+
+	// Drop the look-ahead row and point the cursor at the last row kept
+	rows.Set(rows.Slice(0, limit))
+	last := rows.Index(limit - 1)
+	schema := result.Statement.Schema
+	value, _ := schema.LookUpField(column).ValueOf(c, last)
+	id, _ := schema.LookUpField("id").ValueOf(c, last)
+
+	cursor := listCursor{Value: fmt.Sprint(value), ID: id.(int)}
+	if t, ok := value.(time.Time); ok {
+		cursor.Value = t.Format(time.RFC3339Nano)
+	}
+	c.Header(nextCursorHeader, cursor.encode())
+	return nil
+}
@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// getList fetches a list endpoint and decodes its rows into dest
+func getList(t *testing.T, path string, dest interface{}) *httptest.ResponseRecorder {
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", path, nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	if w.Code == http.StatusOK {
+		err := json.Unmarshal(w.Body.Bytes(), dest)
+		assert.NoError(t, err)
+	}
+	return w
+}
+
+func TestGetExercises_PagesWithCursor(t *testing.T) {
+	db = setupTestDB()
+
+	for day := 1; day <= 5; day++ {
+		db.Create(&Exercise{UserID: testUserID, Date: Date(fmt.Sprintf("2023-10-%02d", day)), Movement: "Squat", Sets: 3, Reps: 5, Weight: float64(100 + day)})
+	}
+	// Same date as another entry, so the ID breaks the tie
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-03", Movement: "Squat", Sets: 3, Reps: 5, Weight: 90})
+
+	var dates []string
+	cursor := ""
+	for pages := 0; pages < 5; pages++ {
+		var exercises []Exercise
+		w := getList(t, "/exercises?limit=4&sort=-date&cursor="+cursor, &exercises)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "6", w.Header().Get(totalCountHeader))
+
+		for _, exercise := range exercises {
+			dates = append(dates, string(exercise.Date))
+		}
+		cursor = w.Header().Get(nextCursorHeader)
+		if cursor == "" {
+			break
+		}
+	}
+
+	assert.Equal(t, []string{"2023-10-05", "2023-10-04", "2023-10-03", "2023-10-03", "2023-10-02", "2023-10-01"}, dates)
+}
+
+func TestGetExercises_Filters(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", Type: "strength", Sets: 3, Reps: 5, Weight: 100})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-02", Movement: "Front Squat", Type: "strength", Sets: 3, Reps: 5, Weight: 80})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-03", Movement: "Bench Press", Type: "strength", Sets: 3, Reps: 5, Weight: 80})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-04", Movement: "Squat", Type: "strength", Sets: 3, Reps: 5, Weight: 105})
+
+	var exercises []Exercise
+	w := getList(t, "/exercises?movement~=squat&from=2023-10-02&sort=date", &exercises)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "2", w.Header().Get(totalCountHeader))
+	assert.Equal(t, "Front Squat", exercises[0].Movement)
+	assert.Equal(t, "Squat", exercises[1].Movement)
+	assert.Empty(t, w.Header().Get(nextCursorHeader))
+
+	exercises = nil
+	getList(t, "/exercises?movement=Squat", &exercises)
+	assert.Len(t, exercises, 2)
+}
+
+func TestGetWeightEntries_SortByWeight(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 81.5})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-02", Weight: 80.2})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-03", Weight: 82})
+
+	var weights []Weight
+	w := getList(t, "/weights?sort=-weight&limit=2", &weights)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 82.0, weights[0].Weight)
+	assert.Equal(t, 81.5, weights[1].Weight)
+
+	var rest []Weight
+	getList(t, "/weights?sort=-weight&limit=2&cursor="+w.Header().Get(nextCursorHeader), &rest)
+	assert.Len(t, rest, 1)
+	assert.Equal(t, 80.2, rest[0].Weight)
+}
+
+func TestGetMeals_DefaultSortPages(t *testing.T) {
+	db = setupTestDB()
+
+	for i := 1; i <= 3; i++ {
+		db.Create(&Meal{UserID: testUserID, Name: fmt.Sprintf("Meal %d", i), Date: "2023-10-01"})
+	}
+
+	var first, second []Meal
+	w := getList(t, "/meals?limit=2", &first)
+	getList(t, "/meals?limit=2&cursor="+w.Header().Get(nextCursorHeader), &second)
+
+	assert.Len(t, first, 2)
+	assert.Len(t, second, 1)
+	assert.Equal(t, "Meal 1", second[0].Name)
+}
+
+func TestGetMeals_InvalidListParams(t *testing.T) {
+	db = setupTestDB()
+
+	var meals []Meal
+	for _, query := range []string{"sort=user_id", "limit=0", "limit=5000", "cursor=!!", "from=yesterday"} {
+		w := getList(t, "/meals?"+query, &meals)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
+
+func TestGetWeightEntries_NoLimitReturnsAll(t *testing.T) {
+	db = setupTestDB()
+
+	weights := make([]Weight, 150)
+	for i := range weights {
+		weights[i] = Weight{UserID: testUserID, Date: Date(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC).AddDate(0, 0, i).Format("2006-01-02")), Weight: 80}
+	}
+	db.Create(&weights)
+
+	var all []Weight
+	w := getList(t, "/weights", &all)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, all, 150)
+	assert.Equal(t, "150", w.Header().Get(totalCountHeader))
+	assert.Empty(t, w.Header().Get(nextCursorHeader))
+}
+
+func TestSetupRoutes_ExposesListHeaders(t *testing.T) {
+	db = setupTestDB()
+	r := SetupRoutes()
+
+	req, _ := http.NewRequest("GET", "/movements", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	exposed := w.Header().Get("Access-Control-Expose-Headers")
+	assert.Contains(t, exposed, totalCountHeader)
+	assert.Contains(t, exposed, nextCursorHeader)
+}
@@ -2,12 +2,24 @@ package main
 
 import (
 	"log"
+	"os"
+
+	// Embed the IANA time zone database for hosts without one
+	_ "time/tzdata"
 )
 
 func main() {
 	// Initialize database connection
 	InitDatabase()
 
+	// Run a command instead of the server when one is given
+	if len(os.Args) > 1 {
+		if err := runCommand(os.Args[1:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Setup routes
 	r := SetupRoutes()
 
@@ -2,24 +2,100 @@ package main
 
 import (
 	"log"
+	"math"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 )
 
+// Warning attached to a meal whose calories disagree with its macros
+const WarningCalorieMismatch = "calorie_mismatch"
+
+// macroCalories returns the calories implied by a meal's macros at 4 kcal per
+// gram of carbs and protein and 9 kcal per gram of fat
+func macroCalories(meal *Meal) int {
+	return meal.Carbs*4 + meal.Protein*4 + meal.Fats*9
+}
+
+// checkMealCalories fills in omitted calories from the macros and compares
+// stated calories against them within MEAL_CALORIE_TOLERANCE percent. A
+// mismatch is ignored, flagged with a warning or rejected depending on
+// MEAL_CALORIE_CHECK; it reports false when the meal must be rejected.
+func checkMealCalories(meal *Meal) bool {
+	expected := macroCalories(meal)
+	check := calorieCheck()
+
+	switch {
+	case check == StrictnessOff || len(meal.Items) > 0:
+		// Calories of item based meals come from the food database
+		return true
+	case meal.Calories == 0:
+		meal.Calories = expected
+		return true
+	case math.Abs(float64(meal.Calories-expected)) <= float64(expected)*calorieTolerance()/100:
+		return true
+	case check == StrictnessReject:
+		return false
+	default:
+		meal.Warnings = append(meal.Warnings, WarningCalorieMismatch)
+		return true
+	}
+}
+
+// calorieMismatchError is the field-level error for a rejected meal
+func calorieMismatchError(meal *Meal) gin.H {
+	return gin.H{
+		"error":             "Calories do not match macros",
+		"field":             "calories",
+		"expected_calories": macroCalories(meal),
+	}
+}
+
+// saveMeal updates a meal, replacing its items in the same transaction when
+// new ones were sent
+func saveMeal(meal *Meal) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit("Items").Save(meal).Error; err != nil || len(meal.Items) == 0 {
+			return err
+		}
+		if err := tx.Unscoped().Where("meal_id = ?", meal.ID).Delete(&MealItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&meal.Items).Error
+	})
+}
+
+// validateMeal applies the rules every logged meal must meet, with dates read
+// in the user's time zone loc. It returns the error response for the first
+// rule broken, or nil when the meal is valid.
+func validateMeal(meal *Meal, loc *time.Location) gin.H {
+	switch {
+	case !normalizeDate(&meal.Date, meal.OccurredAt, loc):
+		return dateFieldError(meal.Date, meal.OccurredAt)
+	case !prepareMealItems(meal):
+		return gin.H{"error": "Invalid meal items"}
+	case meal.Carbs < 0 || meal.Fats < 0 || meal.Protein < 0 || meal.Calories < 0:
+		return gin.H{"error": "Invalid input values"}
+	case !checkMealCalories(meal):
+		return calorieMismatchError(meal)
+	}
+	return nil
+}
+
 // createMeal handles POST /meals
 func createMeal(c *gin.Context) {
-	var meal Meal
+	meal := Meal{UserID: currentUserID(c)}
 
 	log.Println("Received request to create meal")
 
 	switch {
-	case c.ShouldBindJSON(&meal) != nil:
+	case bindAudited(c, &meal.ID, &meal.Audit, &meal) != nil:
 		log.Println("JSON Bind Error")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
-	case meal.Carbs < 0 || meal.Fats < 0 || meal.Protein < 0 || meal.Calories < 0:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+	case rejectInvalid(c, validateMeal(&meal, currentLocation(c))):
 		return
 	case db.Create(&meal).Error != nil:
 		log.Println("DB Insert Error")
@@ -31,12 +107,31 @@ func createMeal(c *gin.Context) {
 	}
 }
 
+// mealList is the filtering and sorting accepted by GET /meals
+var mealList = listSpec{
+	DateColumn:  "date",
+	Sorts:       map[string]string{"date": "date", "created_at": "created_at", "name": "name", "calories": "calories"},
+	DefaultSort: "-created_at",
+	Filters:     map[string]string{"name": "name"},
+	Contains:    map[string]string{"name": "name"},
+}
+
+// preloadItems loads a meal's items in the order they were added
+func preloadItems(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Items", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id ASC")
+	})
+}
+
 // getMeals handles GET /meals
 func getMeals(c *gin.Context) {
 	var meals []Meal
-	switch err := db.Order("created_at DESC").Find(&meals).Error; err {
+	err := paginate(c, userScope(c).Model(&Meal{}), mealList, &meals, preloadItems)
+	switch err.(type) {
 	case nil:
 		c.JSON(http.StatusOK, meals)
+	case listParamError:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch meals"})
 	}
@@ -48,13 +143,15 @@ func updateMeal(c *gin.Context) {
 	var meal Meal
 	
 	switch {
-	case db.First(&meal, id).Error != nil:
+	case userScope(c).First(&meal, id).Error != nil:
 		c.JSON(http.StatusNotFound, gin.H{"error": "Meal not found"})
 		return
-	case c.ShouldBindJSON(&meal) != nil:
+	case bindAudited(c, &meal.ID, &meal.Audit, &meal) != nil:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
-	case db.Save(&meal).Error != nil:
+	case rejectInvalid(c, validateMeal(&meal, currentLocation(c))):
+		return
+	case saveMeal(&meal) != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update meal"})
 		return
 	default:
@@ -71,7 +168,7 @@ func deleteMeal(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid meal ID"})
 		return
 	default:
-		result := db.Where("id = ?", id).Delete(&Meal{})
+		result := userScope(c).Where("id = ?", id).Delete(&Meal{})
 		switch {
 		case result.Error != nil:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete meal"})
@@ -0,0 +1,295 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Orders of the day and month in the numeric dates of a nutrition export
+const (
+	DateOrderMDY = "mdy"
+	DateOrderDMY = "dmy"
+)
+
+// myFitnessPalColumns maps the normalized headers of a MyFitnessPal
+// "Nutrition" export to the field they fill
+var myFitnessPalColumns = map[string]string{
+	"date":            "date",
+	"meal":            "meal",
+	"calories":        "calories",
+	"fat":             "fat",
+	"fat g":           "fat",
+	"carbohydrates":   "carbs",
+	"carbohydrates g": "carbs",
+	"carbs":           "carbs",
+	"protein":         "protein",
+	"protein g":       "protein",
+}
+
+// MealImportResult reports a nutrition export import. Rows that cannot be
+// imported are listed under skipped with the reason, and rows that could
+// mean more than one thing, such as a date like 03/04/2023, under ambiguous;
+// neither are saved.
+type MealImportResult struct {
+	DryRun    bool    `json:"dry_run"`
+	Imported  int     `json:"imported"`
+	Skipped   []gin.H `json:"skipped"`
+	Ambiguous []gin.H `json:"ambiguous"`
+}
+
+// numericDate splits a date such as 10/01/2023, 1.10.23 or 01-10-2023 into
+// its two leading parts and four digit year
+func numericDate(raw string) (first, second, year int, ok bool) {
+	parts := strings.FieldsFunc(raw, func(r rune) bool { return r == '/' || r == '.' || r == '-' })
+	if len(parts) != 3 || len(parts[0]) > 2 {
+		return 0, 0, 0, false
+	}
+	var values [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil {
+			return 0, 0, 0, false
+		}
+		values[i] = n
+	}
+	if values[2] < 100 {
+		values[2] += 2000
+	}
+	return values[0], values[1], values[2], true
+}
+
+// detectDateOrder returns the day and month order the numeric dates of an
+// export show, or "" when none of them can tell or they disagree
+func detectDateOrder(rows []csvRow) string {
+	var mdy, dmy bool
+	for _, row := range rows {
+		first, second, _, ok := numericDate(row.value("date"))
+		switch {
+		case !ok:
+		case first > 12:
+			dmy = true
+		case second > 12:
+			mdy = true
+		}
+	}
+	switch {
+	case mdy && !dmy:
+		return DateOrderMDY
+	case dmy && !mdy:
+		return DateOrderDMY
+	}
+	return ""
+}
+
+// myFitnessPalDate parses the date of a row as ISO-8601 or as a numeric date
+// in order, reporting whether a numeric date is ambiguous because order is
+// unknown and both its day and month could be either part
+func myFitnessPalDate(raw, order string) (Date, bool) {
+	if date := Date(raw); date.Valid() {
+		return date, false
+	}
+	first, second, year, ok := numericDate(raw)
+	if !ok {
+		return Date(raw), false
+	}
+	if order == "" {
+		switch {
+		case first > 12:
+			order = DateOrderDMY
+		case second > 12, first == second:
+			order = DateOrderMDY
+		default:
+			return Date(raw), true
+		}
+	}
+	month, day := first, second
+	if order == DateOrderDMY {
+		month, day = second, first
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC)
+	if t.Month() != time.Month(month) || t.Day() != day {
+		return Date(raw), false
+	}
+	return Date(t.Format(dateLayout)), false
+}
+
+// parseMyFitnessPalRow builds a meal named after the row's meal slot, with
+// its nutrition rounded to whole grams and calories
+func parseMyFitnessPalRow(row csvRow, date Date) (*Meal, error) {
+	meal := &Meal{Date: date, Name: row.value("meal")}
+	targets := map[string]*int{"calories": &meal.Calories, "fat": &meal.Fats, "carbs": &meal.Carbs, "protein": &meal.Protein}
+	for column, target := range targets {
+		n, err := row.number(column)
+		if err != nil {
+			return nil, err
+		}
+		*target = int(math.Round(n))
+	}
+	return meal, nil
+}
+
+// mealSlotKey identifies a meal slot on a day
+func mealSlotKey(date Date, name string) string {
+	return string(date) + "\n" + strings.ToLower(name)
+}
+
+// loggedMealSlots returns the meal slots the user has already logged between
+// the dates from and to
+func loggedMealSlots(tx *gorm.DB, userID int, from, to Date) (map[string]bool, error) {
+	var meals []Meal
+	err := tx.Select("date", "name").Where("user_id = ? AND date >= ? AND date <= ?", userID, from, to).Find(&meals).Error
+	logged := map[string]bool{}
+	for _, meal := range meals {
+		logged[mealSlotKey(meal.Date, meal.Name)] = true
+	}
+	return logged, err
+}
+
+// importMyFitnessPal reads a MyFitnessPal "Nutrition" export for the user
+// and, unless it is a dry run, creates a meal for each valid row in one
+// transaction. Numeric dates are read in order, or in the order the export's
+// dates show when it is empty. Rows for a meal slot already logged that day
+// are skipped, so importing an export twice does not double it. createdBy is
+// recorded as the author of the meals.
+func importMyFitnessPal(body io.Reader, userID, createdBy int, order string, dryRun bool) (*MealImportResult, error) {
+	rows, err := readCSVRows(body, func(header []string) (map[string]int, error) {
+		columns := map[string]int{}
+		for i, name := range header {
+			if field, ok := myFitnessPalColumns[csvHeaderKey(name)]; ok {
+				columns[field] = i
+			}
+		}
+		for _, field := range []string{"date", "meal", "calories"} {
+			if _, ok := columns[field]; !ok {
+				return nil, fmt.Errorf("not a MyFitnessPal nutrition export: no %s column", field)
+			}
+		}
+		return columns, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if order == "" {
+		order = detectDateOrder(rows)
+	}
+
+	result := &MealImportResult{DryRun: dryRun, Skipped: []gin.H{}, Ambiguous: []gin.H{}}
+	loc := userLocation(userID)
+	audit := Audit{CreatedBy: createdBy, UpdatedBy: createdBy}
+	var meals []Meal
+	var lines []int
+	for _, row := range rows {
+		date, ambiguous := myFitnessPalDate(row.value("date"), order)
+		if ambiguous {
+			result.Ambiguous = append(result.Ambiguous, rowError(row.Line, gin.H{
+				"error": "Ambiguous date", "field": "date", "value": row.value("date"),
+				"message": "day and month order unknown; pass date_order=mdy or date_order=dmy",
+			}))
+			continue
+		}
+		meal, err := parseMyFitnessPalRow(row, date)
+		switch {
+		case err != nil:
+			result.Skipped = append(result.Skipped, rowError(row.Line, parseProblem(err)))
+			continue
+		case meal.Name == "":
+			result.Skipped = append(result.Skipped, rowError(row.Line, gin.H{"error": "Missing meal", "field": "meal"}))
+			continue
+		case meal.Calories == 0 && meal.Carbs == 0 && meal.Fats == 0 && meal.Protein == 0:
+			result.Skipped = append(result.Skipped, rowError(row.Line, gin.H{"error": "Nothing logged"}))
+			continue
+		}
+		meal.UserID, meal.Audit = userID, audit
+		if problem := validateMeal(meal, loc); problem != nil {
+			result.Skipped = append(result.Skipped, rowError(row.Line, problem))
+			continue
+		}
+		meals = append(meals, *meal)
+		lines = append(lines, row.Line)
+	}
+	if len(meals) == 0 {
+		return result, nil
+	}
+
+	from, to := meals[0].Date, meals[0].Date
+	for _, meal := range meals {
+		if meal.Date < from {
+			from = meal.Date
+		}
+		if meal.Date > to {
+			to = meal.Date
+		}
+	}
+	logged, err := loggedMealSlots(db, userID, from, to)
+	if err != nil {
+		return result, err
+	}
+
+	// A slot listed twice with different values cannot be told apart from
+	// two meals, so only the first is imported
+	seen := map[string]Meal{}
+	fresh := meals[:0]
+	for i, meal := range meals {
+		key := mealSlotKey(meal.Date, meal.Name)
+		first, repeated := seen[key]
+		switch {
+		case logged[key]:
+			result.Skipped = append(result.Skipped, rowError(lines[i], gin.H{"error": "Meal already logged", "date": meal.Date, "meal": meal.Name}))
+		case repeated && first.Calories == meal.Calories && first.Carbs == meal.Carbs && first.Fats == meal.Fats && first.Protein == meal.Protein:
+			result.Skipped = append(result.Skipped, rowError(lines[i], gin.H{"error": "Duplicate row", "date": meal.Date, "meal": meal.Name}))
+		case repeated:
+			result.Ambiguous = append(result.Ambiguous, rowError(lines[i], gin.H{"error": "Meal listed twice for the day", "date": meal.Date, "meal": meal.Name}))
+		default:
+			seen[key] = meal
+			fresh = append(fresh, meal)
+		}
+	}
+	result.Imported = len(fresh)
+	if dryRun || len(fresh) == 0 {
+		return result, nil
+	}
+	return result, db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&fresh, csvBatchSize).Error
+	})
+}
+
+// importMyFitnessPalMeals handles POST /import/myfitnesspal with a
+// MyFitnessPal "Nutrition" CSV export
+func importMyFitnessPalMeals(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+		return
+	}
+	order := strings.ToLower(c.Query("date_order"))
+	if order != "" && order != DateOrderMDY && order != DateOrderDMY {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date_order"})
+		return
+	}
+
+	var result *MealImportResult
+	err = readUpload(c, func(body io.Reader) (err error) {
+		result, err = importMyFitnessPal(body, currentUserID(c), actingUserID(c), order, dryRun)
+		return err
+	})
+	switch {
+	case result == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV", "message": err.Error()})
+	case err != nil:
+		log.Println("DB Insert Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import meals"})
+	case result.DryRun:
+		c.JSON(http.StatusOK, result)
+	default:
+		c.JSON(http.StatusCreated, result)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// myFitnessPalExport is a MyFitnessPal nutrition export with day-first dates
+const myFitnessPalExport = `Date,Meal,Calories,Fat (g),Saturated Fat,Polyunsaturated Fat,Monounsaturated Fat,Trans Fat,Cholesterol,Sodium (mg),Potassium,Carbohydrates (g),Fiber,Sugar,Protein (g),Note
+25/09/2023,Breakfast,420.0,12.4,3,0,0,0,0,300,0,55.2,4,10,22.6,
+25/09/2023,Lunch,0,0,0,0,0,0,0,0,0,0,0,0,0,
+26/09/2023,Dinner,650,20,5,0,0,0,0,800,0,60,6,5,55,
+26/09/2023,Dinner,700,22,5,0,0,0,0,800,0,65,6,5,55,
+27/09/2023,Snacks,abc,1,0,0,0,0,0,0,0,10,0,5,2,
+`
+
+func TestImportMyFitnessPal(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/import/myfitnesspal", myFitnessPalExport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var result MealImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 2, result.Imported)
+	assert.Len(t, result.Skipped, 2)
+	assert.Len(t, result.Ambiguous, 1)
+	assert.Equal(t, float64(5), result.Ambiguous[0]["line"])
+	assert.Equal(t, "calories", result.Skipped[1]["field"])
+
+	var meals []Meal
+	db.Order("date ASC").Find(&meals)
+	assert.Len(t, meals, 2)
+	assert.Equal(t, Date("2023-09-25"), meals[0].Date)
+	assert.Equal(t, "Breakfast", meals[0].Name)
+	assert.Equal(t, 420, meals[0].Calories)
+	assert.Equal(t, 55, meals[0].Carbs)
+	assert.Equal(t, 23, meals[0].Protein)
+	assert.Equal(t, 12, meals[0].Fats)
+	assert.Equal(t, testUserID, meals[0].CreatedBy)
+}
+
+func TestImportMyFitnessPal_ReimportSkipsLoggedMeals(t *testing.T) {
+	db = setupTestDB()
+
+	postCSV("/import/myfitnesspal", myFitnessPalExport)
+	w := postCSV("/import/myfitnesspal", myFitnessPalExport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var result MealImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 0, result.Imported)
+	var count int64
+	db.Model(&Meal{}).Count(&count)
+	assert.Equal(t, int64(2), count)
+}
+
+func TestImportMyFitnessPal_AmbiguousDates(t *testing.T) {
+	db = setupTestDB()
+	export := "Date,Meal,Calories,Fat (g),Carbohydrates (g),Protein (g)\n03/04/2023,Lunch,500,20,50,30\n"
+
+	w := postCSV("/import/myfitnesspal?dry_run=true", export)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var result MealImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 0, result.Imported)
+	assert.Equal(t, "Ambiguous date", result.Ambiguous[0]["error"])
+
+	w = postCSV("/import/myfitnesspal?date_order=dmy", export)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var meal Meal
+	db.First(&meal)
+	assert.Equal(t, Date("2023-04-03"), meal.Date)
+
+	w = postCSV("/import/myfitnesspal", "Date,Weight\n2023-10-01,80\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -70,6 +70,7 @@ func TestGetMeals_Success(t *testing.T) {
 
 	// Add test data
 	meal := Meal{
+		UserID:   testUserID,
 		Date:     "2023-10-01",
 		Name:     "Breakfast",
 		Carbs:    60,
@@ -99,6 +100,7 @@ func TestUpdateMeal_Success(t *testing.T) {
 
 	// Create a test meal
 	meal := Meal{
+		UserID:   testUserID,
 		Date:     "2023-10-01",
 		Name:     "Breakfast",
 		Carbs:    60,
@@ -137,6 +139,7 @@ func TestDeleteMeal_Success(t *testing.T) {
 
 	// Create a test meal
 	meal := Meal{
+		UserID:   testUserID,
 		Date:     "2023-10-01",
 		Name:     "Breakfast",
 		Carbs:    60,
@@ -158,3 +161,102 @@ func TestDeleteMeal_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, "Meal deleted successfully", response["message"])
 }
+
+func TestCreateMeal_FillsOmittedCalories(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"date": "2023-10-01", "name": "Snack", "carbs": 20, "protein": 25, "fat": 5}`)
+	req, _ := http.NewRequest("POST", "/meals", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var m Meal
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	assert.Equal(t, 225, m.Calories)
+	assert.Empty(t, m.Warnings)
+}
+
+func TestCreateMeal_CalorieMismatchWarning(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(Meal{
+		Date:     "2023-10-01",
+		Name:     "Shake",
+		Protein:  50,
+		Calories: 10,
+	})
+	req, _ := http.NewRequest("POST", "/meals", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var m Meal
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	assert.Equal(t, 10, m.Calories)
+	assert.Equal(t, []string{WarningCalorieMismatch}, m.Warnings)
+}
+
+func TestCreateMeal_CalorieMismatchRejected(t *testing.T) {
+	db = setupTestDB()
+	t.Setenv("MEAL_CALORIE_CHECK", StrictnessReject)
+	t.Setenv("MEAL_CALORIE_TOLERANCE", "5")
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(Meal{
+		Date:     "2023-10-01",
+		Name:     "Breakfast",
+		Carbs:    60,
+		Protein:  30,
+		Fats:     20,
+		Calories: 500,
+	})
+	req, _ := http.NewRequest("POST", "/meals", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "calories", response["field"])
+	assert.Equal(t, 540.0, response["expected_calories"])
+}
+
+func TestUpdateMeal_CalorieMismatchRejected(t *testing.T) {
+	db = setupTestDB()
+	t.Setenv("MEAL_CALORIE_CHECK", StrictnessReject)
+
+	meal := Meal{UserID: testUserID, Date: "2023-10-01", Name: "Breakfast", Carbs: 60, Protein: 30, Fats: 20, Calories: 540}
+	db.Create(&meal)
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"protein": 80}`)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/meals/%d", meal.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	var stored Meal
+	db.First(&stored, meal.ID)
+	assert.Equal(t, 30, stored.Protein)
+}
@@ -1,25 +1,201 @@
 package main
 
-import "gorm.io/gorm"
+import (
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
 
 // Exercise represents a workout exercise entry
 type Exercise struct {
 	gorm.Model
-	ID       int     `json:"id"`
-	Date     string  `json:"date"`
-	Movement string  `json:"movement"`
-	Sets     int     `json:"sets"`
-	Reps     int     `json:"reps"`
-	Weight   float64 `json:"weight"`
-	Type     string  `json:"type"`
+	Audit
+	ID         int           `json:"id"`
+	UserID     int           `json:"-" gorm:"index"`
+	Date       Date          `json:"date"`
+	OccurredAt *time.Time    `json:"occurred_at"`
+	Movement   string        `json:"movement"`
+	MovementID *int          `json:"movement_id"`
+	Sets       int           `json:"sets"`
+	Reps       int           `json:"reps"`
+	Weight     float64       `json:"weight"`
+	Unit       string        `json:"unit,omitempty" gorm:"-"`
+	Type       string        `json:"type"`
+	WorkoutID  *int          `json:"workout_id"`
+	Position   int           `json:"position"`
+	SetDetails []ExerciseSet `json:"set_details,omitempty" gorm:"foreignKey:ExerciseID"`
+
+	// PersonalRecords lists the records a newly created exercise set
+	PersonalRecords []PersonalRecord `json:"personal_records,omitempty" gorm:"-"`
+	Warnings        []string         `json:"warnings,omitempty" gorm:"-"`
+}
+
+// UnmarshalJSON accepts "sets" either as a set count (the flat shorthand) or
+// as an array of per-set entries. An explicit "set_details" array takes
+// precedence so a fetched exercise can be sent back unchanged.
+func (e *Exercise) UnmarshalJSON(data []byte) error {
+	type exerciseAlias Exercise
+	aux := struct {
+		*exerciseAlias
+		Sets       json.RawMessage `json:"sets"`
+		SetDetails json.RawMessage `json:"set_details"`
+	}{exerciseAlias: (*exerciseAlias)(e)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var details json.RawMessage
+	switch {
+	case isJSONArray(aux.SetDetails):
+		details = aux.SetDetails
+	case isJSONArray(aux.Sets):
+		details = aux.Sets
+	case len(aux.Sets) > 0 && string(aux.Sets) != "null":
+		// A set count means the flat fields describe every set
+		e.SetDetails = nil
+		return json.Unmarshal(aux.Sets, &e.Sets)
+	default:
+		return nil
+	}
+
+	e.SetDetails = nil
+	if err := json.Unmarshal(details, &e.SetDetails); err != nil {
+		return err
+	}
+	e.Sets = len(e.SetDetails)
+	return nil
+}
+
+// isJSONArray reports whether raw holds a JSON array
+func isJSONArray(raw json.RawMessage) bool {
+	return len(raw) > 0 && raw[0] == '['
+}
+
+// Set types recorded on an ExerciseSet
+const (
+	SetTypeWarmup  = "warmup"
+	SetTypeWorking = "working"
+	SetTypeDrop    = "drop"
+	SetTypeFailure = "failure"
+)
+
+// ExerciseSet represents a single set performed as part of an exercise
+type ExerciseSet struct {
+	gorm.Model
+	ID         int      `json:"id"`
+	ExerciseID int      `json:"exercise_id"`
+	SetNumber  int      `json:"set_number"`
+	Reps       int      `json:"reps"`
+	Load       float64  `json:"load"`
+	RPE        *float64 `json:"rpe"`
+	SetType    string   `json:"set_type"`
 }
 
 // Meal represents a meal entry with nutritional information
 type Meal struct {
+	gorm.Model
+	Audit
+	ID         int        `json:"id"`
+	UserID     int        `json:"-" gorm:"index"`
+	Date       Date       `json:"date"`
+	OccurredAt *time.Time `json:"occurred_at"`
+	Name       string     `json:"name"`
+	Carbs      int        `json:"carbs"`
+	Protein    int        `json:"protein"`
+	Fats       int        `json:"fat"`
+	Calories   int        `json:"calories"`
+
+	Items    []MealItem `json:"items,omitempty" gorm:"foreignKey:MealID"`
+	Warnings []string   `json:"warnings,omitempty" gorm:"-"`
+}
+
+// MealItem is a portion of a food or servings of a recipe eaten as part of a
+// meal. Its nutrition is computed when the meal is logged so later edits to
+// the food or recipe leave the meal unchanged.
+type MealItem struct {
+	gorm.Model
+	ID        int     `json:"id"`
+	MealID    int     `json:"meal_id"`
+	FoodID    *int    `json:"food_id"`
+	ServingID *int    `json:"serving_id,omitempty"`
+	Quantity  float64 `json:"quantity,omitempty"`
+	RecipeID  *int    `json:"recipe_id,omitempty"`
+	Servings  float64 `json:"servings,omitempty"`
+	Grams     float64 `json:"grams"`
+	Carbs     float64 `json:"carbs"`
+	Protein   float64 `json:"protein"`
+	Fats      float64 `json:"fat"`
+	Calories  float64 `json:"calories"`
+}
+
+// Food represents a food with its nutrition per 100 grams
+type Food struct {
+	gorm.Model
+	ID         int           `json:"id"`
+	ExternalID string        `json:"external_id" gorm:"size:64;index"`
+	Name       string        `json:"name"`
+	Brand      string        `json:"brand"`
+	Carbs      float64       `json:"carbs"`
+	Protein    float64       `json:"protein"`
+	Fats       float64       `json:"fat"`
+	Calories   float64       `json:"calories"`
+	Fiber      float64       `json:"fiber"`
+	Sugar      float64       `json:"sugar"`
+	Sodium     float64       `json:"sodium_mg"`
+	Servings   []FoodServing `json:"servings" gorm:"foreignKey:FoodID"`
+}
+
+// Recipe is a dish made from foods. Its nutrition is per serving of the
+// yield and is recomputed from the foods whenever the recipe is saved.
+type Recipe struct {
+	gorm.Model
+	ID          int                `json:"id"`
+	UserID      int                `json:"-" gorm:"index"`
+	Name        string             `json:"name"`
+	Servings    float64            `json:"servings"`
+	Notes       string             `json:"notes"`
+	Grams       float64            `json:"grams_per_serving"`
+	Carbs       float64            `json:"carbs"`
+	Protein     float64            `json:"protein"`
+	Fats        float64            `json:"fat"`
+	Calories    float64            `json:"calories"`
+	Ingredients []RecipeIngredient `json:"ingredients" gorm:"foreignKey:RecipeID"`
+}
+
+// RecipeIngredient is an amount of a food used in a recipe
+type RecipeIngredient struct {
+	gorm.Model
+	ID       int     `json:"id"`
+	RecipeID int     `json:"recipe_id"`
+	FoodID   int     `json:"food_id"`
+	Grams    float64 `json:"grams"`
+}
+
+// FoodServing is a named serving size of a food, such as "1 cup"
+type FoodServing struct {
+	gorm.Model
+	ID     int     `json:"id"`
+	FoodID int     `json:"food_id"`
+	Name   string  `json:"name"`
+	Grams  float64 `json:"grams"`
+}
+
+// Day types a MacroTarget applies to
+const (
+	DayTypeDefault  = "default"
+	DayTypeTraining = "training"
+	DayTypeRest     = "rest"
+)
+
+// MacroTarget represents a user's daily macro and calorie targets for a day
+// type
+type MacroTarget struct {
 	gorm.Model
 	ID       int    `json:"id"`
-	Date     string `json:"date"`
-	Name     string `json:"name"`
+	UserID   int    `json:"-" gorm:"uniqueIndex:idx_macro_targets_user_day_type"`
+	DayType  string `json:"day_type" gorm:"size:20;uniqueIndex:idx_macro_targets_user_day_type"`
 	Carbs    int    `json:"carbs"`
 	Protein  int    `json:"protein"`
 	Fats     int    `json:"fat"`
@@ -29,7 +205,249 @@ type Meal struct {
 // Weight represents a weight tracking entry
 type Weight struct {
 	gorm.Model
-	ID     int     `json:"id"`
-	Date   string  `json:"date"`
-	Weight float64 `json:"weight"`
+	Audit
+	ID         int        `json:"id"`
+	UserID     int        `json:"-" gorm:"index"`
+	Date       Date       `json:"date"`
+	OccurredAt *time.Time `json:"occurred_at"`
+	Weight     float64    `json:"weight"`
+	Unit       string     `json:"unit,omitempty" gorm:"-"`
+	BodyFat    *float64   `json:"body_fat"`
+
+	// Source names the app a weigh-in was imported from, and ExternalID
+	// identifies the sample there so later imports only add new ones
+	Source     string `json:"source,omitempty" gorm:"size:16"`
+	ExternalID string `json:"-" gorm:"size:64;index"`
+}
+
+// User is an account that owns its exercises, workouts, meals, weights and goals.
+// TimeZone is an IANA name such as "Europe/London"; empty means UTC. Unit is
+// the mass unit responses are shown in; empty means kilograms. Admins can
+// change the movement and food catalogs. UnitsConverted records that the
+// masses logged before units existed were converted to kilograms.
+type User struct {
+	gorm.Model
+	ID             int    `json:"id"`
+	Email          string `json:"email" gorm:"size:255;uniqueIndex"`
+	PasswordHash   string `json:"-"`
+	TimeZone       string `json:"time_zone"`
+	Unit           string `json:"unit"`
+	Admin          bool   `json:"-"`
+	UnitsConverted bool   `json:"-"`
+}
+
+// RefreshToken is a long-lived token exchanged for new access tokens. Only a
+// hash of the token is stored.
+type RefreshToken struct {
+	gorm.Model
+	ID        int        `json:"-"`
+	UserID    int        `json:"-" gorm:"index"`
+	TokenHash string     `json:"-" gorm:"size:64;uniqueIndex"`
+	ExpiresAt time.Time  `json:"-"`
+	RevokedAt *time.Time `json:"-"`
+}
+
+// APIToken is a long-lived personal access token for scripts and
+// integrations. Only a hash of the token is stored; Prefix identifies it in
+// listings.
+type APIToken struct {
+	gorm.Model
+	ID         int        `json:"id"`
+	UserID     int        `json:"-" gorm:"index"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix" gorm:"size:20"`
+	TokenHash  string     `json:"-" gorm:"size:64;uniqueIndex"`
+	Scopes     []string   `json:"scopes" gorm:"serializer:json"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+}
+
+// Scopes an athlete can grant a coach
+const (
+	CoachScopeRead  = "read"
+	CoachScopeWrite = "write"
+)
+
+// CoachLink grants a coach access to an athlete's exercises, meals and
+// weights once the coach accepts the athlete's invitation
+type CoachLink struct {
+	gorm.Model
+	ID         int        `json:"id"`
+	AthleteID  int        `json:"athlete_id" gorm:"uniqueIndex:idx_coach_links_pair"`
+	CoachID    int        `json:"coach_id" gorm:"uniqueIndex:idx_coach_links_pair"`
+	Scope      string     `json:"scope" gorm:"size:20"`
+	AcceptedAt *time.Time `json:"accepted_at"`
+	Athlete    *User      `json:"athlete,omitempty" gorm:"foreignKey:AthleteID"`
+	Coach      *User      `json:"coach,omitempty" gorm:"foreignKey:CoachID"`
+}
+
+// DateMigrationIssue is a row whose free-form date could not be converted to
+// a calendar date when the date columns were migrated. The row's date is
+// cleared and the original value kept here for review.
+type DateMigrationIssue struct {
+	gorm.Model
+	ID        int    `json:"id"`
+	TableName string `json:"table_name"`
+	RowID     int    `json:"row_id"`
+	Value     string `json:"value"`
+}
+
+// MigrationMark records when a one-off migration first ran, which tells rows
+// written before it from rows written after
+type MigrationMark struct {
+	gorm.Model
+	ID        int       `json:"id"`
+	Name      string    `json:"name" gorm:"size:64;uniqueIndex"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+// Audit records which users created and last changed a row, which differs
+// from its owner when a coach logs on an athlete's behalf
+type Audit struct {
+	CreatedBy int `json:"created_by"`
+	UpdatedBy int `json:"updated_by"`
+}
+
+// Goal types
+const (
+	GoalTypeCut      = "cut"
+	GoalTypeBulk     = "bulk"
+	GoalTypeMaintain = "maintain"
+)
+
+// Goal represents a target bodyweight to reach or hold by a date
+type Goal struct {
+	gorm.Model
+	ID           int     `json:"id"`
+	UserID       int     `json:"-" gorm:"index"`
+	Name         string  `json:"name"`
+	Type         string  `json:"type" gorm:"size:20"`
+	TargetWeight float64 `json:"target_weight"`
+	Unit         string  `json:"unit,omitempty" gorm:"-"`
+	TargetDate   string  `json:"target_date"`
+}
+
+// Movement categories
+const (
+	CategoryCompound  = "compound"
+	CategoryIsolation = "isolation"
+)
+
+// Muscle roles on a MovementMuscle
+const (
+	MuscleRolePrimary   = "primary"
+	MuscleRoleSecondary = "secondary"
+)
+
+// Movement is a canonical lift in the movement catalog
+type Movement struct {
+	gorm.Model
+	ID        int              `json:"id"`
+	Name      string           `json:"name"`
+	NameKey   string           `json:"-" gorm:"size:100;uniqueIndex"`
+	Equipment string           `json:"equipment"`
+	Category  string           `json:"category"`
+	Aliases   []MovementAlias  `json:"aliases" gorm:"foreignKey:MovementID"`
+	Muscles   []MovementMuscle `json:"muscles" gorm:"foreignKey:MovementID"`
+}
+
+// MovementAlias is an alternative name a Movement can be logged under. It is
+// stored normalized and travels over JSON as a plain string.
+type MovementAlias struct {
+	gorm.Model
+	ID         int    `json:"-"`
+	MovementID int    `json:"-"`
+	Alias      string `json:"alias" gorm:"size:100;uniqueIndex"`
+}
+
+// MarshalJSON encodes the alias as a plain string
+func (a MovementAlias) MarshalJSON() ([]byte, error) {
+	return json.Marshal(a.Alias)
+}
+
+// UnmarshalJSON decodes the alias from a plain string
+func (a *MovementAlias) UnmarshalJSON(data []byte) error {
+	return json.Unmarshal(data, &a.Alias)
+}
+
+// MovementMuscle maps a Movement to a muscle group it trains
+type MovementMuscle struct {
+	gorm.Model
+	ID          int    `json:"-"`
+	MovementID  int    `json:"-"`
+	MuscleGroup string `json:"muscle_group"`
+	Role        string `json:"role"`
+}
+
+// Workout represents a training session grouping the exercises done in it
+type Workout struct {
+	gorm.Model
+	ID         int        `json:"id"`
+	UserID     int        `json:"-" gorm:"index"`
+	Date       Date       `json:"date"`
+	OccurredAt *time.Time `json:"occurred_at"`
+	Name       string     `json:"name"`
+	StartTime  *time.Time `json:"start_time"`
+	EndTime    *time.Time `json:"end_time"`
+	Duration   int        `json:"duration_minutes"`
+	Bodyweight float64    `json:"bodyweight"`
+	Unit       string     `json:"unit,omitempty" gorm:"-"`
+	Notes      string     `json:"notes"`
+	Exercises  []Exercise `json:"exercises,omitempty" gorm:"foreignKey:WorkoutID"`
+
+	// Source names the app a session was imported from, and ExternalID
+	// identifies it there so importing the same export again skips it
+	Source     string `json:"source,omitempty" gorm:"size:16"`
+	ExternalID string `json:"-" gorm:"size:64;index"`
+}
+
+// CardioMetrics summarises a cardio session or one of its laps. Distances
+// and elevation are in metres, times in seconds and pace in seconds per
+// kilometre; metrics the file does not record are null.
+type CardioMetrics struct {
+	Distance      float64  `json:"distance_meters"`
+	ElapsedTime   float64  `json:"elapsed_seconds"`
+	MovingTime    float64  `json:"moving_seconds"`
+	Pace          *float64 `json:"pace_seconds_per_km"`
+	AvgHeartRate  *int     `json:"avg_heart_rate"`
+	MaxHeartRate  *int     `json:"max_heart_rate"`
+	ElevationGain *float64 `json:"elevation_gain_meters"`
+	Calories      *int     `json:"calories"`
+}
+
+// CardioSession is a run, ride, row or other cardio activity decoded from an
+// uploaded FIT or TCX file. DecoderVersion records the decoder that produced
+// it so sessions can be decoded again from their file when it improves.
+type CardioSession struct {
+	gorm.Model
+	CardioMetrics
+	ID             int         `json:"id"`
+	UserID         int         `json:"-" gorm:"index"`
+	FileID         int         `json:"file_id" gorm:"index"`
+	Date           Date        `json:"date"`
+	OccurredAt     *time.Time  `json:"occurred_at"`
+	Sport          string      `json:"sport"`
+	DecoderVersion int         `json:"-"`
+	Laps           []CardioLap `json:"laps,omitempty" gorm:"foreignKey:SessionID"`
+}
+
+// CardioLap is a lap split of a CardioSession
+type CardioLap struct {
+	gorm.Model
+	CardioMetrics
+	ID        int        `json:"-"`
+	SessionID int        `json:"-" gorm:"index"`
+	LapNumber int        `json:"lap"`
+	StartTime *time.Time `json:"start_time"`
+}
+
+// CardioFile is an uploaded FIT or TCX file, kept as sent
+type CardioFile struct {
+	gorm.Model
+	ID       int    `json:"id"`
+	UserID   int    `json:"-" gorm:"index"`
+	Filename string `json:"filename"`
+	Format   string `json:"format" gorm:"size:8"`
+	SHA256   string `json:"-" gorm:"size:64;index"`
+	Data     []byte `json:"-"`
 }
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"log"
+
+	"gorm.io/gorm"
+)
+
+// catalogEntry describes a seeded movement
+type catalogEntry struct {
+	name      string
+	equipment string
+	category  string
+	aliases   []string
+	primary   []string
+	secondary []string
+}
+
+// defaultMovements seeds the catalog with common lifts
+var defaultMovements = []catalogEntry{
+	{"Bench Press", "barbell", CategoryCompound, []string{"bench", "bb bench press", "barbell bench press", "flat bench", "flat bench press"}, []string{"chest"}, []string{"triceps", "shoulders"}},
+	{"Incline Bench Press", "barbell", CategoryCompound, []string{"incline bench", "incline barbell bench press"}, []string{"chest"}, []string{"shoulders", "triceps"}},
+	{"Dumbbell Bench Press", "dumbbell", CategoryCompound, []string{"db bench", "db bench press", "dumbbell bench"}, []string{"chest"}, []string{"triceps", "shoulders"}},
+	{"Overhead Press", "barbell", CategoryCompound, []string{"ohp", "military press", "standing press", "barbell overhead press", "shoulder press"}, []string{"shoulders"}, []string{"triceps"}},
+	{"Push-up", "bodyweight", CategoryCompound, []string{"push-ups", "pushup", "pushups", "push up", "push ups"}, []string{"chest"}, []string{"triceps", "shoulders"}},
+	{"Dip", "bodyweight", CategoryCompound, []string{"dips", "parallel bar dip"}, []string{"chest", "triceps"}, []string{"shoulders"}},
+	{"Back Squat", "barbell", CategoryCompound, []string{"squat", "squats", "bb squat", "barbell squat", "high bar squat", "low bar squat"}, []string{"quads", "glutes"}, []string{"hamstrings", "core"}},
+	{"Front Squat", "barbell", CategoryCompound, []string{"front squats"}, []string{"quads"}, []string{"glutes", "core"}},
+	{"Deadlift", "barbell", CategoryCompound, []string{"conventional deadlift", "deadlifts", "dl", "bb deadlift"}, []string{"hamstrings", "glutes", "back"}, []string{"quads", "traps", "forearms"}},
+	{"Romanian Deadlift", "barbell", CategoryCompound, []string{"rdl", "rdls", "romanian deadlifts"}, []string{"hamstrings", "glutes"}, []string{"back"}},
+	{"Leg Press", "machine", CategoryCompound, []string{"leg presses"}, []string{"quads"}, []string{"glutes"}},
+	{"Lunge", "dumbbell", CategoryCompound, []string{"lunges", "walking lunge", "walking lunges"}, []string{"quads", "glutes"}, []string{"hamstrings"}},
+	{"Hip Thrust", "barbell", CategoryCompound, []string{"hip thrusts", "barbell hip thrust"}, []string{"glutes"}, []string{"hamstrings"}},
+	{"Leg Extension", "machine", CategoryIsolation, []string{"leg extensions", "quad extension"}, []string{"quads"}, nil},
+	{"Leg Curl", "machine", CategoryIsolation, []string{"leg curls", "lying leg curl", "seated leg curl", "hamstring curl"}, []string{"hamstrings"}, nil},
+	{"Standing Calf Raise", "machine", CategoryIsolation, []string{"calf raise", "calf raises"}, []string{"calves"}, nil},
+	{"Pull-up", "bodyweight", CategoryCompound, []string{"pull-ups", "pullup", "pullups", "pull up", "pull ups"}, []string{"back"}, []string{"biceps"}},
+	{"Chin-up", "bodyweight", CategoryCompound, []string{"chin-ups", "chinup", "chinups", "chin up", "chin ups"}, []string{"back", "biceps"}, nil},
+	{"Lat Pulldown", "cable", CategoryCompound, []string{"pulldown", "lat pull-down", "cable pulldown"}, []string{"back"}, []string{"biceps"}},
+	{"Barbell Row", "barbell", CategoryCompound, []string{"bent over row", "bb row", "pendlay row", "barbell rows"}, []string{"back"}, []string{"biceps", "forearms"}},
+	{"Dumbbell Row", "dumbbell", CategoryCompound, []string{"db row", "one arm dumbbell row", "dumbbell rows"}, []string{"back"}, []string{"biceps"}},
+	{"Seated Cable Row", "cable", CategoryCompound, []string{"cable row", "seated row"}, []string{"back"}, []string{"biceps"}},
+	{"Barbell Curl", "barbell", CategoryIsolation, []string{"bb curl", "curl", "biceps curl", "bicep curl"}, []string{"biceps"}, []string{"forearms"}},
+	{"Dumbbell Curl", "dumbbell", CategoryIsolation, []string{"db curl", "dumbbell curls", "db curls"}, []string{"biceps"}, []string{"forearms"}},
+	{"Triceps Pushdown", "cable", CategoryIsolation, []string{"pushdown", "tricep pushdown", "cable pushdown"}, []string{"triceps"}, nil},
+	{"Skull Crusher", "barbell", CategoryIsolation, []string{"skull crushers", "skullcrusher", "lying triceps extension"}, []string{"triceps"}, nil},
+	{"Lateral Raise", "dumbbell", CategoryIsolation, []string{"lateral raises", "side raise", "db lateral raise"}, []string{"shoulders"}, nil},
+	{"Face Pull", "cable", CategoryIsolation, []string{"face pulls"}, []string{"shoulders"}, []string{"traps", "back"}},
+	{"Shrug", "barbell", CategoryIsolation, []string{"shrugs", "barbell shrug"}, []string{"traps"}, []string{"forearms"}},
+	{"Plank", "bodyweight", CategoryIsolation, []string{"planks"}, []string{"core"}, nil},
+	{"Hanging Leg Raise", "bodyweight", CategoryIsolation, []string{"hanging leg raises", "leg raise"}, []string{"core"}, nil},
+	{"Sit-up", "bodyweight", CategoryIsolation, []string{"sit-ups", "situp", "situps", "sit up", "sit ups"}, []string{"core"}, nil},
+}
+
+// seedMovements inserts any default movements missing from the catalog
+func seedMovements(tx *gorm.DB) error {
+	for _, entry := range defaultMovements {
+		var count int64
+		key := normalizeMovementName(entry.name)
+		if err := tx.Model(&Movement{}).Where("name_key = ?", key).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			continue
+		}
+
+		movement := Movement{Name: entry.name, Equipment: entry.equipment, Category: entry.category}
+		for _, alias := range entry.aliases {
+			movement.Aliases = append(movement.Aliases, MovementAlias{Alias: alias})
+		}
+		for _, muscle := range entry.primary {
+			movement.Muscles = append(movement.Muscles, MovementMuscle{MuscleGroup: muscle, Role: MuscleRolePrimary})
+		}
+		for _, muscle := range entry.secondary {
+			movement.Muscles = append(movement.Muscles, MovementMuscle{MuscleGroup: muscle, Role: MuscleRoleSecondary})
+		}
+		prepareMovement(&movement)
+		if err := tx.Create(&movement).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// linkExerciseMovements resolves the movement of exercises logged before the
+// catalog existed and records the matching catalog entry
+func linkExerciseMovements(tx *gorm.DB) error {
+	var names []string
+	if err := tx.Model(&Exercise{}).Where("movement_id IS NULL").Distinct().Pluck("movement", &names).Error; err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		movement, err := resolveMovement(tx, name)
+		switch {
+		case err != nil:
+			return err
+		case movement == nil:
+			log.Printf("No catalog movement for %q", name)
+			continue
+		}
+		if err := tx.Model(&Exercise{}).Where("movement_id IS NULL AND movement = ?", name).
+			Update("movement_id", movement.ID).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Warning attached to an exercise whose movement is not in the catalog
+const WarningUnknownMovement = "unknown_movement"
+
+// normalizeMovementName lowercases a movement name and collapses punctuation
+// and whitespace so "BB Bench-Press" and "bb bench press" compare equal
+func normalizeMovementName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	return strings.Join(fields, " ")
+}
+
+// preloadMovementDetails loads a movement's aliases and muscle groups
+func preloadMovementDetails(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Aliases", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("alias ASC")
+	}).Preload("Muscles", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("role ASC, id ASC")
+	})
+}
+
+// prepareMovement normalizes a movement's name, aliases and muscle groups and
+// reports whether it is valid
+func prepareMovement(movement *Movement) bool {
+	movement.Name = strings.TrimSpace(movement.Name)
+	movement.NameKey = normalizeMovementName(movement.Name)
+	movement.Category = strings.ToLower(strings.TrimSpace(movement.Category))
+	movement.Equipment = strings.ToLower(strings.TrimSpace(movement.Equipment))
+
+	switch {
+	case movement.NameKey == "":
+		return false
+	case movement.Category != "" && movement.Category != CategoryCompound && movement.Category != CategoryIsolation:
+		return false
+	}
+
+	// Aliases and muscles are always rewritten as new rows for their movement
+	seen := map[string]bool{movement.NameKey: true}
+	aliases := movement.Aliases[:0]
+	for _, alias := range movement.Aliases {
+		key := normalizeMovementName(alias.Alias)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		aliases = append(aliases, MovementAlias{MovementID: movement.ID, Alias: key})
+	}
+	movement.Aliases = aliases
+
+	for i := range movement.Muscles {
+		muscle := &movement.Muscles[i]
+		muscle.Model = gorm.Model{}
+		muscle.ID = 0
+		muscle.MovementID = movement.ID
+		muscle.MuscleGroup = strings.ToLower(strings.TrimSpace(muscle.MuscleGroup))
+		if muscle.Role == "" {
+			muscle.Role = MuscleRolePrimary
+		}
+		if muscle.MuscleGroup == "" || (muscle.Role != MuscleRolePrimary && muscle.Role != MuscleRoleSecondary) {
+			return false
+		}
+	}
+	return true
+}
+
+// movementNameTaken reports whether another movement already uses the name
+// or any alias of movement
+func movementNameTaken(movement *Movement) (bool, error) {
+	keys := []string{movement.NameKey}
+	for _, alias := range movement.Aliases {
+		keys = append(keys, alias.Alias)
+	}
+
+	var count int64
+	if err := db.Model(&Movement{}).Where("name_key IN ? AND id <> ?", keys, movement.ID).Count(&count).Error; err != nil || count > 0 {
+		return count > 0, err
+	}
+	err := db.Model(&MovementAlias{}).Where("alias IN ? AND movement_id <> ?", keys, movement.ID).Count(&count).Error
+	return count > 0, err
+}
+
+// resolveMovement finds the catalog movement matching name or one of its
+// aliases, returning nil when there is none
+func resolveMovement(tx *gorm.DB, name string) (*Movement, error) {
+	key := normalizeMovementName(name)
+	if key == "" {
+		return nil, nil
+	}
+
+	// Find with a limit avoids logging every unknown name as a missing record
+	var movement Movement
+	result := tx.Where("name_key = ?", key).Limit(1).Find(&movement)
+	switch {
+	case result.Error != nil:
+		return nil, result.Error
+	case result.RowsAffected > 0:
+		return &movement, nil
+	}
+
+	var alias MovementAlias
+	result = tx.Where("alias = ?", key).Limit(1).Find(&alias)
+	switch {
+	case result.Error != nil:
+		return nil, result.Error
+	case result.RowsAffected == 0:
+		return nil, nil
+	}
+	if err := tx.First(&movement, alias.MovementID).Error; err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// findMovement looks up a movement with its details by numeric ID or by name
+// or alias
+func findMovement(key string) (*Movement, error) {
+	id, err := strconv.Atoi(key)
+	if err != nil {
+		movement, err := resolveMovement(db, key)
+		if err != nil || movement == nil {
+			return nil, gorm.ErrRecordNotFound
+		}
+		id = movement.ID
+	}
+
+	var movement Movement
+	if err := preloadMovementDetails(db).First(&movement, id).Error; err != nil {
+		return nil, err
+	}
+	return &movement, nil
+}
+
+// resolveExerciseMovement links an exercise to its catalog movement. Unknown
+// movements are accepted, flagged with a warning or rejected depending on
+// MOVEMENT_STRICTNESS; it reports false when the exercise must be rejected.
+func resolveExerciseMovement(exercise *Exercise) bool {
+	movement, err := resolveMovement(db, exercise.Movement)
+	if err != nil {
+		log.Println("Movement lookup failed:", err)
+	}
+
+	exercise.MovementID = nil
+	switch {
+	case movement != nil:
+		exercise.MovementID = &movement.ID
+	case movementStrictness() == StrictnessReject:
+		return false
+	case movementStrictness() == StrictnessFlag:
+		exercise.Warnings = append(exercise.Warnings, WarningUnknownMovement)
+	}
+	return true
+}
+
+// saveMovement updates a movement and replaces its aliases and muscle groups
+// in one transaction
+func saveMovement(movement *Movement) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("movement_id = ?", movement.ID).Delete(&MovementAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("movement_id = ?", movement.ID).Delete(&MovementMuscle{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Aliases", "Muscles").Save(movement).Error; err != nil {
+			return err
+		}
+		if len(movement.Aliases) > 0 {
+			if err := tx.Create(&movement.Aliases).Error; err != nil {
+				return err
+			}
+		}
+		if len(movement.Muscles) > 0 {
+			return tx.Create(&movement.Muscles).Error
+		}
+		return nil
+	})
+}
+
+// createMovement handles POST /movements
+func createMovement(c *gin.Context) {
+	var movement Movement
+
+	switch {
+	case c.ShouldBindJSON(&movement) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !prepareMovement(&movement):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	}
+
+	taken, err := movementNameTaken(&movement)
+	switch {
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create movement"})
+	case taken:
+		c.JSON(http.StatusConflict, gin.H{"error": "Movement name or alias already exists"})
+	case db.Create(&movement).Error != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create movement"})
+	default:
+		c.JSON(http.StatusCreated, movement)
+	}
+}
+
+// getMovements handles GET /movements
+func getMovements(c *gin.Context) {
+	var movements []Movement
+	switch err := preloadMovementDetails(db).Order("name ASC").Find(&movements).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, movements)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch movements"})
+	}
+}
+
+// getMovement handles GET /movements/:name, where name may also be an ID or
+// an alias
+func getMovement(c *gin.Context) {
+	switch movement, err := findMovement(c.Param("name")); err {
+	case nil:
+		c.JSON(http.StatusOK, movement)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movement not found"})
+	}
+}
+
+// updateMovement handles PUT /movements/:name
+func updateMovement(c *gin.Context) {
+	movement, err := findMovement(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movement not found"})
+		return
+	}
+
+	switch {
+	case bindUpdate(c, &movement.ID, movement) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !prepareMovement(movement):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	}
+
+	taken, err := movementNameTaken(movement)
+	switch {
+	case err != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update movement"})
+	case taken:
+		c.JSON(http.StatusConflict, gin.H{"error": "Movement name or alias already exists"})
+	case saveMovement(movement) != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update movement"})
+	default:
+		c.JSON(http.StatusOK, movement)
+	}
+}
+
+// deleteMovement handles DELETE /movements/:name, unlinking any exercises
+// logged against it
+func deleteMovement(c *gin.Context) {
+	movement, err := findMovement(c.Param("name"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Movement not found"})
+		return
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Exercise{}).Where("movement_id = ?", movement.ID).Update("movement_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("movement_id = ?", movement.ID).Delete(&MovementAlias{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Where("movement_id = ?", movement.ID).Delete(&MovementMuscle{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&Movement{}, movement.ID).Error
+	})
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Movement deleted successfully"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete movement"})
+	}
+}
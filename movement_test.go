@@ -0,0 +1,172 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolveMovement_AliasesAndCase(t *testing.T) {
+	db = setupTestDB()
+
+	for _, name := range []string{"Bench", "bench press", "BB Bench Press", "  bench-press "} {
+		movement, err := resolveMovement(db, name)
+		assert.NoError(t, err)
+		if assert.NotNil(t, movement, name) {
+			assert.Equal(t, "Bench Press", movement.Name)
+		}
+	}
+
+	movement, err := resolveMovement(db, "Zercher Carry")
+	assert.NoError(t, err)
+	assert.Nil(t, movement)
+}
+
+func TestCreateMovement_Success(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{
+		"name": "Zercher Squat",
+		"equipment": "Barbell",
+		"category": "compound",
+		"aliases": ["zercher", "Zercher Squats"],
+		"muscles": [{"muscle_group": "Quads"}, {"muscle_group": "core", "role": "secondary"}]
+	}`)
+	req, _ := http.NewRequest("POST", "/movements", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var movement Movement
+	err := json.Unmarshal(w.Body.Bytes(), &movement)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, movement.ID)
+	assert.Len(t, movement.Aliases, 2)
+	assert.Equal(t, "zercher", movement.Aliases[0].Alias)
+	assert.Equal(t, "zercher squats", movement.Aliases[1].Alias)
+	assert.Equal(t, MuscleRolePrimary, movement.Muscles[0].Role)
+	assert.Equal(t, "quads", movement.Muscles[0].MuscleGroup)
+
+	resolved, _ := resolveMovement(db, "ZERCHER")
+	assert.Equal(t, movement.ID, resolved.ID)
+}
+
+func TestCreateMovement_AliasConflict(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"name": "Paused Bench", "aliases": ["bench"]}`)
+	req, _ := http.NewRequest("POST", "/movements", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestGetMovement_ByAlias(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/movements/ohp", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var movement Movement
+	err := json.Unmarshal(w.Body.Bytes(), &movement)
+	assert.NoError(t, err)
+	assert.Equal(t, "Overhead Press", movement.Name)
+	assert.NotEmpty(t, movement.Muscles)
+}
+
+func TestCreateExercise_ResolvesMovement(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"date": "2023-10-01", "movement": "BB Bench Press", "sets": 3, "reps": 5, "weight": 100}`)
+	req, _ := http.NewRequest("POST", "/exercises", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var m Exercise
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	bench, _ := resolveMovement(db, "bench press")
+	assert.Equal(t, bench.ID, *m.MovementID)
+	assert.Empty(t, m.Warnings)
+}
+
+func TestCreateExercise_UnknownMovementStrictness(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"date": "2023-10-01", "movement": "Atlas Stone", "sets": 1, "reps": 3, "weight": 120}`)
+
+	req, _ := http.NewRequest("POST", "/exercises", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var m Exercise
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	assert.Nil(t, m.MovementID)
+	assert.Equal(t, []string{WarningUnknownMovement}, m.Warnings)
+
+	t.Setenv("MOVEMENT_STRICTNESS", StrictnessReject)
+
+	req, _ = http.NewRequest("POST", "/exercises", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]string
+	err = json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Unknown movement", response["error"])
+}
+
+func TestDeleteMovement_UnlinksExercises(t *testing.T) {
+	db = setupTestDB()
+
+	shrug, _ := resolveMovement(db, "shrug")
+	exercise := Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Shrugs", MovementID: &shrug.ID, Sets: 1, Reps: 10, Weight: 60}
+	db.Create(&exercise)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/movements/%d", shrug.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated Exercise
+	db.First(&updated, exercise.ID)
+	assert.Nil(t, updated.MovementID)
+	resolved, _ := resolveMovement(db, "shrugs")
+	assert.Nil(t, resolved)
+}
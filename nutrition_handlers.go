@@ -0,0 +1,126 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MacroTotals is a set of carbs, protein, fat and calorie amounts
+type MacroTotals struct {
+	Carbs    int `json:"carbs"`
+	Protein  int `json:"protein"`
+	Fats     int `json:"fat"`
+	Calories int `json:"calories"`
+}
+
+// DailyNutrition is the nutrition logged on one day compared to its target
+type DailyNutrition struct {
+	Date        string       `json:"date"`
+	TrainingDay bool         `json:"training_day"`
+	Totals      MacroTotals  `json:"totals"`
+	Target      *MacroTotals `json:"target"`
+	Delta       *MacroTotals `json:"delta"`
+}
+
+// targetsByDayType loads the user's macro targets keyed by day type
+func targetsByDayType(userID int) (map[string]MacroTotals, error) {
+	var targets []MacroTarget
+	if err := db.Where("user_id = ?", userID).Find(&targets).Error; err != nil {
+		return nil, err
+	}
+
+	byDayType := map[string]MacroTotals{}
+	for _, target := range targets {
+		byDayType[target.DayType] = MacroTotals{Carbs: target.Carbs, Protein: target.Protein, Fats: target.Fats, Calories: target.Calories}
+	}
+	return byDayType, nil
+}
+
+// targetForDay picks the training or rest day target, falling back to the
+// default target when no specific one is configured
+func targetForDay(targets map[string]MacroTotals, trainingDay bool) (MacroTotals, bool) {
+	dayType := DayTypeRest
+	if trainingDay {
+		dayType = DayTypeTraining
+	}
+	if target, ok := targets[dayType]; ok {
+		return target, true
+	}
+	target, ok := targets[DayTypeDefault]
+	return target, ok
+}
+
+// buildDailyNutrition sums each day's meals between from and to and compares
+// them to the macro target for that day for the user
+func buildDailyNutrition(userID int, from, to string) ([]DailyNutrition, error) {
+	var rows []struct {
+		Date Date
+		MacroTotals
+	}
+	query := db.Model(&Meal{}).Where("user_id = ?", userID).
+		Select("date, SUM(carbs) AS carbs, SUM(protein) AS protein, SUM(fats) AS fats, SUM(calories) AS calories").
+		Group("date").Order("date ASC")
+	exercises := db.Model(&Exercise{}).Where("user_id = ?", userID)
+	if from != "" {
+		query = query.Where("date >= ?", from)
+		exercises = exercises.Where("date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("date <= ?", to)
+		exercises = exercises.Where("date <= ?", to)
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	// Days with any logged exercise count as training days
+	var trainingDates []Date
+	if err := exercises.Distinct().Pluck("date", &trainingDates).Error; err != nil {
+		return nil, err
+	}
+	training := map[Date]bool{}
+	for _, date := range trainingDates {
+		training[date] = true
+	}
+
+	targets, err := targetsByDayType(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	days := []DailyNutrition{}
+	for _, row := range rows {
+		day := DailyNutrition{Date: string(row.Date), TrainingDay: training[row.Date], Totals: row.MacroTotals}
+		if target, ok := targetForDay(targets, day.TrainingDay); ok {
+			day.Target = &target
+			day.Delta = &MacroTotals{
+				Carbs:    row.Carbs - target.Carbs,
+				Protein:  row.Protein - target.Protein,
+				Fats:     row.Fats - target.Fats,
+				Calories: row.Calories - target.Calories,
+			}
+		}
+		days = append(days, day)
+	}
+	return days, nil
+}
+
+// getDailyNutrition handles GET /nutrition/daily
+func getDailyNutrition(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+		return
+	}
+
+	days, err := buildDailyNutrition(currentUserID(c), from, to)
+	switch {
+	case err != nil:
+		log.Println("DB Query Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch daily nutrition"})
+	default:
+		c.JSON(http.StatusOK, days)
+	}
+}
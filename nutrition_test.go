@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGetDailyNutrition_TotalsAndTargets(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Meal{UserID: testUserID, Date: "2023-10-01", Name: "Breakfast", Carbs: 60, Protein: 30, Fats: 20, Calories: 540})
+	db.Create(&Meal{UserID: testUserID, Date: "2023-10-01", Name: "Dinner", Carbs: 100, Protein: 50, Fats: 30, Calories: 870})
+	db.Create(&Meal{UserID: testUserID, Date: "2023-10-02", Name: "Lunch", Carbs: 80, Protein: 40, Fats: 10, Calories: 570})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-02", Movement: "Squat", Sets: 3, Reps: 5, Weight: 140})
+	db.Create(&MacroTarget{UserID: testUserID, DayType: DayTypeDefault, Carbs: 200, Protein: 150, Fats: 60, Calories: 1940})
+	db.Create(&MacroTarget{UserID: testUserID, DayType: DayTypeTraining, Carbs: 300, Protein: 150, Fats: 60, Calories: 2340})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/nutrition/daily?from=2023-10-01&to=2023-10-07", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var days []DailyNutrition
+	err := json.Unmarshal(w.Body.Bytes(), &days)
+	assert.NoError(t, err)
+	assert.Len(t, days, 2)
+
+	rest := days[0]
+	assert.Equal(t, "2023-10-01", rest.Date)
+	assert.False(t, rest.TrainingDay)
+	assert.Equal(t, MacroTotals{Carbs: 160, Protein: 80, Fats: 50, Calories: 1410}, rest.Totals)
+	assert.Equal(t, 200, rest.Target.Carbs)
+	assert.Equal(t, -40, rest.Delta.Carbs)
+	assert.Equal(t, -530, rest.Delta.Calories)
+
+	training := days[1]
+	assert.True(t, training.TrainingDay)
+	assert.Equal(t, 300, training.Target.Carbs)
+	assert.Equal(t, -220, training.Delta.Carbs)
+}
+
+func TestGetDailyNutrition_NoTargets(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Meal{UserID: testUserID, Date: "2023-10-01", Name: "Breakfast", Carbs: 60, Protein: 30, Fats: 20, Calories: 540})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/nutrition/daily", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var days []DailyNutrition
+	err := json.Unmarshal(w.Body.Bytes(), &days)
+	assert.NoError(t, err)
+	assert.Len(t, days, 1)
+	assert.Nil(t, days[0].Target)
+	assert.Nil(t, days[0].Delta)
+}
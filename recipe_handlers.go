@@ -0,0 +1,168 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// preloadIngredients loads a recipe's ingredients in the order they were added
+func preloadIngredients(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Ingredients", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("id ASC")
+	})
+}
+
+// prepareRecipe computes a recipe's per-serving nutrition from its
+// ingredients' foods and reports whether the recipe is valid
+func prepareRecipe(recipe *Recipe) bool {
+	recipe.Name = strings.TrimSpace(recipe.Name)
+	if recipe.Name == "" || recipe.Servings <= 0 || len(recipe.Ingredients) == 0 {
+		return false
+	}
+
+	var grams, carbs, protein, fats, calories float64
+	for i := range recipe.Ingredients {
+		ingredient := &recipe.Ingredients[i]
+		ingredient.Model = gorm.Model{}
+		ingredient.ID = 0
+		ingredient.RecipeID = recipe.ID
+
+		var food Food
+		switch {
+		case ingredient.Grams <= 0:
+			return false
+		case db.Where("id = ?", ingredient.FoodID).Limit(1).Find(&food).RowsAffected == 0:
+			return false
+		}
+
+		scale := ingredient.Grams / 100
+		grams += ingredient.Grams
+		carbs += food.Carbs * scale
+		protein += food.Protein * scale
+		fats += food.Fats * scale
+		calories += food.Calories * scale
+	}
+
+	recipe.Grams = roundTo(grams/recipe.Servings, 1)
+	recipe.Carbs = roundTo(carbs/recipe.Servings, 1)
+	recipe.Protein = roundTo(protein/recipe.Servings, 1)
+	recipe.Fats = roundTo(fats/recipe.Servings, 1)
+	recipe.Calories = roundTo(calories/recipe.Servings, 1)
+	return true
+}
+
+// applyRecipeItem computes a recipe item's nutrition from the current
+// per-serving values of one of the user's recipes
+func applyRecipeItem(item *MealItem, userID int) bool {
+	var recipe Recipe
+	if item.Servings <= 0 || db.Where("id = ? AND user_id = ?", *item.RecipeID, userID).Limit(1).Find(&recipe).RowsAffected == 0 {
+		return false
+	}
+
+	item.Grams = roundTo(recipe.Grams*item.Servings, 1)
+	item.Carbs = roundTo(recipe.Carbs*item.Servings, 1)
+	item.Protein = roundTo(recipe.Protein*item.Servings, 1)
+	item.Fats = roundTo(recipe.Fats*item.Servings, 1)
+	item.Calories = roundTo(recipe.Calories*item.Servings, 1)
+	return true
+}
+
+// saveRecipe updates a recipe and replaces its ingredients in one transaction.
+// Meals already logged from the recipe keep their own nutrition.
+func saveRecipe(recipe *Recipe) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("recipe_id = ?", recipe.ID).Delete(&RecipeIngredient{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Omit("Ingredients").Save(recipe).Error; err != nil {
+			return err
+		}
+		return tx.Create(&recipe.Ingredients).Error
+	})
+}
+
+// createRecipe handles POST /recipes
+func createRecipe(c *gin.Context) {
+	recipe := Recipe{UserID: currentUserID(c)}
+
+	switch {
+	case c.ShouldBindJSON(&recipe) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !prepareRecipe(&recipe):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case db.Create(&recipe).Error != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recipe"})
+		return
+	default:
+		c.JSON(http.StatusCreated, recipe)
+	}
+}
+
+// getRecipes handles GET /recipes
+func getRecipes(c *gin.Context) {
+	var recipes []Recipe
+	switch err := preloadIngredients(userScope(c)).Order("name ASC").Find(&recipes).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, recipes)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch recipes"})
+	}
+}
+
+// getRecipe handles GET /recipes/:id
+func getRecipe(c *gin.Context) {
+	id := c.Param("id")
+	var recipe Recipe
+	switch err := preloadIngredients(userScope(c)).First(&recipe, id).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, recipe)
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+	}
+}
+
+// updateRecipe handles PUT /recipes/:id
+func updateRecipe(c *gin.Context) {
+	id := c.Param("id")
+	var recipe Recipe
+
+	switch {
+	case preloadIngredients(userScope(c)).First(&recipe, id).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+		return
+	case bindUpdate(c, &recipe.ID, &recipe) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !prepareRecipe(&recipe):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case saveRecipe(&recipe) != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update recipe"})
+		return
+	default:
+		c.JSON(http.StatusOK, recipe)
+	}
+}
+
+// deleteRecipe handles DELETE /recipes/:id
+func deleteRecipe(c *gin.Context) {
+	id := c.Param("id")
+
+	result := userScope(c).Where("id = ?", id).Delete(&Recipe{})
+	switch {
+	case result.Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete recipe"})
+	case result.RowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipe not found"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Recipe deleted successfully"})
+	}
+}
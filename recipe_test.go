@@ -0,0 +1,174 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestRecipe stores a four serving chili built from two foods
+func createTestRecipe() Recipe {
+	beef := Food{Name: "Ground Beef 90/10", Protein: 20, Fats: 10, Calories: 176}
+	beans := Food{Name: "Kidney Beans", Carbs: 23, Protein: 9, Fats: 0.5, Calories: 127}
+	db.Create(&beef)
+	db.Create(&beans)
+
+	recipe := Recipe{UserID: testUserID, Name: "Chili", Servings: 4, Ingredients: []RecipeIngredient{
+		{FoodID: beef.ID, Grams: 800},
+		{FoodID: beans.ID, Grams: 400},
+	}}
+	prepareRecipe(&recipe)
+	db.Create(&recipe)
+	return recipe
+}
+
+func TestCreateRecipe_ComputesPerServing(t *testing.T) {
+	db = setupTestDB()
+
+	beef := Food{Name: "Ground Beef 90/10", Protein: 20, Fats: 10, Calories: 176}
+	beans := Food{Name: "Kidney Beans", Carbs: 23, Protein: 9, Fats: 0.5, Calories: 127}
+	db.Create(&beef)
+	db.Create(&beans)
+
+	r := setupRouter()
+
+	reqBody := []byte(fmt.Sprintf(`{"name": "Chili", "servings": 4, "ingredients": [
+		{"food_id": %d, "grams": 800},
+		{"food_id": %d, "grams": 400}
+	]}`, beef.ID, beans.ID))
+	req, _ := http.NewRequest("POST", "/recipes", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var recipe Recipe
+	err := json.Unmarshal(w.Body.Bytes(), &recipe)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, recipe.ID)
+	assert.Equal(t, 300.0, recipe.Grams)
+	assert.Equal(t, 23.0, recipe.Carbs)
+	assert.Equal(t, 49.0, recipe.Protein)
+	assert.Equal(t, 20.5, recipe.Fats)
+	assert.Equal(t, 479.0, recipe.Calories)
+}
+
+func TestCreateRecipe_UnknownFood(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"name": "Chili", "servings": 4, "ingredients": [{"food_id": 999, "grams": 800}]}`)
+	req, _ := http.NewRequest("POST", "/recipes", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateMeal_FromRecipeServings(t *testing.T) {
+	db = setupTestDB()
+
+	recipe := createTestRecipe()
+
+	r := setupRouter()
+
+	reqBody := []byte(fmt.Sprintf(`{"date": "2023-10-01", "name": "Dinner", "items": [{"recipe_id": %d, "servings": 1.5}]}`, recipe.ID))
+	req, _ := http.NewRequest("POST", "/meals", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var m Meal
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	assert.Equal(t, 450.0, m.Items[0].Grams)
+	assert.Equal(t, 35, m.Carbs)
+	assert.Equal(t, 74, m.Protein)
+	assert.Equal(t, 31, m.Fats)
+	assert.Equal(t, 719, m.Calories)
+}
+
+func TestUpdateRecipe_KeepsLoggedMeals(t *testing.T) {
+	db = setupTestDB()
+
+	recipe := createTestRecipe()
+	meal := Meal{UserID: testUserID, Date: "2023-10-01", Name: "Dinner", Items: []MealItem{{RecipeID: &recipe.ID, Servings: 1}}}
+	prepareMealItems(&meal)
+	db.Create(&meal)
+
+	r := setupRouter()
+
+	// Doubling the yield halves the per-serving nutrition
+	reqBody := []byte(`{"servings": 8}`)
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/recipes/%d", recipe.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated Recipe
+	err := json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.NoError(t, err)
+	assert.Equal(t, 239.5, updated.Calories)
+	assert.Len(t, updated.Ingredients, 2)
+
+	var stored Meal
+	db.Preload("Items").First(&stored, meal.ID)
+	assert.Equal(t, 479, stored.Calories)
+	assert.Equal(t, 479.0, stored.Items[0].Calories)
+}
+
+func TestDeleteRecipe_Success(t *testing.T) {
+	db = setupTestDB()
+
+	recipe := createTestRecipe()
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/recipes/%d", recipe.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Recipe deleted successfully", response["message"])
+}
+
+func TestRecipes_BelongToTheirOwner(t *testing.T) {
+	db = setupTestDB()
+
+	other := User{Email: "other@example.com"}
+	db.Create(&other)
+	recipe := createTestRecipe()
+	db.Model(&recipe).Update("user_id", other.ID)
+
+	r := setupRouter()
+
+	w := sendJSON(r, "GET", fmt.Sprintf("/recipes/%d", recipe.ID), nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	w = sendJSON(r, "GET", "/recipes", nil, "")
+	assert.Equal(t, "[]", w.Body.String())
+	w = sendJSON(r, "DELETE", fmt.Sprintf("/recipes/%d", recipe.ID), nil, "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Meals can only be logged from the user's own recipes
+	w = sendJSON(r, "POST", "/meals", map[string]interface{}{"date": "2023-10-01", "name": "Dinner", "items": []map[string]interface{}{{"recipe_id": recipe.ID, "servings": 1}}}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Formulas accepted for estimating a one-rep max
+const (
+	FormulaEpley    = "epley"
+	FormulaBrzycki  = "brzycki"
+	FormulaLombardi = "lombardi"
+)
+
+// maxRecordReps is the highest rep count tracked as a rep max
+const maxRecordReps = 12
+
+// estimateOneRepMax estimates a one-rep max from a set of reps at load using
+// the named formula
+func estimateOneRepMax(formula string, load float64, reps int) float64 {
+	switch {
+	case reps <= 0:
+		return 0
+	case reps == 1:
+		return load
+	}
+
+	switch formula {
+	case FormulaBrzycki:
+		// Brzycki breaks down past 36 reps, fall back to Epley there
+		if reps < 37 {
+			return load * 36 / float64(37-reps)
+		}
+	case FormulaLombardi:
+		return load * math.Pow(float64(reps), 0.10)
+	}
+	return load * (1 + float64(reps)/30)
+}
+
+// validFormula reports whether formula names a supported e1RM formula
+func validFormula(formula string) bool {
+	return formula == FormulaEpley || formula == FormulaBrzycki || formula == FormulaLombardi
+}
+
+// roundTo rounds v to the given number of decimal places
+func roundTo(v float64, places int) float64 {
+	scale := math.Pow(10, float64(places))
+	return math.Round(v*scale) / scale
+}
+
+// movementSet is a single logged set joined with its exercise
+type movementSet struct {
+	ExerciseID int
+	Date       Date
+	Reps       int
+	Load       float64
+}
+
+// RecordEntry is a best performance and when it was achieved
+type RecordEntry struct {
+	Value      float64 `json:"value"`
+	Weight     float64 `json:"weight,omitempty"`
+	Reps       int     `json:"reps,omitempty"`
+	Date       string  `json:"date"`
+	ExerciseID int     `json:"exercise_id"`
+}
+
+// MovementRecords summarises the personal records for one movement
+type MovementRecords struct {
+	Movement   string              `json:"movement"`
+	Formula    string              `json:"formula"`
+	BestE1RM   *RecordEntry        `json:"best_e1rm"`
+	RepMaxes   map[int]RecordEntry `json:"rep_maxes"`
+	BestVolume *RecordEntry        `json:"best_volume"`
+	Unit       string              `json:"unit"`
+}
+
+// loadMovementSets fetches every non-warmup set the user logged for a
+// movement, oldest first, skipping the exercise with excludeID. Exercises match on
+// their catalog movement when movementID is known and on name otherwise.
+func loadMovementSets(userID int, movement string, movementID *int, excludeID int) ([]movementSet, error) {
+	var sets []movementSet
+	match := db.Where("LOWER(exercises.movement) = LOWER(?)", movement)
+	if movementID != nil {
+		match = match.Or("exercises.movement_id = ?", *movementID)
+	}
+	err := db.Table("exercise_sets").
+		Select("exercise_sets.exercise_id, exercises.date, exercise_sets.reps, exercise_sets.load").
+		Joins("JOIN exercises ON exercises.id = exercise_sets.exercise_id").
+		Where("exercise_sets.deleted_at IS NULL AND exercises.deleted_at IS NULL").
+		Where("exercises.user_id = ?", userID).
+		Where(match).
+		Where("exercise_sets.set_type <> ?", SetTypeWarmup).
+		Where("exercises.id <> ?", excludeID).
+		Order("exercises.date ASC, exercises.id ASC, exercise_sets.set_number ASC").
+		Scan(&sets).Error
+	return sets, err
+}
+
+// computeRecords finds the best e1RM, the heaviest load at each rep count up
+// to maxRecordReps and the highest volume day from a movement's sets. Ties
+// keep the earliest date.
+func computeRecords(movement, formula string, sets []movementSet) MovementRecords {
+	records := MovementRecords{Movement: movement, Formula: formula, RepMaxes: map[int]RecordEntry{}}
+	volumeByDate := map[Date]*RecordEntry{}
+	var dates []Date
+
+	for _, set := range sets {
+		e1rm := roundTo(estimateOneRepMax(formula, set.Load, set.Reps), 2)
+		if records.BestE1RM == nil || e1rm > records.BestE1RM.Value {
+			records.BestE1RM = &RecordEntry{Value: e1rm, Weight: set.Load, Reps: set.Reps, Date: string(set.Date), ExerciseID: set.ExerciseID}
+		}
+
+		if set.Reps <= maxRecordReps {
+			if best, ok := records.RepMaxes[set.Reps]; !ok || set.Load > best.Value {
+				records.RepMaxes[set.Reps] = RecordEntry{Value: set.Load, Reps: set.Reps, Date: string(set.Date), ExerciseID: set.ExerciseID}
+			}
+		}
+
+		volume, ok := volumeByDate[set.Date]
+		if !ok {
+			volume = &RecordEntry{Date: string(set.Date), ExerciseID: set.ExerciseID}
+			volumeByDate[set.Date] = volume
+			dates = append(dates, set.Date)
+		}
+		volume.Value += set.Load * float64(set.Reps)
+	}
+
+	for _, date := range dates {
+		if records.BestVolume == nil || volumeByDate[date].Value > records.BestVolume.Value {
+			records.BestVolume = volumeByDate[date]
+		}
+	}
+	return records
+}
+
+// PersonalRecord describes a record set by a newly logged exercise
+type PersonalRecord struct {
+	Type     string  `json:"type"`
+	Reps     int     `json:"reps,omitempty"`
+	Value    float64 `json:"value"`
+	Previous float64 `json:"previous"`
+}
+
+// Personal record types reported on a created exercise
+const (
+	RecordE1RM   = "e1rm"
+	RecordRepMax = "rep_max"
+	RecordVolume = "volume"
+)
+
+// detectPersonalRecords compares a newly created exercise against the
+// movement's history. The first time a movement is logged sets no records.
+func detectPersonalRecords(exercise *Exercise, formula string) ([]PersonalRecord, error) {
+	history, err := loadMovementSets(exercise.UserID, exercise.Movement, exercise.MovementID, exercise.ID)
+	if err != nil || len(history) == 0 {
+		return nil, err
+	}
+	before := computeRecords(exercise.Movement, formula, history)
+
+	var current []movementSet
+	for _, set := range exercise.SetDetails {
+		if set.SetType != SetTypeWarmup {
+			current = append(current, movementSet{ExerciseID: exercise.ID, Date: exercise.Date, Reps: set.Reps, Load: set.Load})
+		}
+	}
+	if len(current) == 0 {
+		return nil, nil
+	}
+	// Include earlier entries from the same day so volume is per day
+	var sameDay []movementSet
+	for _, set := range history {
+		if set.Date == exercise.Date {
+			sameDay = append(sameDay, set)
+		}
+	}
+	after := computeRecords(exercise.Movement, formula, append(sameDay, current...))
+
+	var prs []PersonalRecord
+	if before.BestE1RM != nil && after.BestE1RM.Value > before.BestE1RM.Value {
+		prs = append(prs, PersonalRecord{Type: RecordE1RM, Value: after.BestE1RM.Value, Previous: before.BestE1RM.Value})
+	}
+	for reps := 1; reps <= maxRecordReps; reps++ {
+		best, ok := after.RepMaxes[reps]
+		if !ok || best.ExerciseID != exercise.ID {
+			continue
+		}
+		if previous, ok := before.RepMaxes[reps]; ok && best.Value > previous.Value {
+			prs = append(prs, PersonalRecord{Type: RecordRepMax, Reps: reps, Value: best.Value, Previous: previous.Value})
+		}
+	}
+	if before.BestVolume != nil && after.BestVolume.Value > before.BestVolume.Value {
+		prs = append(prs, PersonalRecord{Type: RecordVolume, Value: after.BestVolume.Value, Previous: before.BestVolume.Value})
+	}
+	return prs, nil
+}
+
+// getMovementRecords handles GET /movements/:name/records
+func getMovementRecords(c *gin.Context) {
+	name := c.Param("name")
+	formula := c.DefaultQuery("formula", FormulaEpley)
+
+	if !validFormula(formula) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid formula"})
+		return
+	}
+
+	// Report under the catalog name but still match exercises logged under
+	// the name as requested
+	canonical, movementID := name, (*int)(nil)
+	if movement, err := resolveMovement(db, name); err == nil && movement != nil {
+		canonical, movementID = movement.Name, &movement.ID
+	}
+
+	sets, err := loadMovementSets(currentUserID(c), name, movementID, 0)
+	switch {
+	case err != nil:
+		log.Println("DB Query Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch records"})
+	case len(sets) == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "No records found for movement"})
+	default:
+		records := computeRecords(canonical, formula, sets)
+		c.JSON(http.StatusOK, inDisplayUnit(c, &records))
+	}
+}
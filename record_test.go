@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateOneRepMax_Formulas(t *testing.T) {
+	assert.Equal(t, 100.0, estimateOneRepMax(FormulaEpley, 100, 1))
+	assert.InDelta(t, 116.67, estimateOneRepMax(FormulaEpley, 100, 5), 0.01)
+	assert.InDelta(t, 112.5, estimateOneRepMax(FormulaBrzycki, 100, 5), 0.01)
+	assert.InDelta(t, 117.46, estimateOneRepMax(FormulaLombardi, 100, 5), 0.01)
+}
+
+func TestGetMovementRecords_Success(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 5, Load: 100, SetType: SetTypeWorking},
+		{SetNumber: 2, Reps: 5, Load: 100, SetType: SetTypeWorking},
+	}})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-08", Movement: "squat", SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 10, Load: 60, SetType: SetTypeWarmup},
+		{SetNumber: 2, Reps: 3, Load: 120, SetType: SetTypeWorking},
+	}})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/movements/Squat/records?formula=epley", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var records MovementRecords
+	err := json.Unmarshal(w.Body.Bytes(), &records)
+	assert.NoError(t, err)
+	assert.Equal(t, 132.0, records.BestE1RM.Value)
+	assert.Equal(t, "2023-10-08", records.BestE1RM.Date)
+	assert.Equal(t, 100.0, records.RepMaxes[5].Value)
+	assert.Equal(t, "2023-10-01", records.RepMaxes[5].Date)
+	assert.NotContains(t, records.RepMaxes, 10)
+	assert.Equal(t, 1000.0, records.BestVolume.Value)
+}
+
+func TestGetMovementRecords_InvalidFormula(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/movements/Squat/records?formula=guess", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateExercise_FlagsPersonalRecord(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 5, Load: 100, SetType: SetTypeWorking},
+	}})
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"date": "2023-10-08", "movement": "Squat", "sets": [{"reps": 5, "load": 105}]}`)
+	req, _ := http.NewRequest("POST", "/exercises", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var m Exercise
+	err := json.Unmarshal(w.Body.Bytes(), &m)
+	assert.NoError(t, err)
+	assert.Len(t, m.PersonalRecords, 3)
+	assert.Equal(t, RecordE1RM, m.PersonalRecords[0].Type)
+	assert.Equal(t, RecordRepMax, m.PersonalRecords[1].Type)
+	assert.Equal(t, 5, m.PersonalRecords[1].Reps)
+	assert.Equal(t, 100.0, m.PersonalRecords[1].Previous)
+	assert.Equal(t, RecordVolume, m.PersonalRecords[2].Type)
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Groupings accepted by the volume report
+const (
+	GroupByDay   = "day"
+	GroupByWeek  = "week"
+	GroupByMonth = "month"
+)
+
+// dateLayout is the ISO-8601 calendar date format used for Date fields
+const dateLayout = "2006-01-02"
+
+// periodExpr returns the SQL expression bucketing column into the start date
+// of its day, ISO week (Monday) or month for the current database dialect
+func periodExpr(tx *gorm.DB, column, groupBy string) string {
+	mysql := tx.Dialector.Name() == "mysql"
+	switch {
+	case groupBy == GroupByWeek && mysql:
+		return fmt.Sprintf("DATE_FORMAT(DATE_SUB(%[1]s, INTERVAL WEEKDAY(%[1]s) DAY), '%%Y-%%m-%%d')", column)
+	case groupBy == GroupByWeek:
+		return fmt.Sprintf("date(%s, 'weekday 0', '-6 days')", column)
+	case groupBy == GroupByMonth && mysql:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-01')", column)
+	case groupBy == GroupByMonth:
+		return fmt.Sprintf("strftime('%%Y-%%m-01', %s)", column)
+	case mysql:
+		return fmt.Sprintf("DATE_FORMAT(%s, '%%Y-%%m-%%d')", column)
+	default:
+		return fmt.Sprintf("date(%s)", column)
+	}
+}
+
+// parseDateRange reads the optional from and to query parameters as ISO-8601
+// dates, reporting false when either is malformed or from is after to
+func parseDateRange(c *gin.Context) (string, string, bool) {
+	from, to := c.Query("from"), c.Query("to")
+	for _, value := range []string{from, to} {
+		if _, err := time.Parse(dateLayout, value); value != "" && err != nil {
+			return "", "", false
+		}
+	}
+	return from, to, from == "" || to == "" || from <= to
+}
+
+// MuscleGroupVolume is the training volume for one muscle group in a period.
+// Sets, reps and tonnage count movements training it as a primary mover;
+// SecondarySets counts sets where it was a secondary mover.
+type MuscleGroupVolume struct {
+	MuscleGroup   string  `json:"muscle_group"`
+	Sets          int     `json:"sets"`
+	SecondarySets int     `json:"secondary_sets"`
+	Reps          int     `json:"reps"`
+	Tonnage       float64 `json:"tonnage"`
+}
+
+// MovementVolume is the training volume for one movement in a period
+type MovementVolume struct {
+	Movement string  `json:"movement"`
+	Sets     int     `json:"sets"`
+	Reps     int     `json:"reps"`
+	Tonnage  float64 `json:"tonnage"`
+}
+
+// VolumePeriod holds the volume for one day, week or month
+type VolumePeriod struct {
+	Period       string              `json:"period"`
+	MuscleGroups []MuscleGroupVolume `json:"muscle_groups"`
+	Movements    []MovementVolume    `json:"movements"`
+}
+
+// volumeRow is one aggregated row from the volume queries
+type volumeRow struct {
+	Period  string
+	Name    string
+	Role    string
+	Sets    int
+	Reps    int
+	Tonnage float64
+}
+
+// workingSetsQuery selects non-warmup sets of the user's live exercises within
+// a date range
+func workingSetsQuery(userID int, from, to string) *gorm.DB {
+	query := db.Table("exercise_sets").
+		Joins("JOIN exercises ON exercises.id = exercise_sets.exercise_id").
+		Where("exercise_sets.deleted_at IS NULL AND exercises.deleted_at IS NULL").
+		Where("exercise_sets.set_type <> ?", SetTypeWarmup).
+		Where("exercises.user_id = ?", userID)
+	if from != "" {
+		query = query.Where("exercises.date >= ?", from)
+	}
+	if to != "" {
+		query = query.Where("exercises.date <= ?", to)
+	}
+	return query
+}
+
+// buildVolumeReport aggregates sets, reps and tonnage per muscle group and
+// per movement for each period between from and to for the user
+func buildVolumeReport(userID int, from, to, groupBy string) ([]VolumePeriod, error) {
+	period := periodExpr(db, "exercises.date", groupBy)
+	totals := "COUNT(*) AS sets, SUM(exercise_sets.reps) AS reps, SUM(exercise_sets.reps * exercise_sets.load) AS tonnage"
+
+	var muscleRows []volumeRow
+	err := workingSetsQuery(userID, from, to).
+		Select(period + " AS period, movement_muscles.muscle_group AS name, movement_muscles.role AS role, " + totals).
+		Joins("JOIN movement_muscles ON movement_muscles.movement_id = exercises.movement_id AND movement_muscles.deleted_at IS NULL").
+		Group("period, movement_muscles.muscle_group, movement_muscles.role").
+		Scan(&muscleRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var movementRows []volumeRow
+	err = workingSetsQuery(userID, from, to).
+		Select(period + " AS period, COALESCE(movements.name, exercises.movement) AS name, " + totals).
+		Joins("LEFT JOIN movements ON movements.id = exercises.movement_id").
+		Group("period, COALESCE(movements.name, exercises.movement)").
+		Scan(&movementRows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	periods := map[string]*VolumePeriod{}
+	periodFor := func(key string) *VolumePeriod {
+		if periods[key] == nil {
+			periods[key] = &VolumePeriod{Period: key, MuscleGroups: []MuscleGroupVolume{}, Movements: []MovementVolume{}}
+		}
+		return periods[key]
+	}
+
+	muscles := map[string]map[string]*MuscleGroupVolume{}
+	for _, row := range muscleRows {
+		if muscles[row.Period] == nil {
+			muscles[row.Period] = map[string]*MuscleGroupVolume{}
+		}
+		volume := muscles[row.Period][row.Name]
+		if volume == nil {
+			volume = &MuscleGroupVolume{MuscleGroup: row.Name}
+			muscles[row.Period][row.Name] = volume
+		}
+		if row.Role == MuscleRoleSecondary {
+			volume.SecondarySets += row.Sets
+			continue
+		}
+		volume.Sets += row.Sets
+		volume.Reps += row.Reps
+		volume.Tonnage += row.Tonnage
+	}
+	for key, byGroup := range muscles {
+		p := periodFor(key)
+		for _, volume := range byGroup {
+			p.MuscleGroups = append(p.MuscleGroups, *volume)
+		}
+		sort.Slice(p.MuscleGroups, func(i, j int) bool { return p.MuscleGroups[i].MuscleGroup < p.MuscleGroups[j].MuscleGroup })
+	}
+
+	for _, row := range movementRows {
+		p := periodFor(row.Period)
+		p.Movements = append(p.Movements, MovementVolume{Movement: row.Name, Sets: row.Sets, Reps: row.Reps, Tonnage: row.Tonnage})
+	}
+
+	report := []VolumePeriod{}
+	for _, p := range periods {
+		sort.Slice(p.Movements, func(i, j int) bool { return p.Movements[i].Movement < p.Movements[j].Movement })
+		report = append(report, *p)
+	}
+	sort.Slice(report, func(i, j int) bool { return report[i].Period < report[j].Period })
+	return report, nil
+}
+
+// getVolumeReport handles GET /reports/volume
+func getVolumeReport(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	groupBy := c.DefaultQuery("group_by", GroupByWeek)
+
+	switch {
+	case !ok:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+		return
+	case groupBy != GroupByDay && groupBy != GroupByWeek && groupBy != GroupByMonth:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid group_by value"})
+		return
+	}
+
+	report, err := buildVolumeReport(currentUserID(c), from, to, groupBy)
+	switch {
+	case err != nil:
+		log.Println("DB Query Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to build volume report"})
+	default:
+		display := displayMasses(c)
+		for i := range report {
+			report[i].convertMass(display)
+		}
+		c.JSON(http.StatusOK, gin.H{"from": from, "to": to, "group_by": groupBy, "unit": display.To, "periods": report})
+	}
+}
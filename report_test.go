@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// volumeResponse mirrors the body returned by GET /reports/volume
+type volumeResponse struct {
+	GroupBy string         `json:"group_by"`
+	Periods []VolumePeriod `json:"periods"`
+}
+
+func TestGetVolumeReport_Weekly(t *testing.T) {
+	db = setupTestDB()
+
+	bench, _ := resolveMovement(db, "bench press")
+	curl, _ := resolveMovement(db, "barbell curl")
+	// Monday and Wednesday of the same week, then the following Monday
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-02", Movement: "Bench", MovementID: &bench.ID, SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 10, Load: 40, SetType: SetTypeWarmup},
+		{SetNumber: 2, Reps: 5, Load: 100, SetType: SetTypeWorking},
+		{SetNumber: 3, Reps: 5, Load: 100, SetType: SetTypeWorking},
+	}})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-04", Movement: "Curl", MovementID: &curl.ID, SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 12, Load: 30, SetType: SetTypeWorking},
+	}})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-09", Movement: "Bench", MovementID: &bench.ID, SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 3, Load: 110, SetType: SetTypeWorking},
+	}})
+	db.Create(&Exercise{UserID: testUserID, Date: "2023-10-04", Movement: "Atlas Stone", SetDetails: []ExerciseSet{
+		{SetNumber: 1, Reps: 3, Load: 120, SetType: SetTypeWorking},
+	}})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/reports/volume?from=2023-10-01&to=2023-10-31&group_by=week", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var report volumeResponse
+	err := json.Unmarshal(w.Body.Bytes(), &report)
+	assert.NoError(t, err)
+	assert.Len(t, report.Periods, 2)
+
+	week := report.Periods[0]
+	assert.Equal(t, "2023-10-02", week.Period)
+	muscles := map[string]MuscleGroupVolume{}
+	for _, volume := range week.MuscleGroups {
+		muscles[volume.MuscleGroup] = volume
+	}
+	assert.Equal(t, 2, muscles["chest"].Sets)
+	assert.Equal(t, 10, muscles["chest"].Reps)
+	assert.Equal(t, 1000.0, muscles["chest"].Tonnage)
+	assert.Equal(t, 2, muscles["triceps"].SecondarySets)
+	assert.Equal(t, 1, muscles["biceps"].Sets)
+	assert.Len(t, week.Movements, 3)
+	assert.Equal(t, "Atlas Stone", week.Movements[0].Movement)
+	assert.Equal(t, "Bench Press", week.Movements[2].Movement)
+
+	assert.Equal(t, "2023-10-09", report.Periods[1].Period)
+}
+
+func TestGetVolumeReport_InvalidParams(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	for _, query := range []string{"from=10/1/23", "from=2023-10-31&to=2023-10-01", "group_by=year"} {
+		req, _ := http.NewRequest("GET", "/reports/volume?"+query, nil)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+	}
+}
@@ -9,28 +9,139 @@ import (
 func SetupRoutes() *gin.Engine {
 	// Initialize Gin router
 	r := gin.Default()
-	r.Use(cors.Default())
+	// Browsers only let clients read the list headers when they are exposed
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowAllOrigins = true
+	corsConfig.ExposeHeaders = []string{totalCountHeader, nextCursorHeader}
+	r.Use(cors.New(corsConfig))
 
 	// Serve static files
 	r.Static("/static", "./static")
 
+	// Routes for registration and login
+	r.POST("/auth/register", register)
+	r.POST("/auth/login", login)
+	r.POST("/auth/refresh", refresh)
+	r.POST("/auth/logout", logout)
+
+	// Routes below need a signed-in user and only see that user's data
+	authorized := r.Group("/", requireAuth())
+
+	// Routes for account settings, personal API tokens, coaches, the
+	// athletes they coach and the account archive, which API tokens cannot
+	// reach
+	account := authorized.Group("/", requireSession())
+	account.GET("/account", getAccount)
+	account.PUT("/account", updateAccount)
+	account.POST("/tokens", createToken)
+	account.GET("/tokens", getTokens)
+	account.DELETE("/tokens/:id", deleteToken)
+	account.POST("/coaches", inviteCoach)
+	account.GET("/coaches", getCoaches)
+	account.PUT("/coaches/:id", updateCoach)
+	account.DELETE("/coaches/:id", deleteCoach)
+	account.GET("/athletes", getAthletes)
+	account.POST("/athletes/:id/accept", acceptAthlete)
+	account.DELETE("/athletes/:id", deleteAthlete)
+	account.GET("/export/archive", exportArchive)
+	account.POST("/import/archive", importArchive)
+
+	// Coaches reach an athlete's exercises, meals and weights with ?athlete_id=
+	shared := authorized.Group("/", coachAccess())
+
+	// Only admins change the shared movement and food catalogs
+	admin := authorized.Group("/", requireSession(), requireAdmin())
+
 	// Routes for exercises
-	r.POST("/exercises", createExercise)
-	r.GET("/exercises", getExercises)
-	r.PUT("/exercises/:id", updateExercise)
-	r.DELETE("/exercises/:id", deleteExercise)
+	exercises := shared.Group("/", requireScope(ResourceExercises))
+	exercises.POST("/exercises", createExercise)
+	exercises.GET("/exercises", getExercises)
+	exercises.PUT("/exercises/:id", updateExercise)
+	exercises.DELETE("/exercises/:id", deleteExercise)
+	exercises.GET("/export/exercises.csv", exportExercises)
+	exercises.POST("/import/exercises", importExercises)
+
+	// Routes for workouts
+	workouts := authorized.Group("/", requireScope(ResourceWorkouts))
+	workouts.POST("/workouts", createWorkout)
+	workouts.GET("/workouts", getWorkouts)
+	workouts.GET("/workouts/:id", getWorkout)
+	workouts.PUT("/workouts/:id", updateWorkout)
+	workouts.DELETE("/workouts/:id", deleteWorkout)
+	workouts.POST("/workouts/:id/exercises", attachWorkoutExercises)
+	workouts.POST("/import/workouts", requireScope(ResourceExercises), importWorkouts)
+
+	// Routes for the movement catalog and records
+	r.GET("/movements", getMovements)
+	r.GET("/movements/:name", getMovement)
+	admin.POST("/movements", createMovement)
+	admin.PUT("/movements/:name", updateMovement)
+	admin.DELETE("/movements/:name", deleteMovement)
+	authorized.GET("/movements/:name/records", requireScope(ResourceExercises), getMovementRecords)
+
+	// Routes for reports
+	authorized.GET("/reports/volume", requireScope(ResourceExercises), getVolumeReport)
 
 	// Routes for meals
-	r.POST("/meals", createMeal)
-	r.GET("/meals", getMeals)
-	r.PUT("/meals/:id", updateMeal)
-	r.DELETE("/meals/:id", deleteMeal)
+	meals := shared.Group("/", requireScope(ResourceMeals))
+	meals.POST("/meals", createMeal)
+	meals.GET("/meals", getMeals)
+	meals.PUT("/meals/:id", updateMeal)
+	meals.DELETE("/meals/:id", deleteMeal)
+	meals.GET("/export/meals.csv", exportMeals)
+	meals.POST("/import/meals", importMeals)
+	meals.POST("/import/myfitnesspal", importMyFitnessPalMeals)
+
+	// Routes for the food database
+	r.GET("/foods", getFoods)
+	r.GET("/foods/:id", getFood)
+	admin.POST("/foods", createFood)
+	admin.PUT("/foods/:id", updateFood)
+	admin.DELETE("/foods/:id", deleteFood)
+
+	// Routes for recipes
+	meals.POST("/recipes", createRecipe)
+	meals.GET("/recipes", getRecipes)
+	meals.GET("/recipes/:id", getRecipe)
+	meals.PUT("/recipes/:id", updateRecipe)
+	meals.DELETE("/recipes/:id", deleteRecipe)
+
+	// Routes for nutrition totals and macro targets
+	authorized.GET("/nutrition/daily", requireScope(ResourceMeals), getDailyNutrition)
+	meals.POST("/targets", createTarget)
+	meals.GET("/targets", getTargets)
+	meals.PUT("/targets/:id", updateTarget)
+	meals.DELETE("/targets/:id", deleteTarget)
 
 	// Routes for weight entries
-	r.POST("/weights", createWeightEntry)
-	r.GET("/weights", getWeightEntries)
-	r.PUT("/weights/:id", updateWeightEntry)
-	r.DELETE("/weights/:id", deleteWeightEntry)
+	weights := shared.Group("/", requireScope(ResourceWeights))
+	weights.POST("/weights", createWeightEntry)
+	weights.GET("/weights", getWeightEntries)
+	weights.GET("/weights/trend", getWeightTrend)
+	weights.PUT("/weights/:id", updateWeightEntry)
+	weights.DELETE("/weights/:id", deleteWeightEntry)
+	weights.GET("/export/weights.csv", exportWeights)
+	weights.POST("/import/weights", importWeights)
+
+	// Route for maintenance calorie estimates
+	authorized.GET("/energy/tdee", requireScope(ResourceMeals, ResourceWeights), getTDEE)
+
+	// Routes for bodyweight goals
+	goals := authorized.Group("/", requireScope(ResourceGoals))
+	goals.POST("/goals", createGoal)
+	goals.GET("/goals", getGoals)
+	goals.GET("/goals/:id", getGoal)
+	goals.GET("/goals/:id/projection", getGoalProjection)
+	goals.PUT("/goals/:id", updateGoal)
+	goals.DELETE("/goals/:id", deleteGoal)
+
+	// Routes for cardio sessions decoded from FIT and TCX files
+	cardio := authorized.Group("/", requireScope(ResourceCardio))
+	cardio.POST("/cardio", uploadCardio)
+	cardio.GET("/cardio", getCardioSessions)
+	cardio.GET("/cardio/:id", getCardioSession)
+	cardio.GET("/cardio/:id/file", getCardioFile)
+	cardio.DELETE("/cardio/:id", deleteCardioSession)
 
 	return r
 }
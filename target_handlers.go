@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// validTarget reports whether a macro target has a known day type and no
+// negative values
+func validTarget(target *MacroTarget) bool {
+	switch target.DayType {
+	case DayTypeDefault, DayTypeTraining, DayTypeRest:
+		return target.Carbs >= 0 && target.Protein >= 0 && target.Fats >= 0 && target.Calories >= 0
+	default:
+		return false
+	}
+}
+
+// targetDayTypeTaken reports whether another of the user's targets already
+// covers the day type
+func targetDayTypeTaken(target *MacroTarget) bool {
+	var count int64
+	db.Model(&MacroTarget{}).Where("user_id = ? AND day_type = ? AND id <> ?", target.UserID, target.DayType, target.ID).Count(&count)
+	return count > 0
+}
+
+// createTarget handles POST /targets
+func createTarget(c *gin.Context) {
+	target := MacroTarget{UserID: currentUserID(c)}
+
+	switch {
+	case c.ShouldBindJSON(&target) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !validTarget(&target):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case targetDayTypeTaken(&target):
+		c.JSON(http.StatusConflict, gin.H{"error": "Target already exists for day type"})
+		return
+	case db.Create(&target).Error != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create target"})
+		return
+	default:
+		c.JSON(http.StatusCreated, target)
+	}
+}
+
+// getTargets handles GET /targets
+func getTargets(c *gin.Context) {
+	var targets []MacroTarget
+	switch err := userScope(c).Order("day_type ASC").Find(&targets).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, targets)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch targets"})
+	}
+}
+
+// updateTarget handles PUT /targets/:id
+func updateTarget(c *gin.Context) {
+	id := c.Param("id")
+	var target MacroTarget
+
+	switch {
+	case userScope(c).First(&target, id).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target not found"})
+		return
+	case bindUpdate(c, &target.ID, &target) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !validTarget(&target):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case targetDayTypeTaken(&target):
+		c.JSON(http.StatusConflict, gin.H{"error": "Target already exists for day type"})
+		return
+	case db.Save(&target).Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update target"})
+		return
+	default:
+		c.JSON(http.StatusOK, target)
+	}
+}
+
+// deleteTarget handles DELETE /targets/:id
+func deleteTarget(c *gin.Context) {
+	id := c.Param("id")
+
+	// Targets are removed outright so the day type can be set again
+	result := userScope(c).Unscoped().Where("id = ?", id).Delete(&MacroTarget{})
+	switch {
+	case result.Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete target"})
+	case result.RowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Target not found"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Target deleted successfully"})
+	}
+}
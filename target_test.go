@@ -0,0 +1,142 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateTarget_Success(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(MacroTarget{DayType: DayTypeTraining, Carbs: 300, Protein: 180, Fats: 70, Calories: 2550})
+	req, _ := http.NewRequest("POST", "/targets", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var target MacroTarget
+	err := json.Unmarshal(w.Body.Bytes(), &target)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, target.ID)
+	assert.Equal(t, 70, target.Fats)
+}
+
+func TestCreateTarget_InvalidDayType(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(MacroTarget{DayType: "refeed", Carbs: 400})
+	req, _ := http.NewRequest("POST", "/targets", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestCreateTarget_DuplicateDayType(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&MacroTarget{UserID: testUserID, DayType: DayTypeRest, Carbs: 150})
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(MacroTarget{DayType: DayTypeRest, Carbs: 120})
+	req, _ := http.NewRequest("POST", "/targets", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+}
+
+func TestUpdateTarget_Success(t *testing.T) {
+	db = setupTestDB()
+
+	target := MacroTarget{UserID: testUserID, DayType: DayTypeDefault, Carbs: 200, Protein: 150, Fats: 60, Calories: 1940}
+	db.Create(&target)
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(MacroTarget{DayType: DayTypeDefault, Carbs: 250, Protein: 150, Fats: 60, Calories: 2140})
+	req, _ := http.NewRequest("PUT", fmt.Sprintf("/targets/%d", target.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var updated MacroTarget
+	err := json.Unmarshal(w.Body.Bytes(), &updated)
+	assert.NoError(t, err)
+	assert.Equal(t, 250, updated.Carbs)
+}
+
+func TestDeleteTarget_Success(t *testing.T) {
+	db = setupTestDB()
+
+	target := MacroTarget{UserID: testUserID, DayType: DayTypeRest, Carbs: 150}
+	db.Create(&target)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/targets/%d", target.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	var response map[string]string
+	err := json.Unmarshal(w.Body.Bytes(), &response)
+	assert.NoError(t, err)
+	assert.Equal(t, "Target deleted successfully", response["message"])
+}
+
+func TestTargets_BelongToTheirOwner(t *testing.T) {
+	db = setupTestDB()
+
+	other := User{Email: "other@example.com"}
+	db.Create(&other)
+	theirs := MacroTarget{UserID: other.ID, DayType: DayTypeRest, Carbs: 150}
+	db.Create(&theirs)
+
+	r := setupRouter()
+
+	// Each user sets their own target for a day type
+	reqBody, _ := json.Marshal(MacroTarget{DayType: DayTypeRest, Carbs: 120})
+	req, _ := http.NewRequest("POST", "/targets", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	req, _ = http.NewRequest("GET", "/targets", nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	var targets []MacroTarget
+	json.Unmarshal(w.Body.Bytes(), &targets)
+	assert.Len(t, targets, 1)
+	assert.Equal(t, 120, targets[0].Carbs)
+
+	req, _ = http.NewRequest("DELETE", fmt.Sprintf("/targets/%d", theirs.ID), nil)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The database holds one target per user and day type
+	assert.Error(t, db.Create(&MacroTarget{UserID: other.ID, DayType: DayTypeRest}).Error)
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// apiTokenPrefix starts every personal API token so the auth middleware can
+// tell them from access tokens
+const apiTokenPrefix = "gbp_"
+
+// Scope suffixes for the resources API tokens can reach
+const (
+	scopeRead  = ":read"
+	scopeWrite = ":write"
+)
+
+// Resources API tokens can be scoped to
+const (
+	ResourceExercises = "exercises"
+	ResourceWorkouts  = "workouts"
+	ResourceMeals     = "meals"
+	ResourceWeights   = "weights"
+	ResourceGoals     = "goals"
+	ResourceCardio    = "cardio"
+)
+
+// validScope reports whether scope names a known resource and access level
+func validScope(scope string) bool {
+	for _, resource := range []string{ResourceExercises, ResourceWorkouts, ResourceMeals, ResourceWeights, ResourceGoals, ResourceCardio} {
+		if scope == resource+scopeRead || scope == resource+scopeWrite {
+			return true
+		}
+	}
+	return false
+}
+
+// findAPIToken loads the unexpired API token matching token and records
+// that it was used
+func findAPIToken(token string, now time.Time) (*APIToken, bool) {
+	var apiToken APIToken
+	found := db.Where("token_hash = ? AND (expires_at IS NULL OR expires_at > ?)", hashToken(token), now).
+		Limit(1).Find(&apiToken).RowsAffected > 0
+	if found {
+		db.Model(&apiToken).UpdateColumn("last_used_at", now)
+	}
+	return &apiToken, found
+}
+
+// createToken handles POST /tokens. The token itself is only returned here.
+func createToken(c *gin.Context) {
+	var req struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expires_in_days"`
+	}
+
+	switch {
+	case c.ShouldBindJSON(&req) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case strings.TrimSpace(req.Name) == "" || len(req.Scopes) == 0 || req.ExpiresInDays < 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	}
+	for _, scope := range req.Scopes {
+		if !validScope(scope) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid scope " + scope})
+			return
+		}
+	}
+
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	token := apiTokenPrefix + base64.RawURLEncoding.EncodeToString(raw)
+	sort.Strings(req.Scopes)
+
+	apiToken := APIToken{
+		UserID:    currentUserID(c),
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    token[:len(apiTokenPrefix)+6],
+		TokenHash: hashToken(token),
+		Scopes:    req.Scopes,
+	}
+	if req.ExpiresInDays > 0 {
+		expires := time.Now().AddDate(0, 0, req.ExpiresInDays)
+		apiToken.ExpiresAt = &expires
+	}
+
+	if err := db.Create(&apiToken).Error; err != nil {
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create token"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"token": token, "api_token": apiToken})
+}
+
+// getTokens handles GET /tokens
+func getTokens(c *gin.Context) {
+	var tokens []APIToken
+	switch err := userScope(c).Order("created_at DESC").Find(&tokens).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, tokens)
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tokens"})
+	}
+}
+
+// deleteToken handles DELETE /tokens/:id, revoking the token
+func deleteToken(c *gin.Context) {
+	id := c.Param("id")
+
+	result := userScope(c).Where("id = ?", id).Delete(&APIToken{})
+	switch {
+	case result.Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke token"})
+	case result.RowsAffected == 0:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Token not found"})
+	default:
+		c.JSON(http.StatusOK, gin.H{"message": "Token revoked successfully"})
+	}
+}
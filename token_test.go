@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// createTestToken creates an API token for the test user and returns the
+// token and its record
+func createTestToken(t *testing.T, r *gin.Engine, scopes ...string) (string, APIToken) {
+	w := postJSON(r, "/tokens", gin.H{"name": "Kiosk", "scopes": scopes}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var created struct {
+		Token    string   `json:"token"`
+		APIToken APIToken `json:"api_token"`
+	}
+	err := json.Unmarshal(w.Body.Bytes(), &created)
+	assert.NoError(t, err)
+	return created.Token, created.APIToken
+}
+
+func TestCreateToken_Success(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	token, apiToken := createTestToken(t, r, "weights:write", "exercises:read")
+
+	assert.Contains(t, token, apiTokenPrefix)
+	assert.Equal(t, token[:len(apiToken.Prefix)], apiToken.Prefix)
+	assert.Equal(t, []string{"exercises:read", "weights:write"}, apiToken.Scopes)
+
+	// Only the hash is stored and listings never include the token
+	var stored APIToken
+	db.First(&stored, apiToken.ID)
+	assert.Equal(t, hashToken(token), stored.TokenHash)
+
+	w := sendJSON(r, "GET", "/tokens", nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), token)
+}
+
+func TestCreateToken_InvalidScope(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	w := postJSON(r, "/tokens", gin.H{"name": "Script", "scopes": []string{"foods:write"}}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postJSON(r, "/tokens", gin.H{"name": "Script", "scopes": []string{}}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestAPIToken_Scopes(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	token, _ := createTestToken(t, r, "weights:write", "exercises:read")
+
+	w := sendJSON(r, "POST", "/weights", Weight{Date: "2023-10-01", Weight: 80}, token)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var weight Weight
+	db.First(&weight)
+	assert.Equal(t, testUserID, weight.UserID)
+
+	w = sendJSON(r, "GET", "/exercises", nil, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendJSON(r, "POST", "/exercises", Exercise{Date: "2023-10-01", Movement: "Squat", Sets: 3, Reps: 5, Weight: 100}, token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = sendJSON(r, "GET", "/meals", nil, token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	// Tokens cannot mint more tokens
+	w = sendJSON(r, "GET", "/tokens", nil, token)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	var stored APIToken
+	db.First(&stored)
+	assert.NotNil(t, stored.LastUsedAt)
+}
+
+func TestDeleteToken_Revokes(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	token, apiToken := createTestToken(t, r, "weights:read")
+
+	w := sendJSON(r, "GET", "/weights", nil, token)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendJSON(r, "DELETE", fmt.Sprintf("/tokens/%d", apiToken.ID), nil, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = sendJSON(r, "GET", "/weights", nil, token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func TestAPIToken_Expired(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	token, apiToken := createTestToken(t, r, "weights:read")
+	db.Model(&apiToken).Update("expires_at", time.Now().Add(-time.Minute))
+
+	w := sendJSON(r, "GET", "/weights", nil, token)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"gorm.io/gorm"
+)
+
+// Mass units accepted on input and used for display. Loads and bodyweights
+// are stored in kilograms.
+const (
+	UnitKg = "kg"
+	UnitLb = "lb"
+)
+
+// kgPerLb is the exact international pound in kilograms
+const kgPerLb = 0.45359237
+
+// massPlaces is the number of decimal places masses are displayed with
+const massPlaces = 2
+
+// errInvalidUnit is returned when a request body names an unknown unit
+var errInvalidUnit = errors.New("invalid unit")
+
+// unitsMigration names the MigrationMark for the switch to storing masses
+// in kilograms
+const unitsMigration = "units"
+
+// errUnitsConverted is returned when a user's legacy masses were already
+// converted
+var errUnitsConverted = errors.New("masses already converted")
+
+// validUnit reports whether unit is a supported mass unit
+func validUnit(unit string) bool {
+	return unit == UnitKg || unit == UnitLb
+}
+
+// unitKg returns the number of kilograms in one unit
+func unitKg(unit string) float64 {
+	if unit == UnitLb {
+		return kgPerLb
+	}
+	return 1
+}
+
+// massConverter converts masses from one unit to another. Only conversions
+// for display are rounded, so values read from a request and stored in
+// kilograms convert back exactly.
+type massConverter struct {
+	From, To string
+	Round    bool
+}
+
+// mass converts a mass, or a mass multiplied by reps such as tonnage
+func (m massConverter) mass(value float64) float64 {
+	value = value * unitKg(m.From) / unitKg(m.To)
+	if m.Round {
+		value = roundTo(value, massPlaces)
+	}
+	return value
+}
+
+// optionalMass converts a mass that may be absent
+func (m massConverter) optionalMass(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	converted := m.mass(*value)
+	return &converted
+}
+
+// massHolder is a record or report holding masses in kilograms
+type massHolder interface {
+	convertMass(m massConverter)
+}
+
+// userUnit returns the user's display unit, kilograms unless they chose pounds
+func userUnit(userID int) string {
+	var user User
+	db.Select("unit").Where("id = ?", userID).Limit(1).Find(&user)
+	if validUnit(user.Unit) {
+		return user.Unit
+	}
+	return UnitKg
+}
+
+// displayUnit returns the display unit of the user making the request, so a
+// coach sees an athlete's data in the coach's own unit
+func displayUnit(c *gin.Context) string {
+	return userUnit(actingUserID(c))
+}
+
+// displayMasses returns the converter from stored kilograms to the display
+// unit of the user making the request
+func displayMasses(c *gin.Context) massConverter {
+	return massConverter{From: UnitKg, To: displayUnit(c), Round: true}
+}
+
+// inDisplayUnit converts a record or report for the response and returns it
+func inDisplayUnit(c *gin.Context, obj massHolder) massHolder {
+	obj.convertMass(displayMasses(c))
+	return obj
+}
+
+// requestUnit returns the unit a request body's masses are written in: its
+// "unit" field, or the display unit of the user making the request
+func requestUnit(c *gin.Context) (string, bool) {
+	var body struct {
+		Unit string `json:"unit"`
+	}
+	c.ShouldBindBodyWith(&body, binding.JSON)
+	unit := strings.ToLower(strings.TrimSpace(body.Unit))
+	if unit == "" {
+		unit = displayUnit(c)
+	}
+	return unit, validUnit(unit)
+}
+
+// bindMasses binds the JSON body over obj. Masses are read in the request's
+// unit and stored in kilograms; masses already on obj are converted to that
+// unit first so fields the body leaves out keep their value.
+func bindMasses(c *gin.Context, obj interface{}) error {
+	holder, ok := obj.(massHolder)
+	if !ok {
+		return c.ShouldBindBodyWith(obj, binding.JSON)
+	}
+	unit, ok := requestUnit(c)
+	if !ok {
+		return errInvalidUnit
+	}
+	holder.convertMass(massConverter{From: UnitKg, To: unit})
+	err := c.ShouldBindBodyWith(obj, binding.JSON)
+	holder.convertMass(massConverter{From: unit, To: UnitKg})
+	return err
+}
+
+// convertMass converts the exercise's weight, set loads and records
+func (e *Exercise) convertMass(m massConverter) {
+	e.Unit = m.To
+	e.Weight = m.mass(e.Weight)
+	for i := range e.SetDetails {
+		e.SetDetails[i].Load = m.mass(e.SetDetails[i].Load)
+	}
+	for i := range e.PersonalRecords {
+		e.PersonalRecords[i].Value = m.mass(e.PersonalRecords[i].Value)
+		e.PersonalRecords[i].Previous = m.mass(e.PersonalRecords[i].Previous)
+	}
+}
+
+// convertMass converts the workout's bodyweight and exercises
+func (w *Workout) convertMass(m massConverter) {
+	w.Unit = m.To
+	w.Bodyweight = m.mass(w.Bodyweight)
+	for i := range w.Exercises {
+		w.Exercises[i].convertMass(m)
+	}
+}
+
+// convertMass converts the logged bodyweight
+func (w *Weight) convertMass(m massConverter) {
+	w.Unit = m.To
+	w.Weight = m.mass(w.Weight)
+}
+
+// convertMass converts the goal's target weight
+func (g *Goal) convertMass(m massConverter) {
+	g.Unit = m.To
+	g.TargetWeight = m.mass(g.TargetWeight)
+}
+
+// convertMass converts the projection's weights and weekly rates
+func (p *GoalProjection) convertMass(m massConverter) {
+	p.Unit = m.To
+	p.CurrentWeight = m.mass(p.CurrentWeight)
+	p.TargetWeight = m.mass(p.TargetWeight)
+	p.RemainingWeight = m.mass(p.RemainingWeight)
+	p.RequiredWeeklyRate = m.optionalMass(p.RequiredWeeklyRate)
+	p.ActualWeeklyRate = m.optionalMass(p.ActualWeeklyRate)
+}
+
+// convertMass converts the trend weights and the energy density, which is
+// per unit of mass
+func (e *TDEEEstimate) convertMass(m massConverter) {
+	e.Unit = m.To
+	e.StartTrendWeight = m.mass(e.StartTrendWeight)
+	e.EndTrendWeight = m.mass(e.EndTrendWeight)
+	e.WeeklyWeightChange = m.mass(e.WeeklyWeightChange)
+	e.GoalRate = m.mass(e.GoalRate)
+	e.EnergyDensity = roundTo(e.EnergyDensity*unitKg(m.To)/unitKg(m.From), 1)
+}
+
+// convertMass converts the point's weights and weekly change. The percent
+// change is the same in any unit.
+func (p *TrendPoint) convertMass(m massConverter) {
+	p.Weight = m.mass(p.Weight)
+	p.Trend = m.mass(p.Trend)
+	p.RollingMean = m.optionalMass(p.RollingMean)
+	p.WeeklyChange = m.optionalMass(p.WeeklyChange)
+}
+
+// convertMass converts record loads, estimated maxes and volume
+func (r *MovementRecords) convertMass(m massConverter) {
+	r.Unit = m.To
+	for _, entry := range []*RecordEntry{r.BestE1RM, r.BestVolume} {
+		if entry != nil {
+			entry.Value, entry.Weight = m.mass(entry.Value), m.mass(entry.Weight)
+		}
+	}
+	for reps, entry := range r.RepMaxes {
+		entry.Value, entry.Weight = m.mass(entry.Value), m.mass(entry.Weight)
+		r.RepMaxes[reps] = entry
+	}
+}
+
+// convertMass converts the period's tonnage
+func (p *VolumePeriod) convertMass(m massConverter) {
+	for i := range p.MuscleGroups {
+		p.MuscleGroups[i].Tonnage = m.mass(p.MuscleGroups[i].Tonnage)
+	}
+	for i := range p.Movements {
+		p.Movements[i].Tonnage = m.mass(p.Movements[i].Tonnage)
+	}
+}
+
+// convertLegacyMasses treats the masses a user logged before units existed
+// as written in unit, converts them to kilograms and makes unit the user's
+// display unit. Rows created since the units migration are already in
+// kilograms and left alone. It returns errUnitsConverted when the user's
+// masses were converted before, so they are never converted twice.
+func convertLegacyMasses(tx *gorm.DB, userID int, unit string) error {
+	factor := unitKg(unit)
+	return tx.Transaction(func(tx *gorm.DB) error {
+		var mark MigrationMark
+		if err := tx.Where("name = ?", unitsMigration).First(&mark).Error; err != nil {
+			return fmt.Errorf("units migration not recorded: %w", err)
+		}
+		marked := tx.Model(&User{}).Where("id = ? AND units_converted = ?", userID, false).Update("units_converted", true)
+		switch {
+		case marked.Error != nil:
+			return marked.Error
+		case marked.RowsAffected == 0:
+			return errUnitsConverted
+		}
+
+		// Sets follow their exercise, as updates write them again
+		legacy := "user_id = ? AND created_at < ?"
+		exercises := tx.Unscoped().Model(&Exercise{}).Select("id").Where(legacy, userID, mark.AppliedAt)
+		updates := []*gorm.DB{
+			tx.Unscoped().Model(&ExerciseSet{}).Where("exercise_id IN (?)", exercises).Update("load", gorm.Expr("exercise_sets.load * ?", factor)),
+			tx.Unscoped().Model(&Exercise{}).Where(legacy, userID, mark.AppliedAt).Update("weight", gorm.Expr("exercises.weight * ?", factor)),
+			tx.Unscoped().Model(&Workout{}).Where(legacy, userID, mark.AppliedAt).Update("bodyweight", gorm.Expr("workouts.bodyweight * ?", factor)),
+			tx.Unscoped().Model(&Weight{}).Where(legacy, userID, mark.AppliedAt).Update("weight", gorm.Expr("weights.weight * ?", factor)),
+			tx.Unscoped().Model(&Goal{}).Where(legacy, userID, mark.AppliedAt).Update("target_weight", gorm.Expr("goals.target_weight * ?", factor)),
+			tx.Model(&User{}).Where("id = ?", userID).Update("unit", unit),
+		}
+		for _, result := range updates {
+			if result.Error != nil {
+				return result.Error
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+// setDisplayUnit makes unit the test user's display unit
+func setDisplayUnit(unit string) {
+	db.Model(&User{}).Where("id = ?", testUserID).Update("unit", unit)
+}
+
+func TestCreateExercise_PoundsRoundTrip(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	setDisplayUnit(UnitLb)
+
+	body := gin.H{"date": "2023-10-01", "movement": "Bench Press", "sets": []gin.H{{"reps": 5, "load": 225}, {"reps": 5, "load": 227.5}}}
+	w := postJSON(r, "/exercises", body, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var exercise Exercise
+	json.Unmarshal(w.Body.Bytes(), &exercise)
+	assert.Equal(t, UnitLb, exercise.Unit)
+	assert.Equal(t, 227.5, exercise.Weight)
+
+	var stored Exercise
+	preloadSets(db).First(&stored, exercise.ID)
+	assert.InDelta(t, 102.0583, stored.SetDetails[0].Load, 0.0001)
+
+	// Sending the fetched exercise back unchanged leaves the loads exactly as
+	// they were entered
+	for i := 0; i < 10; i++ {
+		w = sendJSON(r, "PUT", fmt.Sprintf("/exercises/%d", exercise.ID), exercise, "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		json.Unmarshal(w.Body.Bytes(), &exercise)
+	}
+	assert.Equal(t, 225.0, exercise.SetDetails[0].Load)
+	assert.Equal(t, 227.5, exercise.SetDetails[1].Load)
+
+	var exercises []Exercise
+	getList(t, "/exercises", &exercises)
+	if assert.Len(t, exercises, 1) {
+		assert.Equal(t, 227.5, exercises[0].Weight)
+		assert.Equal(t, 225.0, exercises[0].SetDetails[0].Load)
+	}
+}
+
+func TestCreateWeightEntry_ExplicitUnit(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	w := postJSON(r, "/weights", gin.H{"date": "2023-10-01", "weight": 180, "unit": "LB"}, "")
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var weight Weight
+	json.Unmarshal(w.Body.Bytes(), &weight)
+	assert.Equal(t, UnitKg, weight.Unit)
+	assert.Equal(t, 81.65, weight.Weight)
+
+	w = postJSON(r, "/weights", gin.H{"date": "2023-10-01", "weight": 180, "unit": "stone"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUpdateWeightEntry_KeepsOmittedMasses(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+	setDisplayUnit(UnitLb)
+
+	weight := Weight{UserID: testUserID, Date: "2023-10-01", Weight: 80}
+	db.Create(&weight)
+
+	w := sendJSON(r, "PUT", fmt.Sprintf("/weights/%d", weight.ID), gin.H{"date": "2023-10-02"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	db.First(&weight, weight.ID)
+	assert.Equal(t, Date("2023-10-02"), weight.Date)
+	assert.InDelta(t, 80, weight.Weight, 1e-9)
+}
+
+func TestGetWeightTrend_DisplayUnit(t *testing.T) {
+	db = setupTestDB()
+	setDisplayUnit(UnitLb)
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 100})
+
+	var response struct {
+		Unit   string       `json:"unit"`
+		Points []TrendPoint `json:"points"`
+	}
+	getList(t, "/weights/trend", &response)
+	assert.Equal(t, UnitLb, response.Unit)
+	if assert.Len(t, response.Points, 1) {
+		assert.Equal(t, 220.46, response.Points[0].Trend)
+	}
+}
+
+func TestUpdateAccount_Unit(t *testing.T) {
+	db = setupTestDB()
+	r := setupRouter()
+
+	w := sendJSON(r, "PUT", "/account", gin.H{"unit": "lb"}, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, UnitLb, userUnit(testUserID))
+
+	w = sendJSON(r, "PUT", "/account", gin.H{"unit": "st"}, "")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, UnitLb, userUnit(testUserID))
+}
+
+func TestConvertLegacyMasses(t *testing.T) {
+	db = setupTestDB()
+
+	exercise := Exercise{UserID: testUserID, Date: "2023-10-01", Movement: "Squat", Sets: 1, Reps: 5, Weight: 315, SetDetails: []ExerciseSet{{SetNumber: 1, Reps: 5, Load: 315}}}
+	db.Create(&exercise)
+	legacy := Weight{UserID: testUserID, Date: "2023-10-01", Weight: 200}
+	db.Create(&legacy)
+	other := User{Email: "other@example.com"}
+	db.Create(&other)
+	db.Create(&Weight{UserID: other.ID, Date: "2023-10-01", Weight: 90})
+
+	// Logged in kilograms after the units migration
+	cutoff, err := markMigration(db, unitsMigration)
+	assert.NoError(t, err)
+	after := gorm.Model{CreatedAt: cutoff.Add(time.Minute)}
+	recent := Exercise{Model: after, UserID: testUserID, Date: "2023-10-02", Movement: "Squat", Sets: 1, Reps: 5, Weight: 140, SetDetails: []ExerciseSet{{SetNumber: 1, Reps: 5, Load: 140}}}
+	db.Create(&recent)
+	recentWeight := Weight{Model: after, UserID: testUserID, Date: "2023-10-02", Weight: 90}
+	db.Create(&recentWeight)
+
+	assert.NoError(t, convertLegacyMasses(db, testUserID, UnitLb))
+
+	preloadSets(db).First(&exercise, exercise.ID)
+	assert.InDelta(t, 142.88, exercise.Weight, 0.01)
+	assert.InDelta(t, 142.88, exercise.SetDetails[0].Load, 0.01)
+	db.First(&legacy, legacy.ID)
+	assert.InDelta(t, 90.72, legacy.Weight, 0.01)
+	assert.Equal(t, UnitLb, userUnit(testUserID))
+
+	preloadSets(db).First(&recent, recent.ID)
+	assert.Equal(t, 140.0, recent.Weight)
+	assert.Equal(t, 140.0, recent.SetDetails[0].Load)
+	db.First(&recentWeight, recentWeight.ID)
+	assert.Equal(t, 90.0, recentWeight.Weight)
+	var count int64
+	db.Model(&Weight{}).Where("user_id = ? AND weight = ?", other.ID, 90).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	// A second run is refused and leaves the masses as they are
+	assert.Equal(t, errUnitsConverted, convertLegacyMasses(db, testUserID, UnitLb))
+	db.First(&exercise, exercise.ID)
+	assert.InDelta(t, 142.88, exercise.Weight, 0.01)
+}
@@ -3,37 +3,66 @@ package main
 import (
 	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+// validateWeight applies the rules every weight entry must meet, with dates
+// read in the user's time zone loc. It returns the error response for the
+// first rule broken, or nil when the entry is valid.
+func validateWeight(weight *Weight, loc *time.Location) gin.H {
+	switch {
+	case !normalizeDate(&weight.Date, weight.OccurredAt, loc):
+		return dateFieldError(weight.Date, weight.OccurredAt)
+	case weight.Weight <= 0:
+		return gin.H{"error": "Invalid weight input value"}
+	case weight.BodyFat != nil && (*weight.BodyFat <= 0 || *weight.BodyFat >= 100):
+		return gin.H{"error": "Invalid body fat percentage", "field": "body_fat"}
+	}
+	return nil
+}
+
 // createWeightEntry handles POST /weights
 func createWeightEntry(c *gin.Context) {
-	var weight Weight
+	weight := Weight{UserID: currentUserID(c)}
 
 	switch {
-	case c.ShouldBindBodyWithJSON(&weight) != nil:
+	case bindAudited(c, &weight.ID, &weight.Audit, &weight) != nil:
 		log.Println("JSON Bind Error")
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
-	case weight.Weight <= 0:
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weight input value"})
+	case rejectInvalid(c, validateWeight(&weight, currentLocation(c))):
 		return
 	case db.Create(&weight).Error != nil:
 		log.Println("DB Insert Error")
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create weight entry"})
 		return
 	default:
-		c.JSON(http.StatusCreated, weight)
+		c.JSON(http.StatusCreated, inDisplayUnit(c, &weight))
 	}
 }
 
+// weightList is the filtering and sorting accepted by GET /weights
+var weightList = listSpec{
+	DateColumn:  "date",
+	Sorts:       map[string]string{"date": "date", "created_at": "created_at", "weight": "weight"},
+	DefaultSort: "-created_at",
+}
+
 // getWeightEntries handles GET /weights
 func getWeightEntries(c *gin.Context) {
 	var weights []Weight
-	switch err := db.Order("created_at DESC").Find(&weights).Error; err {
+	err := paginate(c, userScope(c).Model(&Weight{}), weightList, &weights, nil)
+	switch err.(type) {
 	case nil:
+		display := displayMasses(c)
+		for i := range weights {
+			weights[i].convertMass(display)
+		}
 		c.JSON(http.StatusOK, weights)
+	case listParamError:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 	default:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch weight entries"})
 	}
@@ -45,17 +74,19 @@ func updateWeightEntry(c *gin.Context) {
 	var weight Weight
 	
 	switch {
-	case db.First(&weight, id).Error != nil:
+	case userScope(c).First(&weight, id).Error != nil:
 		c.JSON(http.StatusNotFound, gin.H{"error": "Weight entry not found"})
 		return
-	case c.ShouldBindJSON(&weight) != nil:
+	case bindAudited(c, &weight.ID, &weight.Audit, &weight) != nil:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
 		return
+	case rejectInvalid(c, validateWeight(&weight, currentLocation(c))):
+		return
 	case db.Save(&weight).Error != nil:
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update weight entry"})
 		return
 	default:
-		c.JSON(http.StatusOK, weight)
+		c.JSON(http.StatusOK, inDisplayUnit(c, &weight))
 	}
 }
 
@@ -68,7 +99,7 @@ func deleteWeightEntry(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid weight entry ID"})
 		return
 	default:
-		result := db.Where("id = ?", id).Delete(&Weight{})
+		result := userScope(c).Where("id = ?", id).Delete(&Weight{})
 		switch {
 		case result.Error != nil:
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete weight entry"})
@@ -62,6 +62,7 @@ func TestGetWeightEntries_Success(t *testing.T) {
 
 	// Add test data
 	weight := Weight{
+		UserID: testUserID,
 		Date:   "2023-10-01",
 		Weight: 75.5,
 	}
@@ -87,6 +88,7 @@ func TestUpdateWeightEntry_Success(t *testing.T) {
 
 	// Create a test weight entry
 	weight := Weight{
+		UserID: testUserID,
 		Date:   "2023-10-01",
 		Weight: 75.5,
 	}
@@ -117,6 +119,7 @@ func TestDeleteWeightEntry_Success(t *testing.T) {
 
 	// Create a test weight entry
 	weight := Weight{
+		UserID: testUserID,
 		Date:   "2023-10-01",
 		Weight: 75.5,
 	}
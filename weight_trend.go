@@ -0,0 +1,145 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultTrendAlpha is the EWMA smoothing factor used when none is given
+const defaultTrendAlpha = 0.1
+
+// rollingWindow is the number of days in the rolling mean and rate of change
+const rollingWindow = 7
+
+// TrendPoint is one day of the smoothed bodyweight trend. Days without a
+// logged weight are filled by linear interpolation between their neighbours.
+type TrendPoint struct {
+	Date                string   `json:"date"`
+	Weight              float64  `json:"weight"`
+	Interpolated        bool     `json:"interpolated"`
+	Trend               float64  `json:"trend"`
+	RollingMean         *float64 `json:"rolling_mean_7d"`
+	WeeklyChange        *float64 `json:"weekly_change"`
+	WeeklyChangePercent *float64 `json:"weekly_change_percent"`
+}
+
+// dailyWeights averages the user's logged weights per calendar day, skipping
+// entries whose date cannot be parsed, and returns the days in order
+func dailyWeights(userID int) ([]time.Time, map[time.Time]float64, error) {
+	var weights []Weight
+	if err := db.Where("user_id = ?", userID).Order("date ASC").Find(&weights).Error; err != nil {
+		return nil, nil, err
+	}
+
+	sums := map[time.Time]float64{}
+	counts := map[time.Time]int{}
+	var days []time.Time
+	for _, weight := range weights {
+		day, err := time.Parse(dateLayout, string(weight.Date))
+		if err != nil {
+			continue
+		}
+		if counts[day] == 0 {
+			days = append(days, day)
+		}
+		sums[day] += weight.Weight
+		counts[day]++
+	}
+
+	for day := range sums {
+		sums[day] /= float64(counts[day])
+	}
+	return days, sums, nil
+}
+
+// buildWeightTrend computes the EWMA trend, 7-day rolling mean and weekly
+// rate of change over every day from the user's first to last weigh-in, then
+// keeps the points between from and to
+func buildWeightTrend(userID int, alpha float64, from, to string) ([]TrendPoint, error) {
+	days, weights, err := dailyWeights(userID)
+	if err != nil || len(days) == 0 {
+		return []TrendPoint{}, err
+	}
+
+	var points []TrendPoint
+	for i, day := range days {
+		weight := weights[day]
+		if i > 0 {
+			// Interpolate the days between the previous weigh-in and this one
+			prev := days[i-1]
+			gap := int(day.Sub(prev).Hours() / 24)
+			for d := 1; d < gap; d++ {
+				fill := weights[prev] + (weight-weights[prev])*float64(d)/float64(gap)
+				points = append(points, TrendPoint{Date: prev.AddDate(0, 0, d).Format(dateLayout), Weight: fill, Interpolated: true})
+			}
+		}
+		points = append(points, TrendPoint{Date: day.Format(dateLayout), Weight: weight})
+	}
+
+	var windowSum float64
+	for i := range points {
+		point := &points[i]
+		if i == 0 {
+			point.Trend = point.Weight
+		} else {
+			point.Trend = points[i-1].Trend + alpha*(point.Weight-points[i-1].Trend)
+		}
+
+		windowSum += point.Weight
+		if i >= rollingWindow {
+			windowSum -= points[i-rollingWindow].Weight
+		}
+		if i >= rollingWindow-1 {
+			mean := roundTo(windowSum/rollingWindow, 2)
+			point.RollingMean = &mean
+		}
+		if i >= rollingWindow {
+			previous := points[i-rollingWindow].Trend
+			change := roundTo(point.Trend-previous, 2)
+			percent := roundTo((point.Trend-previous)/previous*100, 2)
+			point.WeeklyChange, point.WeeklyChangePercent = &change, &percent
+		}
+	}
+
+	filtered := []TrendPoint{}
+	for _, point := range points {
+		if (from == "" || point.Date >= from) && (to == "" || point.Date <= to) {
+			point.Weight = roundTo(point.Weight, 2)
+			point.Trend = roundTo(point.Trend, 2)
+			filtered = append(filtered, point)
+		}
+	}
+	return filtered, nil
+}
+
+// getWeightTrend handles GET /weights/trend
+func getWeightTrend(c *gin.Context) {
+	from, to, ok := parseDateRange(c)
+	alpha, err := strconv.ParseFloat(c.DefaultQuery("alpha", strconv.FormatFloat(defaultTrendAlpha, 'f', -1, 64)), 64)
+
+	switch {
+	case !ok:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date range"})
+		return
+	case err != nil || alpha <= 0 || alpha > 1:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid alpha"})
+		return
+	}
+
+	points, err := buildWeightTrend(currentUserID(c), alpha, from, to)
+	switch {
+	case err != nil:
+		log.Println("DB Query Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to compute weight trend"})
+	default:
+		display := displayMasses(c)
+		for i := range points {
+			points[i].convertMass(display)
+		}
+		c.JSON(http.StatusOK, gin.H{"alpha": alpha, "unit": display.To, "points": points})
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// trendResponse mirrors the body returned by GET /weights/trend
+type trendResponse struct {
+	Alpha  float64      `json:"alpha"`
+	Points []TrendPoint `json:"points"`
+}
+
+func TestBuildWeightTrend_InterpolatesGaps(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 80})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-04", Weight: 83})
+	// Two weigh-ins on one day are averaged
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-05", Weight: 82})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-05", Weight: 84})
+
+	points, err := buildWeightTrend(testUserID, 0.5, "", "")
+	assert.NoError(t, err)
+	assert.Len(t, points, 5)
+
+	assert.Equal(t, "2023-10-02", points[1].Date)
+	assert.True(t, points[1].Interpolated)
+	assert.Equal(t, 81.0, points[1].Weight)
+	assert.Equal(t, 82.0, points[2].Weight)
+	assert.False(t, points[3].Interpolated)
+	assert.Equal(t, 83.0, points[4].Weight)
+
+	// 80, 80.5, 81.25, 82.13, 82.56
+	assert.Equal(t, 80.0, points[0].Trend)
+	assert.Equal(t, 80.5, points[1].Trend)
+	assert.Equal(t, 82.56, points[4].Trend)
+	assert.Nil(t, points[4].RollingMean)
+}
+
+func TestBuildWeightTrend_WeeklyRate(t *testing.T) {
+	db = setupTestDB()
+
+	// Lose 0.1 a day for two weeks
+	for i := 0; i < 14; i++ {
+		db.Create(&Weight{UserID: testUserID, Date: Date(fmt.Sprintf("2023-10-%02d", i+1)), Weight: 90 - 0.1*float64(i)})
+	}
+
+	points, err := buildWeightTrend(testUserID, 1, "2023-10-08", "")
+	assert.NoError(t, err)
+	assert.Len(t, points, 7)
+	assert.Equal(t, "2023-10-08", points[0].Date)
+	assert.Equal(t, 89.6, *points[0].RollingMean)
+	assert.Equal(t, -0.7, *points[0].WeeklyChange)
+	assert.Equal(t, -0.78, *points[0].WeeklyChangePercent)
+}
+
+func TestGetWeightTrend_Success(t *testing.T) {
+	db = setupTestDB()
+
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-01", Weight: 80})
+	db.Create(&Weight{UserID: testUserID, Date: "2023-10-03", Weight: 81})
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/weights/trend?alpha=0.25", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var trend trendResponse
+	err := json.Unmarshal(w.Body.Bytes(), &trend)
+	assert.NoError(t, err)
+	assert.Equal(t, 0.25, trend.Alpha)
+	assert.Len(t, trend.Points, 3)
+}
+
+func TestGetWeightTrend_InvalidAlpha(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", "/weights/trend?alpha=1.5", nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
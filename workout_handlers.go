@@ -0,0 +1,223 @@
+package main
+
+import (
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// preloadWorkoutExercises loads a workout's exercises in session order along
+// with their sets
+func preloadWorkoutExercises(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Exercises", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("position ASC, id ASC")
+	}).Preload("Exercises.SetDetails", func(tx *gorm.DB) *gorm.DB {
+		return tx.Order("set_number ASC")
+	})
+}
+
+// prepareWorkout derives the session duration from its start and end times
+// and reports whether the workout and any nested exercises are valid. Nested
+// exercises' dates are checked against the user's time zone loc.
+func prepareWorkout(workout *Workout, loc *time.Location) bool {
+	for _, t := range []*time.Time{workout.StartTime, workout.EndTime} {
+		if t != nil {
+			*t = t.UTC()
+		}
+	}
+
+	switch {
+	case workout.Bodyweight < 0 || workout.Duration < 0:
+		return false
+	case workout.StartTime != nil && workout.EndTime != nil:
+		if workout.EndTime.Before(*workout.StartTime) {
+			return false
+		}
+		workout.Duration = int(workout.EndTime.Sub(*workout.StartTime).Minutes())
+	}
+
+	for i := range workout.Exercises {
+		exercise := &workout.Exercises[i]
+		exercise.Position = i + 1
+		exercise.UserID = workout.UserID
+		exercise.Audit = Audit{CreatedBy: workout.UserID, UpdatedBy: workout.UserID}
+		if exercise.Date == "" && exercise.OccurredAt == nil {
+			exercise.Date = workout.Date
+		}
+		if !normalizeDate(&exercise.Date, exercise.OccurredAt, loc) || !prepareExerciseSets(exercise) || !resolveExerciseMovement(exercise) {
+			return false
+		}
+	}
+	return true
+}
+
+// createWorkout handles POST /workouts
+func createWorkout(c *gin.Context) {
+	workout := Workout{UserID: currentUserID(c)}
+
+	log.Println("Received request to create workout")
+
+	switch {
+	case bindUpdate(c, &workout.ID, &workout) != nil:
+		log.Println("JSON Bind Error")
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !normalizeDate(&workout.Date, workout.OccurredAt, currentLocation(c)):
+		c.JSON(http.StatusBadRequest, dateFieldError(workout.Date, workout.OccurredAt))
+		return
+	case !prepareWorkout(&workout, currentLocation(c)):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case db.Create(&workout).Error != nil:
+		log.Println("DB Insert Error")
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create workout"})
+		return
+	default:
+		c.JSON(http.StatusCreated, inDisplayUnit(c, &workout))
+	}
+}
+
+// workoutList is the filtering and sorting accepted by GET /workouts
+var workoutList = listSpec{
+	DateColumn:  "date",
+	Sorts:       map[string]string{"date": "date", "created_at": "created_at", "name": "name"},
+	DefaultSort: "-created_at",
+	Filters:     map[string]string{"name": "name"},
+	Contains:    map[string]string{"name": "name"},
+}
+
+// getWorkouts handles GET /workouts
+func getWorkouts(c *gin.Context) {
+	var workouts []Workout
+	err := paginate(c, userScope(c).Model(&Workout{}), workoutList, &workouts, nil)
+	switch err.(type) {
+	case nil:
+		display := displayMasses(c)
+		for i := range workouts {
+			workouts[i].convertMass(display)
+		}
+		c.JSON(http.StatusOK, workouts)
+	case listParamError:
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch workouts"})
+	}
+}
+
+// getWorkout handles GET /workouts/:id
+func getWorkout(c *gin.Context) {
+	id := c.Param("id")
+	var workout Workout
+	switch err := preloadWorkoutExercises(userScope(c)).First(&workout, id).Error; err {
+	case nil:
+		c.JSON(http.StatusOK, inDisplayUnit(c, &workout))
+	default:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+	}
+}
+
+// updateWorkout handles PUT /workouts/:id
+func updateWorkout(c *gin.Context) {
+	id := c.Param("id")
+	var workout Workout
+
+	switch {
+	case userScope(c).First(&workout, id).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		return
+	case bindUpdate(c, &workout.ID, &workout) != nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	case !normalizeDate(&workout.Date, workout.OccurredAt, currentLocation(c)):
+		c.JSON(http.StatusBadRequest, dateFieldError(workout.Date, workout.OccurredAt))
+		return
+	case len(workout.Exercises) > 0:
+		// Exercises are attached through /workouts/:id/exercises
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exercises cannot be changed through workout update"})
+		return
+	case !prepareWorkout(&workout, currentLocation(c)):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid input values"})
+		return
+	case db.Omit("Exercises").Save(&workout).Error != nil:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update workout"})
+		return
+	default:
+		c.JSON(http.StatusOK, inDisplayUnit(c, &workout))
+	}
+}
+
+// deleteWorkout handles DELETE /workouts/:id, leaving its exercises in place
+// but detached from the session
+func deleteWorkout(c *gin.Context) {
+	id := c.Param("id")
+
+	var workout Workout
+	if err := userScope(c).First(&workout, id).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&Exercise{}).Where("workout_id = ?", workout.ID).Update("workout_id", nil).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&workout).Error
+	})
+	switch err {
+	case nil:
+		c.JSON(http.StatusOK, gin.H{"message": "Workout deleted successfully"})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete workout"})
+	}
+}
+
+// attachWorkoutExercises handles POST /workouts/:id/exercises, attaching
+// already logged exercises to the end of a workout in the order given
+func attachWorkoutExercises(c *gin.Context) {
+	id := c.Param("id")
+	var workout Workout
+	var req struct {
+		ExerciseIDs []int `json:"exercise_ids"`
+	}
+
+	switch {
+	case userScope(c).First(&workout, id).Error != nil:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Workout not found"})
+		return
+	case c.ShouldBindJSON(&req) != nil || len(req.ExerciseIDs) == 0:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request format"})
+		return
+	}
+
+	var count int64
+	userScope(c).Model(&Exercise{}).Where("id IN ?", req.ExerciseIDs).Count(&count)
+	if int(count) != len(req.ExerciseIDs) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Exercise not found"})
+		return
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		var last int
+		if err := tx.Model(&Exercise{}).Where("workout_id = ?", workout.ID).
+			Select("COALESCE(MAX(position), 0)").Scan(&last).Error; err != nil {
+			return err
+		}
+		for i, exerciseID := range req.ExerciseIDs {
+			updates := map[string]interface{}{"workout_id": workout.ID, "position": last + i + 1}
+			if err := tx.Model(&Exercise{}).Where("id = ?", exerciseID).Updates(updates).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to attach exercises"})
+		return
+	}
+
+	preloadWorkoutExercises(db).First(&workout, workout.ID)
+	c.JSON(http.StatusOK, inDisplayUnit(c, &workout))
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+)
+
+// Workout apps whose CSV exports can be imported
+const (
+	SourceStrong = "strong"
+	SourceHevy   = "hevy"
+)
+
+// workoutLogColumns maps the normalized headers of each app's export to the
+// field they fill. Strong exports local times and loads in the unit named by
+// "Weight Unit", when present; Hevy names the unit in the load column.
+var workoutLogColumns = map[string]map[string]string{
+	SourceStrong: {
+		"date":          "start",
+		"workout name":  "workout",
+		"duration":      "duration",
+		"exercise name": "exercise",
+		"set order":     "set",
+		"weight":        "weight",
+		"weight unit":   "unit",
+		"reps":          "reps",
+		"rpe":           "rpe",
+		"workout notes": "notes",
+	},
+	SourceHevy: {
+		"title":          "workout",
+		"start time":     "start",
+		"end time":       "end",
+		"description":    "notes",
+		"exercise title": "exercise",
+		"set index":      "set",
+		"set type":       "set_type",
+		"weight kg":      "weight_kg",
+		"weight lbs":     "weight_lb",
+		"reps":           "reps",
+		"rpe":            "rpe",
+	},
+}
+
+// workoutLogTimeLayouts are the local time formats used by the exports
+var workoutLogTimeLayouts = []string{
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2 Jan 2006, 15:04",
+	"2 Jan 2006 15:04",
+	"Jan 2, 2006, 15:04",
+}
+
+// workoutLogSetTypes maps the set types of the exports to ours. Strong marks
+// warmup, drop and failure sets in place of the set number.
+var workoutLogSetTypes = map[string]string{
+	"w":       SetTypeWarmup,
+	"d":       SetTypeDrop,
+	"f":       SetTypeFailure,
+	"normal":  SetTypeWorking,
+	"warmup":  SetTypeWarmup,
+	"dropset": SetTypeDrop,
+	"failure": SetTypeFailure,
+}
+
+// WorkoutImportResult reports the sessions found in a workout app export.
+// Sessions imported before are counted as duplicates and skipped, as are sets
+// without reps such as cardio or timed holds. Nothing is saved unless every
+// row is valid.
+type WorkoutImportResult struct {
+	Source      string  `json:"source"`
+	DryRun      bool    `json:"dry_run"`
+	Workouts    int     `json:"workouts"`
+	Exercises   int     `json:"exercises"`
+	Duplicates  int     `json:"duplicates"`
+	SkippedSets int     `json:"skipped_sets"`
+	Errors      []gin.H `json:"errors"`
+}
+
+// detectWorkoutLog returns the app that wrote an export with the header, and
+// the column index of each field it fills
+func detectWorkoutLog(header []string) (string, map[string]int, error) {
+	keys := map[string]int{}
+	for i, name := range header {
+		keys[csvHeaderKey(name)] = i
+	}
+
+	var source string
+	switch _, strong := keys["exercise name"]; {
+	case strong:
+		source = SourceStrong
+	default:
+		if _, hevy := keys["exercise title"]; !hevy {
+			return "", nil, fmt.Errorf("not a Strong or Hevy export")
+		}
+		source = SourceHevy
+	}
+
+	columns := map[string]int{}
+	for key, field := range workoutLogColumns[source] {
+		if i, ok := keys[key]; ok {
+			columns[field] = i
+		}
+	}
+	return source, columns, nil
+}
+
+// workoutLogTime parses a column holding a local time in the user's time
+// zone loc, returning nil when it is empty
+func workoutLogTime(row csvRow, column string, loc *time.Location) (*time.Time, error) {
+	raw := row.value(column)
+	if raw == "" {
+		return nil, nil
+	}
+	for _, layout := range workoutLogTimeLayouts {
+		if t, err := time.ParseInLocation(layout, raw, loc); err == nil {
+			return &t, nil
+		}
+	}
+	return nil, csvFieldError{Field: column, Message: fmt.Sprintf("%s must be a date and time, got %q", column, raw)}
+}
+
+// workoutLogSet parses the set on a row with its load in kilograms. Strong
+// loads are in the row's unit, or defaultUnit when the export has none.
+func workoutLogSet(row csvRow, defaultUnit string) (ExerciseSet, error) {
+	set := ExerciseSet{SetType: SetTypeWorking}
+	// Strong writes reps as decimals such as "5.0"
+	reps, err := row.number("reps")
+	if err != nil || reps != float64(int(reps)) {
+		return set, csvFieldError{Field: "reps", Message: fmt.Sprintf("reps must be a whole number, got %q", row.value("reps"))}
+	}
+	set.Reps = int(reps)
+	if row.value("rpe") != "" {
+		rpe, err := row.number("rpe")
+		if err != nil {
+			return set, err
+		}
+		set.RPE = &rpe
+	}
+
+	kind := strings.ToLower(row.value("set_type"))
+	if kind == "" {
+		kind = strings.ToLower(row.value("set"))
+	}
+	if setType, ok := workoutLogSetTypes[kind]; ok {
+		set.SetType = setType
+	}
+
+	unit := defaultUnit
+	switch {
+	case row.value("weight_kg") != "":
+		set.Load, err = row.number("weight_kg")
+		unit = UnitKg
+	case row.value("weight_lb") != "":
+		set.Load, err = row.number("weight_lb")
+		unit = UnitLb
+	default:
+		set.Load, err = row.number("weight")
+		if named := strings.TrimSuffix(strings.ToLower(row.value("unit")), "s"); named != "" {
+			unit = named
+		}
+	}
+	switch {
+	case err != nil:
+		return set, err
+	case !validUnit(unit):
+		return set, csvFieldError{Field: "unit", Message: fmt.Sprintf("unit must be %s or %s, got %q", UnitKg, UnitLb, row.value("unit"))}
+	}
+	set.Load = massConverter{From: unit, To: UnitKg}.mass(set.Load)
+	return set, nil
+}
+
+// catalogMovementName maps an app's exercise name onto the catalog. Apps name
+// the equipment in parentheses, so "Bench Press (Dumbbell)" is tried as
+// "Dumbbell Bench Press" and then as "Bench Press". Names with no match are
+// returned unchanged.
+func catalogMovementName(name string) string {
+	candidates := []string{name}
+	if open := strings.LastIndex(name, "("); open > 0 && strings.HasSuffix(name, ")") {
+		base := strings.TrimSpace(name[:open])
+		equipment := strings.TrimSpace(name[open+1 : len(name)-1])
+		candidates = append(candidates, equipment+" "+base, base)
+	}
+	for _, candidate := range candidates {
+		movement, err := resolveMovement(db, candidate)
+		if err != nil {
+			log.Println("Movement lookup failed:", err)
+			break
+		}
+		if movement != nil {
+			return movement.Name
+		}
+	}
+	return name
+}
+
+// workoutLogID identifies a session by its start time as written in the
+// export and its name, so it does not change with the user's time zone
+func workoutLogID(start, name string) string {
+	sum := sha1.Sum([]byte(strings.TrimSpace(start) + "\n" + name))
+	return hex.EncodeToString(sum[:])
+}
+
+// parseWorkoutLog reads a Strong or Hevy CSV export into workouts. Rows are
+// grouped into sessions by start time and workout name, and into exercises
+// by exercise name; each row is one set. Times are read in the user's time
+// zone loc and Strong loads without a unit in defaultUnit.
+func parseWorkoutLog(body io.Reader, loc *time.Location, defaultUnit string) ([]Workout, *WorkoutImportResult, error) {
+	result := &WorkoutImportResult{Errors: []gin.H{}}
+	rows, err := readCSVRows(body, func(header []string) (map[string]int, error) {
+		source, columns, err := detectWorkoutLog(header)
+		result.Source = source
+		return columns, err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var workouts []Workout
+	sessions := map[string]int{}
+	exercises := map[string]int{}
+	lines := map[[2]int]int{}
+	for _, row := range rows {
+		start, err := workoutLogTime(row, "start", loc)
+		if err == nil && start == nil {
+			err = csvFieldError{Field: "start", Message: "start time is required"}
+		}
+		if err != nil {
+			result.Errors = append(result.Errors, rowError(row.Line, parseProblem(err)))
+			continue
+		}
+		name, movement := row.value("workout"), row.value("exercise")
+		if movement == "" || strings.EqualFold(row.value("set"), "rest timer") {
+			continue
+		}
+		set, err := workoutLogSet(row, defaultUnit)
+		if err != nil {
+			result.Errors = append(result.Errors, rowError(row.Line, parseProblem(err)))
+			continue
+		}
+		if set.Reps <= 0 {
+			result.SkippedSets++
+			continue
+		}
+
+		id := workoutLogID(row.value("start"), name)
+		session, ok := sessions[id]
+		if !ok {
+			workout := Workout{Name: name, OccurredAt: start, StartTime: start, Notes: row.value("notes"), Source: result.Source, ExternalID: id}
+			if workout.EndTime, err = workoutLogTime(row, "end", loc); err != nil {
+				result.Errors = append(result.Errors, rowError(row.Line, parseProblem(err)))
+				continue
+			}
+			if duration, err := time.ParseDuration(strings.ReplaceAll(row.value("duration"), " ", "")); err == nil && duration > 0 {
+				end := start.Add(duration)
+				workout.EndTime = &end
+			}
+			session = len(workouts)
+			sessions[id] = session
+			workouts = append(workouts, workout)
+		}
+
+		workout := &workouts[session]
+		key := id + "\n" + strings.ToLower(movement)
+		position, ok := exercises[key]
+		if !ok {
+			position = len(workout.Exercises)
+			exercises[key] = position
+			workout.Exercises = append(workout.Exercises, Exercise{Movement: catalogMovementName(movement), OccurredAt: start})
+		}
+		exercise := &workout.Exercises[position]
+		if _, ok := lines[[2]int{session, position}]; !ok {
+			lines[[2]int{session, position}] = row.Line
+		}
+		set.SetNumber = len(exercise.SetDetails) + 1
+		exercise.SetDetails = append(exercise.SetDetails, set)
+	}
+
+	for i := range workouts {
+		workout := &workouts[i]
+		valid := true
+		for j := range workout.Exercises {
+			if problem := validateExercise(&workout.Exercises[j], loc); problem != nil {
+				result.Errors = append(result.Errors, rowError(lines[[2]int{i, j}], problem))
+				valid = false
+			}
+		}
+		if valid && (!normalizeDate(&workout.Date, workout.OccurredAt, loc) || !prepareWorkout(workout, loc)) {
+			result.Errors = append(result.Errors, rowError(lines[[2]int{i, 0}], gin.H{"error": "Invalid input values"}))
+		}
+		result.Exercises += len(workout.Exercises)
+	}
+	result.Workouts = len(workouts)
+	return workouts, result, nil
+}
+
+// skipImportedWorkouts drops the workouts the user has imported from source
+// before, including those since deleted
+func skipImportedWorkouts(tx *gorm.DB, userID int, source string, workouts []Workout) ([]Workout, error) {
+	if len(workouts) == 0 {
+		return workouts, nil
+	}
+	ids := make([]string, len(workouts))
+	for i, workout := range workouts {
+		ids[i] = workout.ExternalID
+	}
+	var imported []string
+	for start := 0; start < len(ids); start += csvBatchSize {
+		end := start + csvBatchSize
+		if end > len(ids) {
+			end = len(ids)
+		}
+		var batch []string
+		err := tx.Unscoped().Model(&Workout{}).Where("user_id = ? AND source = ?", userID, source).
+			Where("external_id IN ?", ids[start:end]).Pluck("external_id", &batch).Error
+		if err != nil {
+			return nil, err
+		}
+		imported = append(imported, batch...)
+	}
+	seen := map[string]bool{}
+	for _, id := range imported {
+		seen[id] = true
+	}
+
+	fresh := workouts[:0]
+	for _, workout := range workouts {
+		if !seen[workout.ExternalID] {
+			fresh = append(fresh, workout)
+		}
+	}
+	return fresh, nil
+}
+
+// importWorkoutLog parses a Strong or Hevy export for the user and, unless
+// it is a dry run or has errors, creates the sessions not imported before
+// with their exercises in one transaction. createdBy is recorded as the
+// author of the exercises.
+func importWorkoutLog(body io.Reader, userID, createdBy int, defaultUnit string, dryRun bool) (*WorkoutImportResult, error) {
+	loc := userLocation(userID)
+	workouts, result, err := parseWorkoutLog(body, loc, defaultUnit)
+	if err != nil {
+		return nil, err
+	}
+	result.DryRun = dryRun
+	if len(result.Errors) > 0 {
+		return result, nil
+	}
+
+	found := len(workouts)
+	if workouts, err = skipImportedWorkouts(db, userID, result.Source, workouts); err != nil {
+		return result, err
+	}
+	result.Duplicates = found - len(workouts)
+	result.Workouts, result.Exercises = len(workouts), 0
+	for i := range workouts {
+		result.Exercises += len(workouts[i].Exercises)
+	}
+	if dryRun || len(workouts) == 0 {
+		return result, nil
+	}
+	for i := range workouts {
+		workouts[i].UserID = userID
+		for j := range workouts[i].Exercises {
+			exercise := &workouts[i].Exercises[j]
+			exercise.UserID = userID
+			exercise.Audit = Audit{CreatedBy: createdBy, UpdatedBy: createdBy}
+		}
+	}
+	return result, db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&workouts, csvBatchSize).Error
+	})
+}
+
+// importWorkouts handles POST /import/workouts with a Strong or Hevy CSV
+// export. Strong loads are read in ?unit= when the export does not name it.
+func importWorkouts(c *gin.Context) {
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid dry_run"})
+		return
+	}
+	unit := strings.ToLower(c.DefaultQuery("unit", displayUnit(c)))
+	if !validUnit(unit) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid unit", "field": "unit", "value": unit})
+		return
+	}
+
+	var result *WorkoutImportResult
+	err = readUpload(c, func(body io.Reader) (err error) {
+		result, err = importWorkoutLog(body, currentUserID(c), actingUserID(c), unit, dryRun)
+		return err
+	})
+	switch {
+	case result == nil:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid CSV", "message": err.Error()})
+	case err != nil:
+		log.Println("DB Insert Error:", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to import workouts"})
+	case len(result.Errors) > 0:
+		c.JSON(http.StatusUnprocessableEntity, result)
+	case result.DryRun:
+		c.JSON(http.StatusOK, result)
+	default:
+		c.JSON(http.StatusCreated, result)
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// strongExport is a semicolon separated Strong export with a warmup set, a
+// rest timer and a cardio exercise without reps
+const strongExport = `Date;Workout Name;Duration;Exercise Name;Set Order;Weight;Weight Unit;Reps;Distance;Seconds;Notes;Workout Notes;RPE
+2023-10-01 07:00:00;"Push Day";1h 5m;"Bench Press (Dumbbell)";W;40;lbs;10.0;0;0;"";"";
+2023-10-01 07:00:00;"Push Day";1h 5m;"Bench Press (Dumbbell)";1;70;lbs;8.0;0;0;"";"";8.5
+2023-10-01 07:00:00;"Push Day";1h 5m;"Bench Press (Dumbbell)";Rest Timer;0;lbs;0;0;90;"";"";
+2023-10-01 07:00:00;"Push Day";1h 5m;"Squat (Barbell)";1;225;lbs;5.0;0;0;"";"";
+2023-10-01 07:00:00;"Push Day";1h 5m;"Running";1;0;lbs;0;5;1800;"";"";
+2023-10-03 18:30:00;"Pull Day";45m;"Pull Up";1;0;lbs;10.0;0;0;"";"";
+`
+
+func TestImportWorkouts_Strong(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/import/workouts", strongExport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var result WorkoutImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, SourceStrong, result.Source)
+	assert.Equal(t, 2, result.Workouts)
+	assert.Equal(t, 3, result.Exercises)
+	assert.Equal(t, 1, result.SkippedSets)
+
+	var workout Workout
+	preloadWorkoutExercises(db).Where("name = ?", "Push Day").First(&workout)
+	assert.Equal(t, SourceStrong, workout.Source)
+	assert.Equal(t, Date("2023-10-01"), workout.Date)
+	assert.Equal(t, 65, workout.Duration)
+	assert.Len(t, workout.Exercises, 2)
+
+	bench := workout.Exercises[0]
+	assert.Equal(t, "Dumbbell Bench Press", bench.Movement)
+	assert.NotNil(t, bench.MovementID)
+	assert.Equal(t, 1, bench.Position)
+	assert.Len(t, bench.SetDetails, 2)
+	assert.Equal(t, SetTypeWarmup, bench.SetDetails[0].SetType)
+	assert.Equal(t, SetTypeWorking, bench.SetDetails[1].SetType)
+	assert.InDelta(t, 31.75, bench.SetDetails[1].Load, 0.01)
+	assert.Equal(t, 8.5, *bench.SetDetails[1].RPE)
+	assert.Equal(t, "Back Squat", workout.Exercises[1].Movement)
+}
+
+func TestImportWorkouts_ReimportSkipsSessions(t *testing.T) {
+	db = setupTestDB()
+
+	assert.Equal(t, http.StatusCreated, postCSV("/import/workouts", strongExport).Code)
+	w := postCSV("/import/workouts", strongExport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var result WorkoutImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 0, result.Workouts)
+	assert.Equal(t, 2, result.Duplicates)
+
+	var workouts, exercises int64
+	db.Model(&Workout{}).Count(&workouts)
+	db.Model(&Exercise{}).Count(&exercises)
+	assert.Equal(t, int64(2), workouts)
+	assert.Equal(t, int64(3), exercises)
+}
+
+func TestImportWorkouts_ReimportAfterTimeZoneChange(t *testing.T) {
+	db = setupTestDB()
+
+	assert.Equal(t, http.StatusCreated, postCSV("/import/workouts", strongExport).Code)
+	db.Model(&User{}).Where("id = ?", testUserID).Update("time_zone", "Asia/Tokyo")
+	w := postCSV("/import/workouts", strongExport)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var result WorkoutImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Equal(t, 0, result.Workouts)
+	assert.Equal(t, 2, result.Duplicates)
+
+	var workouts int64
+	db.Model(&Workout{}).Count(&workouts)
+	assert.Equal(t, int64(2), workouts)
+}
+
+func TestImportWorkouts_HevyInUserTimeZone(t *testing.T) {
+	db = setupTestDB()
+	db.Model(&User{}).Where("id = ?", testUserID).Update("time_zone", "America/New_York")
+
+	w := postCSV("/import/workouts", `"title","start_time","end_time","description","exercise_title","superset_id","exercise_notes","set_index","set_type","weight_lbs","reps","distance_miles","duration_seconds","rpe"
+"Legs","2 Oct 2023, 21:15","2 Oct 2023, 22:05","","Squat (Barbell)",,"",0,"warmup",135,5,,,
+"Legs","2 Oct 2023, 21:15","2 Oct 2023, 22:05","","Squat (Barbell)",,"",1,"normal",225,5,,,9
+"Legs","2 Oct 2023, 21:15","2 Oct 2023, 22:05","","Squat (Barbell)",,"",2,"dropset",185,8,,,
+`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var workout Workout
+	preloadWorkoutExercises(db).First(&workout)
+	assert.Equal(t, SourceHevy, workout.Source)
+	assert.Equal(t, Date("2023-10-02"), workout.Date)
+	assert.Equal(t, "2023-10-03T01:15:00Z", workout.StartTime.UTC().Format("2006-01-02T15:04:05Z"))
+	assert.Equal(t, 50, workout.Duration)
+	sets := workout.Exercises[0].SetDetails
+	assert.Len(t, sets, 3)
+	assert.Equal(t, []string{SetTypeWarmup, SetTypeWorking, SetTypeDrop}, []string{sets[0].SetType, sets[1].SetType, sets[2].SetType})
+	assert.InDelta(t, 102.06, sets[1].Load, 0.01)
+}
+
+func TestImportWorkouts_DryRunAndErrors(t *testing.T) {
+	db = setupTestDB()
+
+	w := postCSV("/import/workouts?dry_run=true", strongExport)
+	assert.Equal(t, http.StatusOK, w.Code)
+	var count int64
+	db.Model(&Workout{}).Count(&count)
+	assert.Equal(t, int64(0), count)
+
+	w = postCSV("/import/workouts", "Date,Workout Name,Exercise Name,Set Order,Weight,Reps\n10/01/2023,Push,Bench Press,1,100,five\n")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var result WorkoutImportResult
+	json.Unmarshal(w.Body.Bytes(), &result)
+	assert.Len(t, result.Errors, 1)
+	assert.Equal(t, "start", result.Errors[0]["field"])
+
+	w = postCSV("/import/workouts", "id,date,movement\n1,2023-10-01,Squat\n")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
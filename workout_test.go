@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCreateWorkout_Success(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{
+		"date": "2023-10-02",
+		"name": "Monday push day",
+		"start_time": "2023-10-02T17:00:00Z",
+		"end_time": "2023-10-02T18:15:00Z",
+		"bodyweight": 82.5,
+		"exercises": [
+			{"movement": "Bench Press", "sets": [{"reps": 5, "load": 100}, {"reps": 5, "load": 100}]},
+			{"movement": "Dips", "sets": 3, "reps": 12, "weight": 0}
+		]
+	}`)
+	req, _ := http.NewRequest("POST", "/workouts", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	var workout Workout
+	err := json.Unmarshal(w.Body.Bytes(), &workout)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, workout.ID)
+	assert.Equal(t, 75, workout.Duration)
+	assert.Len(t, workout.Exercises, 2)
+	assert.Equal(t, Date("2023-10-02"), workout.Exercises[1].Date)
+	assert.Equal(t, 2, workout.Exercises[1].Position)
+}
+
+func TestCreateWorkout_EndBeforeStart(t *testing.T) {
+	db = setupTestDB()
+
+	r := setupRouter()
+
+	reqBody := []byte(`{"date": "2023-10-02", "start_time": "2023-10-02T18:00:00Z", "end_time": "2023-10-02T17:00:00Z"}`)
+	req, _ := http.NewRequest("POST", "/workouts", bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestGetWorkout_IncludesExercisesAndSets(t *testing.T) {
+	db = setupTestDB()
+
+	workout := Workout{
+		UserID: testUserID,
+		Date:   "2023-10-02",
+		Name:   "Leg day",
+		Exercises: []Exercise{
+			{UserID: testUserID, Movement: "Squat", Sets: 1, Reps: 5, Weight: 140, Position: 1, SetDetails: []ExerciseSet{{SetNumber: 1, Reps: 5, Load: 140}}},
+		},
+	}
+	db.Create(&workout)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("GET", fmt.Sprintf("/workouts/%d", workout.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var fetched Workout
+	err := json.Unmarshal(w.Body.Bytes(), &fetched)
+	assert.NoError(t, err)
+	assert.Len(t, fetched.Exercises, 1)
+	assert.Equal(t, "Squat", fetched.Exercises[0].Movement)
+	assert.Len(t, fetched.Exercises[0].SetDetails, 1)
+}
+
+func TestAttachWorkoutExercises_Success(t *testing.T) {
+	db = setupTestDB()
+
+	workout := Workout{UserID: testUserID, Date: "2023-10-02", Name: "Pull day"}
+	db.Create(&workout)
+	first := Exercise{UserID: testUserID, Date: "2023-10-02", Movement: "Rows", Sets: 3, Reps: 10, Weight: 60}
+	second := Exercise{UserID: testUserID, Date: "2023-10-02", Movement: "Chin-ups", Sets: 3, Reps: 8}
+	db.Create(&first)
+	db.Create(&second)
+
+	r := setupRouter()
+
+	reqBody, _ := json.Marshal(map[string][]int{"exercise_ids": {second.ID, first.ID}})
+	req, _ := http.NewRequest("POST", fmt.Sprintf("/workouts/%d/exercises", workout.ID), bytes.NewBuffer(reqBody))
+	req.Header.Set("Content-Type", "application/json")
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var fetched Workout
+	err := json.Unmarshal(w.Body.Bytes(), &fetched)
+	assert.NoError(t, err)
+	assert.Len(t, fetched.Exercises, 2)
+	assert.Equal(t, "Chin-ups", fetched.Exercises[0].Movement)
+	assert.Equal(t, "Rows", fetched.Exercises[1].Movement)
+}
+
+func TestDeleteWorkout_DetachesExercises(t *testing.T) {
+	db = setupTestDB()
+
+	workout := Workout{UserID: testUserID, Date: "2023-10-02", Exercises: []Exercise{{UserID: testUserID, Movement: "Squat", Sets: 1, Reps: 5, Weight: 140}}}
+	db.Create(&workout)
+
+	r := setupRouter()
+
+	req, _ := http.NewRequest("DELETE", fmt.Sprintf("/workouts/%d", workout.ID), nil)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var exercise Exercise
+	db.First(&exercise, workout.Exercises[0].ID)
+	assert.Equal(t, "Squat", exercise.Movement)
+	assert.Nil(t, exercise.WorkoutID)
+}